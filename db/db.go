@@ -1,12 +1,15 @@
 package database
 
 import (
-	"errors"
+	"encoding/json"
 	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"orderService.com/go-orderService-grpc/model"
 )
 
@@ -17,6 +20,9 @@ const (
 	password = "pgpswd"
 	dbName   = "OrderServiceDB"
 	sslMode  = "disable"
+
+	// LegacyCurrency is the currency of orders stored before amounts carried one.
+	LegacyCurrency = "INR"
 )
 
 func Connection() *gorm.DB {
@@ -29,23 +35,729 @@ func Connection() *gorm.DB {
 
 	log.Println("Connected to the database")
 
-	err = db.AutoMigrate(&model.User{}, &model.Order{})
+	err = backfillNormalizedUsernames(db)
+	if err != nil {
+		log.Fatalf("Error migrating database: %v", err)
+	}
+
+	err = db.AutoMigrate(&model.User{}, &model.Order{}, &model.OrderItem{}, &model.Promotion{}, &model.PromotionRedemption{}, &model.Cart{}, &model.CartItem{}, &model.GroupOrder{}, &model.GroupOrderItem{}, &model.RestaurantOwner{}, &model.ChatMessage{}, &model.SupportAgent{}, &model.LoyaltyTransaction{}, &model.LoyaltyEntry{}, &model.WalletTransaction{}, &model.WalletEntry{})
 
 	if err != nil {
 		log.Fatalf("Error migrating database: %v", err)
 	}
 
+	err = migrateFloatPrices(db)
+	if err != nil {
+		log.Fatalf("Error migrating order prices: %v", err)
+	}
+
+	err = migrateLegacyMenuItems(db)
+	if err != nil {
+		log.Fatalf("Error migrating order menu items: %v", err)
+	}
+
+	err = db.Model(&model.Order{}).Where("status IS NULL OR status = ''").Update("status", model.OrderPlaced).Error
+	if err != nil {
+		log.Fatalf("Error migrating order statuses: %v", err)
+	}
+
+	err = installOrderChangeSequence(db)
+	if err != nil {
+		log.Fatalf("Error installing the order change sequence: %v", err)
+	}
+
 	return db
 }
 
 func GetUserByUsername(db *gorm.DB, username string) (*model.User, error) {
 	var user model.User
 
-	err := db.Where("username = ?", username).Find(&user).Error
+	err := db.Where("normalized_username = ?", model.NormalizeUsername(username)).First(&user).Error
 	if err != nil {
-		errStr := fmt.Sprintf("user not found: %v", err)
-		return nil, errors.New(errStr)
+		return nil, fmt.Errorf("user not found: %w", err)
 	}
 
 	return &user, nil
 }
+
+func IsUsernameTaken(db *gorm.DB, username string) (bool, error) {
+	var count int64
+
+	err := db.Model(&model.User{}).Where("normalized_username = ?", model.NormalizeUsername(username)).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func CountOrdersByUsername(db *gorm.DB, username string) (int64, error) {
+	var count int64
+
+	err := db.Model(&model.Order{}).Where("username = ?", username).Count(&count).Error
+	return count, err
+}
+
+func GetPromotionByCode(db *gorm.DB, code string) (*model.Promotion, error) {
+	var promotion model.Promotion
+
+	err := db.Where("code = ?", code).First(&promotion).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &promotion, nil
+}
+
+// LockPromotion reads a promotion with a row lock, so that concurrent orders
+// redeeming it are serialized until the surrounding transaction ends.
+func LockPromotion(tx *gorm.DB, id int64) (*model.Promotion, error) {
+	var promotion model.Promotion
+
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&promotion).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &promotion, nil
+}
+
+func CountPromotionRedemptions(db *gorm.DB, promotionId int64, username string) (int64, error) {
+	var count int64
+
+	err := db.Model(&model.PromotionRedemption{}).Where("promotion_id = ? AND username = ?", promotionId, username).Count(&count).Error
+	return count, err
+}
+
+// RecordRedemption counts one more use of the promotion and remembers which
+// order used it. It must run in the transaction that holds LockPromotion.
+func RecordRedemption(tx *gorm.DB, redemption *model.PromotionRedemption) error {
+	err := tx.Model(&model.Promotion{}).Where("id = ?", redemption.PromotionId).
+		UpdateColumn("usage_count", gorm.Expr("usage_count + 1")).Error
+	if err != nil {
+		return err
+	}
+
+	return tx.Create(redemption).Error
+}
+
+// GetCart returns the user's cart with its items, or gorm.ErrRecordNotFound
+// if the user has none.
+func GetCart(db *gorm.DB, username string) (*model.Cart, error) {
+	var cart model.Cart
+
+	err := db.Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("menu_item_name")
+	}).Where("username = ?", username).First(&cart).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &cart, nil
+}
+
+// LockCart reads the user's cart with a row lock, creating an empty one for
+// restaurantId if the user has none, so that concurrent changes to the same
+// cart are applied one after the other.
+func LockCart(tx *gorm.DB, username string, restaurantId string) (*model.Cart, error) {
+	cart := model.Cart{Username: username, RestaurantId: restaurantId}
+
+	err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&cart).Error
+	if err != nil {
+		return nil, err
+	}
+
+	cart = model.Cart{}
+	err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Items").Where("username = ?", username).First(&cart).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &cart, nil
+}
+
+// SetCartItemQuantity stores the quantity of a menu item in a cart, removing
+// the item when quantity is not positive.
+func SetCartItemQuantity(tx *gorm.DB, cartId int64, menuItemName string, quantity int32) error {
+	if quantity <= 0 {
+		return tx.Where("cart_id = ? AND menu_item_name = ?", cartId, menuItemName).Delete(&model.CartItem{}).Error
+	}
+
+	item := model.CartItem{CartId: cartId, MenuItemName: menuItemName, Quantity: quantity}
+
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "cart_id"}, {Name: "menu_item_name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"quantity"}),
+	}).Create(&item).Error
+}
+
+// ResetCart empties a cart and scopes it to restaurantId.
+func ResetCart(tx *gorm.DB, cartId int64, restaurantId string) error {
+	if err := tx.Where("cart_id = ?", cartId).Delete(&model.CartItem{}).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&model.Cart{}).Where("id = ?", cartId).Update("restaurant_id", restaurantId).Error
+}
+
+// DeleteCart removes the user's cart and its items. Run it in a transaction
+// so the two deletes can't be separated.
+func DeleteCart(tx *gorm.DB, username string) error {
+	err := tx.Where("cart_id IN (?)", tx.Model(&model.Cart{}).Select("id").Where("username = ?", username)).Delete(&model.CartItem{}).Error
+	if err != nil {
+		return err
+	}
+
+	return tx.Where("username = ?", username).Delete(&model.Cart{}).Error
+}
+
+// ClaimDueOrders marks up to limit scheduled orders that are due by dueBy as
+// dispatching and returns them. Orders left dispatching since before
+// staleBefore, by a scheduler that stopped midway, are claimed again. Rows
+// locked by another scheduler are skipped, so an order is only ever claimed
+// by one of them at a time.
+func ClaimDueOrders(db *gorm.DB, dueBy time.Time, staleBefore time.Time, now time.Time, limit int) ([]model.Order, error) {
+	var orders []model.Order
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(status = ? AND scheduled_for <= ?) OR (status = ? AND dispatch_claimed_at <= ?)", model.OrderScheduled, dueBy, model.OrderDispatching, staleBefore).
+			Order("scheduled_for").Limit(limit).Find(&orders).Error
+		if err != nil || len(orders) == 0 {
+			return err
+		}
+
+		ids := make([]int64, len(orders))
+		for i := range orders {
+			ids[i] = orders[i].Id
+			orders[i].Status = model.OrderDispatching
+			orders[i].DispatchClaimedAt = &now
+		}
+
+		return tx.Model(&model.Order{}).Where("id IN ?", ids).
+			Updates(map[string]any{"status": model.OrderDispatching, "dispatch_claimed_at": now}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+// UpdateOrder saves the given columns of an order.
+func UpdateOrder(tx *gorm.DB, order *model.Order, columns ...string) error {
+	return tx.Model(order).Select(columns).Updates(order).Error
+}
+
+// MarkOrderDispatched stores the status and dispatch time of an order that
+// was sent to the fulfillment service, unless it is no longer
+// OrderDispatching. It reports whether the order was updated.
+func MarkOrderDispatched(db *gorm.DB, order *model.Order) (bool, error) {
+	result := db.Model(order).Where("status = ?", model.OrderDispatching).Select("status", "dispatched_at").Updates(order)
+	return result.RowsAffected > 0, result.Error
+}
+
+// ReplaceOrderItems swaps the stored items of an order for order.Items.
+func ReplaceOrderItems(tx *gorm.DB, order *model.Order) error {
+	if err := tx.Where("order_id = ?", order.Id).Delete(&model.OrderItem{}).Error; err != nil {
+		return err
+	}
+
+	for i := range order.Items {
+		order.Items[i].Id = 0
+		order.Items[i].OrderId = order.Id
+	}
+
+	return tx.Create(&order.Items).Error
+}
+
+// ReleaseRedemption gives back the promotion use of an order that was
+// cancelled or rejected before it was dispatched.
+func ReleaseRedemption(tx *gorm.DB, orderId int64) error {
+	var redemptions []model.PromotionRedemption
+	if err := tx.Where("order_id = ?", orderId).Find(&redemptions).Error; err != nil {
+		return err
+	}
+
+	for _, redemption := range redemptions {
+		err := tx.Model(&model.Promotion{}).Where("id = ? AND usage_count > 0", redemption.PromotionId).
+			UpdateColumn("usage_count", gorm.Expr("usage_count - 1")).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Delete(&redemption).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReverseLoyaltyRedemption gives back the loyalty points spent on an order
+// that was cancelled or rejected.
+func ReverseLoyaltyRedemption(tx *gorm.DB, order *model.Order) error {
+	if order.LoyaltyPointsRedeemed == 0 {
+		return nil
+	}
+
+	return RecordLoyaltyTransaction(tx, model.LoyaltyTransfer(model.LoyaltyReversal, order.Username, order.Id, order.LoyaltyPointsRedeemed,
+		model.LoyaltyRedeemedAccount, model.LoyaltyAccount(order.Username)))
+}
+
+// LockUser reads a user with a row lock. Changes to the user's loyalty
+// points and store credit are serialized on it.
+func LockUser(tx *gorm.DB, username string) (*model.User, error) {
+	var user model.User
+
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("username = ?", username).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func GetLoyaltyBalance(db *gorm.DB, username string) (int64, error) {
+	var balance int64
+
+	err := db.Model(&model.LoyaltyEntry{}).Where("account = ?", model.LoyaltyAccount(username)).
+		Select("COALESCE(SUM(points), 0)").Scan(&balance).Error
+	return balance, err
+}
+
+// ListLoyaltyLots returns the points a user earned, which expire in turn.
+func ListLoyaltyLots(db *gorm.DB, username string) ([]model.LoyaltyTransaction, error) {
+	var lots []model.LoyaltyTransaction
+
+	err := db.Where("username = ? AND kind = ?", username, model.LoyaltyEarn).Order("expires_at, id").Find(&lots).Error
+	return lots, err
+}
+
+func RecordLoyaltyTransaction(tx *gorm.DB, transaction *model.LoyaltyTransaction) error {
+	return tx.Create(transaction).Error
+}
+
+// ListLoyaltyTransactions returns up to limit of a user's transactions with
+// their entries, newest first, starting below beforeId unless it is 0.
+func ListLoyaltyTransactions(db *gorm.DB, username string, beforeId int64, limit int) ([]model.LoyaltyTransaction, error) {
+	var transactions []model.LoyaltyTransaction
+
+	query := db.Preload("Entries", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).Where("username = ?", username)
+	if beforeId > 0 {
+		query = query.Where("id < ?", beforeId)
+	}
+
+	err := query.Order("id DESC").Limit(limit).Find(&transactions).Error
+	return transactions, err
+}
+
+// ReverseWalletPayment gives back the store credit paid for an order that was
+// cancelled or rejected.
+func ReverseWalletPayment(tx *gorm.DB, order *model.Order) error {
+	if order.WalletAmount == 0 {
+		return nil
+	}
+
+	return RecordWalletTransaction(tx, model.WalletTransfer(model.WalletReversal, order.Username, order.Id, order.Currency, order.WalletAmount,
+		model.WalletPaymentsAccount, model.WalletAccount(order.Username)))
+}
+
+func GetWalletBalance(db *gorm.DB, username string, currency string) (int64, error) {
+	var balance int64
+
+	err := db.Model(&model.WalletEntry{}).Where("account = ? AND currency = ?", model.WalletAccount(username), currency).
+		Select("COALESCE(SUM(amount), 0)").Scan(&balance).Error
+	return balance, err
+}
+
+// GetWalletBalances returns a user's balance in each currency they were ever
+// credited in, by currency.
+func GetWalletBalances(db *gorm.DB, username string) (map[string]int64, error) {
+	var rows []struct {
+		Currency string
+		Amount   int64
+	}
+
+	err := db.Model(&model.WalletEntry{}).Where("account = ?", model.WalletAccount(username)).
+		Select("currency, SUM(amount) AS amount").Group("currency").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		balances[row.Currency] = row.Amount
+	}
+
+	return balances, nil
+}
+
+func RecordWalletTransaction(tx *gorm.DB, transaction *model.WalletTransaction) error {
+	return tx.Create(transaction).Error
+}
+
+// SumWalletRefunds is the store credit refunded for an order so far.
+func SumWalletRefunds(db *gorm.DB, orderId int64) (int64, error) {
+	var refunded int64
+
+	err := db.Model(&model.WalletTransaction{}).Where("order_id = ? AND kind = ? AND source = ?", orderId, model.WalletCredit, model.WalletRefund).
+		Select("COALESCE(SUM(amount), 0)").Scan(&refunded).Error
+	return refunded, err
+}
+
+// GetWalletCredit returns the credit issued with an idempotency key.
+func GetWalletCredit(db *gorm.DB, idempotencyKey string) (*model.WalletTransaction, error) {
+	var transaction model.WalletTransaction
+
+	err := db.Preload("Entries", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Where("idempotency_key = ?", idempotencyKey).First(&transaction).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &transaction, nil
+}
+
+// ListWalletTransactions returns up to limit of a user's transactions with
+// their entries, newest first, starting below beforeId unless it is 0.
+func ListWalletTransactions(db *gorm.DB, username string, beforeId int64, limit int) ([]model.WalletTransaction, error) {
+	var transactions []model.WalletTransaction
+
+	query := db.Preload("Entries", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).Where("username = ?", username)
+	if beforeId > 0 {
+		query = query.Where("id < ?", beforeId)
+	}
+
+	err := query.Order("id DESC").Limit(limit).Find(&transactions).Error
+	return transactions, err
+}
+
+func orderedGroupOrderItems(db *gorm.DB) *gorm.DB {
+	return db.Order("username, menu_item_name")
+}
+
+func GetGroupOrderByInviteCode(db *gorm.DB, inviteCode string) (*model.GroupOrder, error) {
+	var groupOrder model.GroupOrder
+
+	err := db.Preload("Items", orderedGroupOrderItems).Where("invite_code = ?", inviteCode).First(&groupOrder).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &groupOrder, nil
+}
+
+// LockGroupOrder reads a group order with a row lock, so that items can't be
+// added while the host locks or submits it.
+func LockGroupOrder(tx *gorm.DB, inviteCode string) (*model.GroupOrder, error) {
+	var groupOrder model.GroupOrder
+
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Items", orderedGroupOrderItems).
+		Where("invite_code = ?", inviteCode).First(&groupOrder).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &groupOrder, nil
+}
+
+// SetGroupOrderItemQuantity stores how many of a menu item a participant
+// wants, removing the item when quantity is not positive.
+func SetGroupOrderItemQuantity(tx *gorm.DB, groupOrderId int64, username string, menuItemName string, quantity int32) error {
+	if quantity <= 0 {
+		return tx.Where("group_order_id = ? AND username = ? AND menu_item_name = ?", groupOrderId, username, menuItemName).
+			Delete(&model.GroupOrderItem{}).Error
+	}
+
+	item := model.GroupOrderItem{GroupOrderId: groupOrderId, Username: username, MenuItemName: menuItemName, Quantity: quantity}
+
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "group_order_id"}, {Name: "username"}, {Name: "menu_item_name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"quantity"}),
+	}).Create(&item).Error
+}
+
+func UpdateGroupOrderStatus(tx *gorm.DB, groupOrder *model.GroupOrder) error {
+	return tx.Model(groupOrder).Select("status", "order_id").Updates(groupOrder).Error
+}
+
+// LockOrder reads an order, without its items, with a row lock.
+func LockOrder(tx *gorm.DB, id int64) (*model.Order, error) {
+	var order model.Order
+
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&order).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &order, nil
+}
+
+// GetOrder returns an order with its items.
+func GetOrder(db *gorm.DB, id int64) (*model.Order, error) {
+	var order model.Order
+
+	err := db.Preload("Items").Where("id = ?", id).First(&order).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &order, nil
+}
+
+func IsRestaurantOwner(db *gorm.DB, username string, restaurantId string) (bool, error) {
+	var count int64
+
+	err := db.Model(&model.RestaurantOwner{}).Where("username = ? AND restaurant_id = ?", username, restaurantId).Count(&count).Error
+	return count > 0, err
+}
+
+// ListIncomingOrders returns the orders of a restaurant that wait for it to
+// accept them, with their items, the ones to answer first first.
+func ListIncomingOrders(db *gorm.DB, restaurantId string) ([]model.Order, error) {
+	var orders []model.Order
+
+	err := db.Preload("Items").Where("restaurant_id = ? AND status = ?", restaurantId, model.OrderAwaitingAcceptance).
+		Order("accept_by, id").Find(&orders).Error
+	return orders, err
+}
+
+// RejectExpiredOrders rejects up to limit orders that the restaurant didn't
+// accept by their accept_by, gives back their promotion uses, loyalty points
+// and store credit and returns them.
+// Rows locked by a restaurant accepting the order are skipped.
+func RejectExpiredOrders(db *gorm.DB, now time.Time, reason string, limit int) ([]model.Order, error) {
+	var orders []model.Order
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND accept_by <= ?", model.OrderAwaitingAcceptance, now).
+			Order("accept_by").Limit(limit).Find(&orders).Error
+		if err != nil {
+			return err
+		}
+
+		for i := range orders {
+			if err := ReleaseRedemption(tx, orders[i].Id); err != nil {
+				return err
+			}
+
+			if err := ReverseLoyaltyRedemption(tx, &orders[i]); err != nil {
+				return err
+			}
+
+			if err := ReverseWalletPayment(tx, &orders[i]); err != nil {
+				return err
+			}
+
+			orders[i].Status = model.OrderRejected
+			orders[i].CancellationReason = reason
+			if err := UpdateOrder(tx, &orders[i], "status", "cancellation_reason"); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+// LatestOrderChange returns the highest change_seq of a restaurant's orders,
+// 0 if it has none.
+func LatestOrderChange(db *gorm.DB, restaurantId string) (int64, error) {
+	var latest int64
+
+	err := db.Model(&model.Order{}).Where("restaurant_id = ?", restaurantId).Select("COALESCE(MAX(change_seq), 0)").Scan(&latest).Error
+	return latest, err
+}
+
+// ListOpenRestaurantOrders returns the orders of a restaurant that are not
+// final, as of change upTo, with their items, in change order.
+func ListOpenRestaurantOrders(db *gorm.DB, restaurantId string, upTo int64) ([]model.Order, error) {
+	var orders []model.Order
+
+	err := db.Preload("Items").
+		Where("restaurant_id = ? AND change_seq <= ? AND status NOT IN ?", restaurantId, upTo, []model.OrderStatus{model.OrderCancelled, model.OrderRejected, model.OrderDelivered}).
+		Order("change_seq").Find(&orders).Error
+	return orders, err
+}
+
+// ListOrderChanges returns up to limit orders of a restaurant whose
+// change_seq is above cursor, with their items, in change order. An order that
+// changed several times is returned once, in its current state.
+func ListOrderChanges(db *gorm.DB, restaurantId string, cursor int64, limit int) ([]model.Order, error) {
+	var orders []model.Order
+
+	err := db.Preload("Items").Where("restaurant_id = ? AND change_seq > ?", restaurantId, cursor).
+		Order("change_seq").Limit(limit).Find(&orders).Error
+	return orders, err
+}
+
+func IsSupportAgent(db *gorm.DB, username string) (bool, error) {
+	var count int64
+
+	err := db.Model(&model.SupportAgent{}).Where("username = ?", username).Count(&count).Error
+	return count > 0, err
+}
+
+// ListChatMessages returns up to limit messages of an order's chat with an
+// id above after, oldest first.
+func ListChatMessages(db *gorm.DB, orderId int64, after int64, limit int) ([]model.ChatMessage, error) {
+	var messages []model.ChatMessage
+
+	err := db.Where("order_id = ? AND id > ?", orderId, after).Order("id").Limit(limit).Find(&messages).Error
+	return messages, err
+}
+
+// installOrderChangeSequence makes the database number every change to an
+// order. Changes to the orders of one restaurant are serialized by an
+// advisory lock held until commit, so their numbers follow commit order and a
+// reader resuming after a number can't miss a change committed late.
+func installOrderChangeSequence(db *gorm.DB) error {
+	statements := []string{
+		`CREATE SEQUENCE IF NOT EXISTS order_change_seq`,
+		`CREATE OR REPLACE FUNCTION set_order_change_seq() RETURNS trigger AS $$
+		BEGIN
+			PERFORM pg_advisory_xact_lock(hashtext('orders:' || NEW.restaurant_id));
+			NEW.change_seq := nextval('order_change_seq');
+			RETURN NEW;
+		END
+		$$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS orders_change_seq ON orders`,
+		`CREATE TRIGGER orders_change_seq BEFORE INSERT OR UPDATE ON orders FOR EACH ROW EXECUTE FUNCTION set_order_change_seq()`,
+		// Numbers the orders stored before the trigger existed.
+		`UPDATE orders SET change_seq = 0 WHERE change_seq IS NULL`,
+	}
+
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// backfillNormalizedUsernames fills the normalized_username column for users
+// created before it existed, so the unique index can be built by AutoMigrate.
+// Usernames that differ only in case would break the index, so they are
+// reported for renaming instead, until the index exists.
+func backfillNormalizedUsernames(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&model.User{}) || migrator.HasIndex(&model.User{}, "NormalizedUsername") {
+		return nil
+	}
+
+	if !migrator.HasColumn(&model.User{}, "NormalizedUsername") {
+		if err := migrator.AddColumn(&model.User{}, "NormalizedUsername"); err != nil {
+			return err
+		}
+	}
+
+	if err := db.Exec("UPDATE users SET normalized_username = LOWER(TRIM(username))").Error; err != nil {
+		return err
+	}
+
+	var duplicates []string
+	err := db.Raw("SELECT STRING_AGG(username, ', ' ORDER BY username) FROM users GROUP BY normalized_username HAVING COUNT(*) > 1").
+		Scan(&duplicates).Error
+	if err != nil {
+		return err
+	}
+
+	if len(duplicates) > 0 {
+		return fmt.Errorf("usernames that differ only in case must be renamed before they can be made unique: %s", strings.Join(duplicates, "; "))
+	}
+
+	return nil
+}
+
+// migrateFloatPrices converts prices stored as floating point major units into
+// integer minor units. Those rows predate currencies, so they are assumed to
+// be in LegacyCurrency.
+func migrateFloatPrices(db *gorm.DB) error {
+	migrator := db.Migrator()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if migrator.HasColumn(&model.Order{}, "total_price") {
+			err := tx.Exec("UPDATE orders SET total_amount = ROUND(total_price * 100), subtotal_amount = ROUND(total_price * 100), currency = ? WHERE currency IS NULL OR currency = ''", LegacyCurrency).Error
+			if err != nil {
+				return err
+			}
+
+			if err := tx.Migrator().DropColumn(&model.Order{}, "total_price"); err != nil {
+				return err
+			}
+		}
+
+		if migrator.HasColumn(&model.OrderItem{}, "unit_price") {
+			err := tx.Exec("UPDATE order_items SET unit_amount = ROUND(unit_price * 100), line_amount = ROUND(line_total * 100)").Error
+			if err != nil {
+				return err
+			}
+
+			if err := tx.Migrator().DropColumn(&model.OrderItem{}, "unit_price"); err != nil {
+				return err
+			}
+
+			if err := tx.Migrator().DropColumn(&model.OrderItem{}, "line_total"); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// migrateLegacyMenuItems moves orders created when menu items were stored as a
+// JSON object of name to quantity into order_items, then drops the old column.
+// Unit prices were never recorded for those orders, so they can only be
+// recovered for single item orders.
+func migrateLegacyMenuItems(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&model.Order{}, "menu_items") {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var legacyOrders []struct {
+			Id          int64
+			TotalAmount int64
+			MenuItems   string
+		}
+
+		err := tx.Table("orders").Select("id, total_amount, menu_items").Where("menu_items IS NOT NULL AND menu_items <> ''").Scan(&legacyOrders).Error
+		if err != nil {
+			return err
+		}
+
+		for _, legacyOrder := range legacyOrders {
+			var menuItems map[string]int32
+			if err := json.Unmarshal([]byte(legacyOrder.MenuItems), &menuItems); err != nil {
+				return fmt.Errorf("order %d: %w", legacyOrder.Id, err)
+			}
+
+			var items []model.OrderItem
+			for name, quantity := range menuItems {
+				item := model.OrderItem{OrderId: legacyOrder.Id, MenuItemName: name, Quantity: quantity}
+				if len(menuItems) == 1 && quantity > 0 {
+					item.UnitAmount = legacyOrder.TotalAmount / int64(quantity)
+					item.LineAmount = legacyOrder.TotalAmount
+				}
+				items = append(items, item)
+			}
+
+			if len(items) > 0 {
+				if err := tx.Create(&items).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		return tx.Migrator().DropColumn(&model.Order{}, "menu_items")
+	})
+}
@@ -0,0 +1,102 @@
+package eta
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"time"
+
+	"orderService.com/go-orderService-grpc/geo"
+	"orderService.com/go-orderService-grpc/model"
+)
+
+// Config is the speed model used to estimate delivery times.
+type Config struct {
+	// SpeedKmph is the average speed of a courier on the road.
+	SpeedKmph float64 `json:"speed_kmph"`
+	// RoadFactor is how much longer the road is than the straight line
+	// between two ZIP code centroids.
+	RoadFactor float64 `json:"road_factor"`
+	// PickupMinutes is how long a courier takes to reach the restaurant
+	// once assigned; cooking and the ride to the restaurant overlap.
+	PickupMinutes float64 `json:"pickup_minutes"`
+	// DropMinutes is spent at the drop address, parking and handing over.
+	DropMinutes float64 `json:"drop_minutes"`
+	// DefaultPrepMinutes stands for the preparation time until the
+	// restaurant names one when accepting the order.
+	DefaultPrepMinutes float64 `json:"default_prep_minutes"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		SpeedKmph:          20,
+		RoadFactor:         1.3,
+		PickupMinutes:      10,
+		DropMinutes:        5,
+		DefaultPrepMinutes: 20,
+	}
+}
+
+// LoadConfig reads a JSON encoded Config, starting from DefaultConfig so that
+// the file only needs to list what it overrides.
+func LoadConfig(path string) (Config, error) {
+	config := DefaultConfig()
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+
+	if err := json.Unmarshal(content, &config); err != nil {
+		return Config{}, err
+	}
+
+	return config, nil
+}
+
+// Estimator estimates when orders are delivered. A nil Estimator knows no
+// distances, so it never estimates anything.
+type Estimator struct {
+	centroids geo.Centroids
+	config    Config
+}
+
+func NewEstimator(centroids geo.Centroids, config Config) *Estimator {
+	return &Estimator{centroids: centroids, config: config}
+}
+
+// Distance is the distance in meters between the pickup and drop addresses,
+// if both ZIP codes are known.
+func (estimator *Estimator) Distance(pickup *model.Address, drop *model.Address) (int64, bool) {
+	if estimator == nil {
+		return 0, false
+	}
+
+	meters, ok := estimator.centroids.Distance(pickup, drop)
+	return int64(math.Round(meters)), ok
+}
+
+// DefaultPrep is the preparation time assumed before the restaurant names one.
+func (estimator *Estimator) DefaultPrep() time.Duration {
+	return minutes(estimator.config.DefaultPrepMinutes)
+}
+
+// Estimate is when an order that still needs prep to be cooked at start is
+// delivered, meters away from the restaurant. The courier heads to the
+// restaurant while the order is cooked.
+func (estimator *Estimator) Estimate(start time.Time, prep time.Duration, meters int64) time.Time {
+	return estimator.AfterPickup(start.Add(max(prep, minutes(estimator.config.PickupMinutes))), meters)
+}
+
+// AfterPickup is when an order picked up at pickedUpAt, meters away from the
+// drop address, is delivered.
+func (estimator *Estimator) AfterPickup(pickedUpAt time.Time, meters int64) time.Time {
+	hours := float64(meters) * estimator.config.RoadFactor / 1000 / estimator.config.SpeedKmph
+	ride := time.Duration(hours * float64(time.Hour))
+
+	return pickedUpAt.Add(ride + minutes(estimator.config.DropMinutes)).Truncate(time.Second)
+}
+
+func minutes(value float64) time.Duration {
+	return time.Duration(value * float64(time.Minute))
+}
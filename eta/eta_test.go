@@ -0,0 +1,38 @@
+package eta
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"orderService.com/go-orderService-grpc/geo"
+	"orderService.com/go-orderService-grpc/model"
+)
+
+var now = time.Date(2024, 3, 10, 19, 0, 0, 0, time.UTC)
+
+func TestEstimate_CooksWhileCourierRidesToRestaurant(t *testing.T) {
+	estimator := NewEstimator(nil, Config{SpeedKmph: 20, RoadFactor: 1, PickupMinutes: 10, DropMinutes: 5})
+
+	// 5 km at 20 km/h is 15 minutes, after 25 minutes of cooking.
+	assert.Equal(t, now.Add(45*time.Minute), estimator.Estimate(now, 25*time.Minute, 5000))
+	// The courier needs 10 minutes to reach the restaurant anyway.
+	assert.Equal(t, now.Add(30*time.Minute), estimator.Estimate(now, 5*time.Minute, 5000))
+	assert.Equal(t, now.Add(20*time.Minute), estimator.AfterPickup(now, 5000))
+}
+
+func TestDistance_NeedsBothZipcodes(t *testing.T) {
+	estimator := NewEstimator(geo.Centroids{"560001": {Lat: 12, Lng: 77}, "560002": {Lat: 12.01, Lng: 77}}, DefaultConfig())
+
+	meters, ok := estimator.Distance(&model.Address{Zipcode: "560001"}, &model.Address{Zipcode: "560002"})
+	assert.True(t, ok)
+	assert.Equal(t, int64(1112), meters)
+
+	_, ok = estimator.Distance(&model.Address{Zipcode: "560001"}, &model.Address{Zipcode: "110001"})
+	assert.False(t, ok)
+
+	var disabled *Estimator
+	_, ok = disabled.Distance(&model.Address{Zipcode: "560001"}, &model.Address{Zipcode: "560002"})
+	assert.False(t, ok)
+}
@@ -0,0 +1,134 @@
+package geo
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"orderService.com/go-orderService-grpc/model"
+)
+
+const earthRadiusMeters = 6371000
+
+// Point is a position in decimal degrees.
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// Distance is the great circle distance between two points in meters.
+func Distance(a Point, b Point) float64 {
+	lat1, lat2 := radians(a.Lat), radians(b.Lat)
+	dLat, dLng := lat2-lat1, radians(b.Lng-a.Lng)
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+// Polygon is an area bounded by its vertices, in order. The last vertex
+// connects back to the first. Edges are straight in degrees, which is close
+// enough over the size of a city.
+type Polygon []Point
+
+// Contains tells whether point lies inside the polygon, by counting how many
+// edges a ray going east from it crosses.
+func (polygon Polygon) Contains(point Point) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		a, b := polygon[i], polygon[j]
+		if (a.Lat > point.Lat) != (b.Lat > point.Lat) &&
+			point.Lng < a.Lng+(point.Lat-a.Lat)*(b.Lng-a.Lng)/(b.Lat-a.Lat) {
+			inside = !inside
+		}
+	}
+
+	return inside
+}
+
+// Centroids locates addresses by the centre of their ZIP code. Addresses are
+// only known down to the ZIP code, which is precise enough to estimate travel.
+type Centroids map[string]Point
+
+// LoadCentroids reads a CSV file of zipcode,latitude,longitude rows. A first
+// row that isn't numeric is taken as a header.
+func LoadCentroids(path string) (Centroids, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadCentroids(file)
+}
+
+func ReadCentroids(r io.Reader) (Centroids, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+
+	centroids := Centroids{}
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return centroids, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		lat, latErr := strconv.ParseFloat(record[1], 64)
+		lng, lngErr := strconv.ParseFloat(record[2], 64)
+		switch {
+		case (latErr != nil || lngErr != nil) && line == 1:
+			continue
+		case latErr != nil || lngErr != nil:
+			return nil, fmt.Errorf("line %d: invalid coordinates %q, %q", line, record[1], record[2])
+		case lat < -90 || lat > 90 || lng < -180 || lng > 180:
+			return nil, fmt.Errorf("line %d: coordinates out of range", line)
+		}
+
+		centroids[NormalizeZipcode(record[0])] = Point{Lat: lat, Lng: lng}
+	}
+}
+
+// Locate returns the centroid of the address's ZIP code.
+func (centroids Centroids) Locate(address *model.Address) (Point, bool) {
+	if address == nil {
+		return Point{}, false
+	}
+
+	point, ok := centroids[NormalizeZipcode(address.Zipcode)]
+	return point, ok
+}
+
+// Distance is the distance in meters between two addresses, if both ZIP
+// codes are known.
+func (centroids Centroids) Distance(from *model.Address, to *model.Address) (float64, bool) {
+	a, ok := centroids.Locate(from)
+	if !ok {
+		return 0, false
+	}
+
+	b, ok := centroids.Locate(to)
+	if !ok {
+		return 0, false
+	}
+
+	return Distance(a, b), true
+}
+
+// NormalizeZipcode makes ZIP codes written with different case or spacing
+// compare equal.
+func NormalizeZipcode(zipcode string) string {
+	return strings.ReplaceAll(strings.ToUpper(strings.TrimSpace(zipcode)), " ", "")
+}
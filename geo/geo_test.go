@@ -0,0 +1,47 @@
+package geo
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"orderService.com/go-orderService-grpc/model"
+)
+
+func TestDistance(t *testing.T) {
+	// One degree of latitude is about 111.2 km anywhere.
+	assert.InDelta(t, 111195, Distance(Point{Lat: 12, Lng: 77}, Point{Lat: 13, Lng: 77}), 1)
+	assert.Equal(t, 0.0, Distance(Point{Lat: 12, Lng: 77}, Point{Lat: 12, Lng: 77}))
+}
+
+func TestReadCentroids(t *testing.T) {
+	centroids, err := ReadCentroids(strings.NewReader("zipcode,latitude,longitude\n560001, 12.0, 77.0\n560002,13.0,77.0\n"))
+	assert.Nil(t, err)
+
+	meters, ok := centroids.Distance(&model.Address{Zipcode: "560001"}, &model.Address{Zipcode: " 560002 "})
+	assert.True(t, ok)
+	assert.InDelta(t, 111195, meters, 1)
+
+	_, ok = centroids.Distance(&model.Address{Zipcode: "560001"}, &model.Address{Zipcode: "999999"})
+	assert.False(t, ok)
+}
+
+func TestReadCentroids_RejectsInvalidRows(t *testing.T) {
+	_, err := ReadCentroids(strings.NewReader("560001,12.0,77.0\n560002,north,77.0\n"))
+	assert.NotNil(t, err)
+
+	_, err = ReadCentroids(strings.NewReader("560001,95.0,77.0\n"))
+	assert.NotNil(t, err)
+}
+
+func TestPolygonContains(t *testing.T) {
+	// An L shape: the square from (0,0) to (2,2) without its top right quarter.
+	polygon := Polygon{{Lat: 0, Lng: 0}, {Lat: 0, Lng: 2}, {Lat: 1, Lng: 2}, {Lat: 1, Lng: 1}, {Lat: 2, Lng: 1}, {Lat: 2, Lng: 0}}
+
+	assert.True(t, polygon.Contains(Point{Lat: 0.5, Lng: 1.5}))
+	assert.True(t, polygon.Contains(Point{Lat: 1.5, Lng: 0.5}))
+	assert.False(t, polygon.Contains(Point{Lat: 1.5, Lng: 1.5}))
+	assert.False(t, polygon.Contains(Point{Lat: 3, Lng: 0.5}))
+	assert.False(t, Polygon{}.Contains(Point{}))
+}
@@ -0,0 +1,198 @@
+package hours
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	dateLayout    = "2006-01-02"
+	minutesPerDay = 24 * 60
+	// lookaheadDays is how far NextOpening looks, a week and a day so that
+	// today's window is found again next week.
+	lookaheadDays = 8
+)
+
+// Window is a span of opening hours within a day, as "15:04" times. A Close
+// at or before Open ends the window after midnight, on the next day.
+type Window struct {
+	Open  string `json:"open"`
+	Close string `json:"close"`
+}
+
+// Holiday replaces the regular hours on Date, given as "2006-01-02". A
+// holiday without windows is closed all day.
+type Holiday struct {
+	Date    string   `json:"date"`
+	Windows []Window `json:"windows"`
+}
+
+// Schedule is the opening hours of a restaurant as the catalog describes
+// them. Weekly is keyed by the lower case English name of the day.
+type Schedule struct {
+	TimeZone string              `json:"time_zone"`
+	Weekly   map[string][]Window `json:"weekly"`
+	Holidays []Holiday           `json:"holidays"`
+}
+
+// Hours tells when a restaurant is open. A nil Hours stands for a restaurant
+// without opening hours, which is always open.
+type Hours struct {
+	location *time.Location
+	weekly   map[time.Weekday][]span
+	holidays map[string][]span
+}
+
+// span is a window in minutes since midnight. close may run into the next
+// day.
+type span struct {
+	open  int
+	close int
+}
+
+// Parse checks a schedule. A schedule that lists no hours at all gives a nil
+// Hours.
+func Parse(schedule Schedule) (*Hours, error) {
+	if len(schedule.Weekly) == 0 && len(schedule.Holidays) == 0 {
+		return nil, nil
+	}
+
+	location, err := time.LoadLocation(schedule.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q: %w", schedule.TimeZone, err)
+	}
+
+	hours := &Hours{location: location, weekly: map[time.Weekday][]span{}, holidays: map[string][]span{}}
+
+	for day, windows := range schedule.Weekly {
+		weekday, ok := weekdays[strings.ToLower(day)]
+		if !ok {
+			return nil, fmt.Errorf("invalid day %q", day)
+		}
+
+		if hours.weekly[weekday], err = parseWindows(windows); err != nil {
+			return nil, fmt.Errorf("%s: %w", day, err)
+		}
+	}
+
+	for _, holiday := range schedule.Holidays {
+		if _, err := time.Parse(dateLayout, holiday.Date); err != nil {
+			return nil, fmt.Errorf("invalid holiday date %q", holiday.Date)
+		}
+
+		if hours.holidays[holiday.Date], err = parseWindows(holiday.Windows); err != nil {
+			return nil, fmt.Errorf("%s: %w", holiday.Date, err)
+		}
+	}
+
+	return hours, nil
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+func parseWindows(windows []Window) ([]span, error) {
+	spans := make([]span, 0, len(windows))
+
+	for _, window := range windows {
+		open, err := parseClock(window.Open)
+		if err != nil {
+			return nil, err
+		}
+
+		closing, err := parseClock(window.Close)
+		if err != nil {
+			return nil, err
+		}
+
+		if closing <= open {
+			closing += minutesPerDay
+		}
+
+		spans = append(spans, span{open: open, close: closing})
+	}
+
+	return spans, nil
+}
+
+func parseClock(clock string) (int, error) {
+	var hour, minute int
+	if _, err := fmt.Sscanf(clock, "%d:%d", &hour, &minute); err != nil || hour < 0 || minute < 0 || minute > 59 || hour*60+minute > minutesPerDay {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", clock)
+	}
+
+	return hour*60 + minute, nil
+}
+
+// OpenAt tells whether the restaurant is open at t. Windows of the day before
+// that run past midnight count too.
+func (hours *Hours) OpenAt(t time.Time) bool {
+	if hours == nil {
+		return true
+	}
+
+	for _, offset := range []int{-1, 0} {
+		for _, window := range hours.windowsOn(t, offset) {
+			if !t.Before(window.from) && t.Before(window.until) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// NextOpening is t if the restaurant is open at t, or else when it opens
+// next within the coming week.
+func (hours *Hours) NextOpening(t time.Time) (time.Time, bool) {
+	if hours.OpenAt(t) {
+		return t, true
+	}
+
+	var next time.Time
+	for offset := 0; offset < lookaheadDays; offset++ {
+		for _, window := range hours.windowsOn(t, offset) {
+			if window.from.After(t) && (next.IsZero() || window.from.Before(next)) {
+				next = window.from
+			}
+		}
+	}
+
+	return next, !next.IsZero()
+}
+
+type opening struct {
+	from  time.Time
+	until time.Time
+}
+
+// windowsOn returns the openings of the day offset days from t's day, in the
+// restaurant's time zone.
+func (hours *Hours) windowsOn(t time.Time, offset int) []opening {
+	local := t.In(hours.location)
+	year, month, day := local.Date()
+	date := time.Date(year, month, day+offset, 0, 0, 0, 0, hours.location)
+
+	spans, ok := hours.holidays[date.Format(dateLayout)]
+	if !ok {
+		spans = hours.weekly[date.Weekday()]
+	}
+
+	openings := make([]opening, 0, len(spans))
+	for _, span := range spans {
+		openings = append(openings, opening{
+			from:  time.Date(year, month, day+offset, 0, span.open, 0, 0, hours.location),
+			until: time.Date(year, month, day+offset, 0, span.close, 0, 0, hours.location),
+		})
+	}
+
+	return openings
+}
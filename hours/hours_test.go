@@ -0,0 +1,91 @@
+package hours
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var kolkata, _ = time.LoadLocation("Asia/Kolkata")
+
+func parse(t *testing.T, schedule Schedule) *Hours {
+	hours, err := Parse(schedule)
+	assert.Nil(t, err)
+	return hours
+}
+
+func at(day int, hour int, minute int) time.Time {
+	// March 2024: the 8th is a Friday.
+	return time.Date(2024, 3, day, hour, minute, 0, 0, kolkata)
+}
+
+func TestOpenAt_WindowsInRestaurantTimeZone(t *testing.T) {
+	hours := parse(t, Schedule{
+		TimeZone: "Asia/Kolkata",
+		Weekly: map[string][]Window{
+			"friday":   {{Open: "11:00", Close: "15:00"}, {Open: "18:00", Close: "02:00"}},
+			"Saturday": {{Open: "12:00", Close: "23:00"}},
+		},
+	})
+
+	assert.False(t, hours.OpenAt(at(8, 10, 59)))
+	assert.True(t, hours.OpenAt(at(8, 11, 0)))
+	assert.False(t, hours.OpenAt(at(8, 15, 0)))
+	assert.True(t, hours.OpenAt(at(8, 20, 0).UTC()))
+	// Friday night runs into Saturday.
+	assert.True(t, hours.OpenAt(at(9, 1, 30)))
+	assert.False(t, hours.OpenAt(at(9, 2, 0)))
+	assert.False(t, hours.OpenAt(at(10, 12, 0)))
+}
+
+func TestOpenAt_HolidaysReplaceRegularHours(t *testing.T) {
+	hours := parse(t, Schedule{
+		TimeZone: "Asia/Kolkata",
+		Weekly:   map[string][]Window{"friday": {{Open: "11:00", Close: "23:00"}}, "saturday": {{Open: "11:00", Close: "23:00"}}},
+		Holidays: []Holiday{{Date: "2024-03-08"}, {Date: "2024-03-09", Windows: []Window{{Open: "17:00", Close: "21:00"}}}},
+	})
+
+	assert.False(t, hours.OpenAt(at(8, 12, 0)))
+	assert.False(t, hours.OpenAt(at(9, 12, 0)))
+	assert.True(t, hours.OpenAt(at(9, 18, 0)))
+}
+
+func TestNextOpening(t *testing.T) {
+	hours := parse(t, Schedule{
+		TimeZone: "Asia/Kolkata",
+		Weekly:   map[string][]Window{"friday": {{Open: "11:00", Close: "15:00"}}},
+		Holidays: []Holiday{{Date: "2024-03-15"}},
+	})
+
+	next, ok := hours.NextOpening(at(8, 9, 0))
+	assert.True(t, ok)
+	assert.True(t, at(8, 11, 0).Equal(next))
+
+	next, ok = hours.NextOpening(at(8, 12, 0))
+	assert.True(t, ok)
+	assert.True(t, at(8, 12, 0).Equal(next))
+
+	// Next Friday is a holiday, and the one after is too far off.
+	_, ok = hours.NextOpening(at(8, 16, 0))
+	assert.False(t, ok)
+}
+
+func TestParse(t *testing.T) {
+	hours, err := Parse(Schedule{})
+	assert.Nil(t, err)
+	assert.Nil(t, hours)
+	assert.True(t, hours.OpenAt(at(8, 3, 0)))
+
+	_, err = Parse(Schedule{TimeZone: "Mars/Olympus", Weekly: map[string][]Window{"friday": {{Open: "11:00", Close: "15:00"}}}})
+	assert.ErrorContains(t, err, "time zone")
+
+	_, err = Parse(Schedule{Weekly: map[string][]Window{"fri": {{Open: "11:00", Close: "15:00"}}}})
+	assert.ErrorContains(t, err, "invalid day")
+
+	_, err = Parse(Schedule{Weekly: map[string][]Window{"friday": {{Open: "11", Close: "25:00"}}}})
+	assert.ErrorContains(t, err, "invalid time")
+
+	_, err = Parse(Schedule{Holidays: []Holiday{{Date: "25/12/2024"}}})
+	assert.ErrorContains(t, err, "holiday date")
+}
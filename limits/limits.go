@@ -0,0 +1,145 @@
+package limits
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"orderService.com/go-orderService-grpc/model"
+	"orderService.com/go-orderService-grpc/money"
+)
+
+// Kinds of Violation.
+const (
+	MinSubtotal      = "MIN_SUBTOTAL"
+	MaxTotalQuantity = "MAX_TOTAL_QUANTITY"
+	MaxDistinctItems = "MAX_DISTINCT_ITEMS"
+	MaxOrderValue    = "MAX_ORDER_VALUE"
+)
+
+// Limits bound what a single order may hold. Amounts are in minor units of
+// the order's currency, and a zero leaves that limit off.
+type Limits struct {
+	MinSubtotal      int64 `json:"min_subtotal"`
+	MaxTotalQuantity int32 `json:"max_total_quantity"`
+	MaxDistinctItems int   `json:"max_distinct_items"`
+	MaxOrderValue    int64 `json:"max_order_value"`
+}
+
+// Config holds the limits of each restaurant. Restaurants not listed get
+// Default; if there is no Default either, their orders aren't limited.
+type Config struct {
+	Default     *Limits           `json:"default"`
+	Restaurants map[string]Limits `json:"restaurants"`
+}
+
+// LoadConfig reads a JSON encoded Config.
+func LoadConfig(path string) (Config, error) {
+	var config Config
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+
+	if err := json.Unmarshal(content, &config); err != nil {
+		return Config{}, err
+	}
+
+	if config.Default != nil {
+		if err := config.Default.validate(); err != nil {
+			return Config{}, fmt.Errorf("default limits: %w", err)
+		}
+	}
+
+	for restaurantId, limits := range config.Restaurants {
+		if err := limits.validate(); err != nil {
+			return Config{}, fmt.Errorf("limits of restaurant %s: %w", restaurantId, err)
+		}
+	}
+
+	return config, nil
+}
+
+func (limits Limits) validate() error {
+	switch {
+	case limits.MinSubtotal < 0 || limits.MaxTotalQuantity < 0 || limits.MaxDistinctItems < 0 || limits.MaxOrderValue < 0:
+		return errors.New("limits must not be negative")
+	case limits.MaxOrderValue > 0 && limits.MinSubtotal > limits.MaxOrderValue:
+		return errors.New("min_subtotal must not exceed max_order_value")
+	}
+
+	return nil
+}
+
+// Order is what the limits are checked against. Total is the amount the
+// customer pays, fees and taxes included.
+type Order struct {
+	Subtotal money.Money
+	Total    money.Money
+	Items    []model.OrderItem
+}
+
+// Violation is a limit an order breaks. Limit and Actual are in minor units
+// for amounts; Short is how much has to be added to meet a minimum, or
+// removed to stay within a maximum.
+type Violation struct {
+	Kind   string
+	Limit  int64
+	Actual int64
+	Short  int64
+}
+
+// Checker checks orders against the limits of their restaurant. A nil
+// Checker has no limits.
+type Checker struct {
+	config Config
+}
+
+func NewChecker(config Config) *Checker {
+	return &Checker{config: config}
+}
+
+// Check returns the limits the order breaks, if any.
+func (checker *Checker) Check(restaurantId string, order Order) []Violation {
+	if checker == nil {
+		return nil
+	}
+
+	limits, ok := checker.config.Restaurants[restaurantId]
+	if !ok {
+		if checker.config.Default == nil {
+			return nil
+		}
+
+		limits = *checker.config.Default
+	}
+
+	var quantity int64
+	distinct := map[string]bool{}
+	for _, item := range order.Items {
+		quantity += int64(item.Quantity)
+		distinct[item.MenuItemId] = true
+	}
+
+	var violations []Violation
+
+	if limits.MinSubtotal > 0 && order.Subtotal.Amount < limits.MinSubtotal {
+		violations = append(violations, Violation{Kind: MinSubtotal, Limit: limits.MinSubtotal, Actual: order.Subtotal.Amount, Short: limits.MinSubtotal - order.Subtotal.Amount})
+	}
+
+	if limits.MaxTotalQuantity > 0 && quantity > int64(limits.MaxTotalQuantity) {
+		violations = append(violations, Violation{Kind: MaxTotalQuantity, Limit: int64(limits.MaxTotalQuantity), Actual: quantity, Short: quantity - int64(limits.MaxTotalQuantity)})
+	}
+
+	if limits.MaxDistinctItems > 0 && len(distinct) > limits.MaxDistinctItems {
+		violations = append(violations, Violation{Kind: MaxDistinctItems, Limit: int64(limits.MaxDistinctItems), Actual: int64(len(distinct)), Short: int64(len(distinct) - limits.MaxDistinctItems)})
+	}
+
+	if limits.MaxOrderValue > 0 && order.Total.Amount > limits.MaxOrderValue {
+		violations = append(violations, Violation{Kind: MaxOrderValue, Limit: limits.MaxOrderValue, Actual: order.Total.Amount, Short: order.Total.Amount - limits.MaxOrderValue})
+	}
+
+	return violations
+}
@@ -0,0 +1,62 @@
+package limits
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"orderService.com/go-orderService-grpc/model"
+	"orderService.com/go-orderService-grpc/money"
+)
+
+func order(subtotal int64, total int64, items ...model.OrderItem) Order {
+	return Order{Subtotal: money.New(subtotal, "INR"), Total: money.New(total, "INR"), Items: items}
+}
+
+func item(id string, quantity int32) model.OrderItem {
+	return model.OrderItem{MenuItemId: id, Quantity: quantity}
+}
+
+func TestCheck_MinSubtotal(t *testing.T) {
+	checker := NewChecker(Config{Restaurants: map[string]Limits{"1": {MinSubtotal: 20000}}})
+
+	assert.Equal(t, []Violation{{Kind: MinSubtotal, Limit: 20000, Actual: 15000, Short: 5000}}, checker.Check("1", order(15000, 18000, item("a", 1))))
+	assert.Empty(t, checker.Check("1", order(20000, 23000, item("a", 1))))
+	assert.Empty(t, checker.Check("2", order(100, 100, item("a", 1))))
+}
+
+func TestCheck_Maximums(t *testing.T) {
+	checker := NewChecker(Config{Default: &Limits{MaxTotalQuantity: 10, MaxDistinctItems: 2, MaxOrderValue: 500000}})
+
+	assert.Empty(t, checker.Check("1", order(10000, 12000, item("a", 6), item("b", 4))))
+	assert.Equal(t, []Violation{
+		{Kind: MaxTotalQuantity, Limit: 10, Actual: 12, Short: 2},
+		{Kind: MaxDistinctItems, Limit: 2, Actual: 3, Short: 1},
+		{Kind: MaxOrderValue, Limit: 500000, Actual: 510000, Short: 10000},
+	}, checker.Check("1", order(480000, 510000, item("a", 6), item("b", 4), item("c", 1), item("a", 1))))
+}
+
+func TestCheck_NilCheckerHasNoLimits(t *testing.T) {
+	var checker *Checker
+
+	assert.Empty(t, checker.Check("1", order(0, 0)))
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "limits.json")
+
+	assert.Nil(t, os.WriteFile(path, []byte(`{"default":{"max_total_quantity":50},"restaurants":{"1":{"min_subtotal":20000,"max_order_value":1000000}}}`), 0o600))
+	config, err := LoadConfig(path)
+	assert.Nil(t, err)
+	assert.Equal(t, Config{Default: &Limits{MaxTotalQuantity: 50}, Restaurants: map[string]Limits{"1": {MinSubtotal: 20000, MaxOrderValue: 1000000}}}, config)
+
+	assert.Nil(t, os.WriteFile(path, []byte(`{"restaurants":{"1":{"min_subtotal":20000,"max_order_value":10000}}}`), 0o600))
+	_, err = LoadConfig(path)
+	assert.ErrorContains(t, err, "restaurant 1")
+
+	assert.Nil(t, os.WriteFile(path, []byte(`{"default":{"max_distinct_items":-1}}`), 0o600))
+	_, err = LoadConfig(path)
+	assert.ErrorContains(t, err, "negative")
+}
@@ -0,0 +1,133 @@
+package loyalty
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"orderService.com/go-orderService-grpc/money"
+)
+
+// Config sets how points are earned and what they are worth.
+type Config struct {
+	// PointsPerUnit are earned per major currency unit paid for a
+	// delivered order.
+	PointsPerUnit int64 `json:"points_per_unit"`
+	// PointValue is what a point takes off an order, in hundredths of a
+	// major currency unit: paise for INR, and a quarter of a yen for 25 in
+	// JPY, which has no minor unit.
+	PointValue int64 `json:"point_value"`
+	// ExpiryDays is how long earned points can be spent.
+	ExpiryDays int `json:"expiry_days"`
+}
+
+func DefaultConfig() Config {
+	return Config{PointsPerUnit: 1, PointValue: 25, ExpiryDays: 365}
+}
+
+// LoadConfig reads a JSON encoded Config, starting from DefaultConfig so that
+// the file only needs to list what it overrides.
+func LoadConfig(path string) (Config, error) {
+	config := DefaultConfig()
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+
+	if err := json.Unmarshal(content, &config); err != nil {
+		return Config{}, err
+	}
+
+	if config.PointsPerUnit < 0 || config.PointValue <= 0 || config.ExpiryDays <= 0 {
+		return Config{}, errors.New("point_value and expiry_days must be positive, points_per_unit not negative")
+	}
+
+	return config, nil
+}
+
+// Program applies a Config. A nil Program earns no points and gives them no
+// value.
+type Program struct {
+	config Config
+}
+
+func NewProgram(config Config) *Program {
+	return &Program{config: config}
+}
+
+// Earned is how many points paying for an order earns. Only whole currency
+// units count.
+func (program *Program) Earned(paid money.Money) int64 {
+	if program == nil || paid.Amount <= 0 {
+		return 0
+	}
+
+	exponent, err := money.Exponent(paid.Currency)
+	if err != nil {
+		return 0
+	}
+
+	return paid.Amount / int64(math.Pow10(exponent)) * program.config.PointsPerUnit
+}
+
+// ExpiresAt is when points earned at earnedAt expire.
+func (program *Program) ExpiresAt(earnedAt time.Time) time.Time {
+	return earnedAt.AddDate(0, 0, program.config.ExpiryDays)
+}
+
+// Redeem spends up to points on an order whose items are worth up to, and
+// returns the points spent and the discount they give, rounded down to the
+// currency's minor unit. No more points are spent than the items are worth.
+func (program *Program) Redeem(points int64, upTo money.Money) (int64, money.Money) {
+	if program == nil || points <= 0 || upTo.Amount <= 0 {
+		return 0, money.New(0, upTo.Currency)
+	}
+
+	exponent, err := money.Exponent(upTo.Currency)
+	if err != nil {
+		return 0, money.New(0, upTo.Currency)
+	}
+
+	// A point is worth PointValue * minorUnits / 100 minor units, which is a
+	// fraction for currencies with fewer than two decimals.
+	minorUnits := int64(math.Pow10(exponent))
+	spent := min(points, upTo.Amount*100/(program.config.PointValue*minorUnits))
+	return spent, money.New(spent*program.config.PointValue*minorUnits/100, upTo.Currency)
+}
+
+// Lot is a batch of earned points.
+type Lot struct {
+	Points    int64
+	ExpiresAt time.Time
+}
+
+// Remaining works out what is left of each lot given the balance, spending
+// the lots that expire first before the others. Lots with nothing left are
+// dropped.
+func Remaining(lots []Lot, balance int64) []Lot {
+	sorted := append([]Lot(nil), lots...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].ExpiresAt.Before(sorted[j].ExpiresAt) })
+
+	var earned int64
+	for _, lot := range sorted {
+		earned += lot.Points
+	}
+
+	spent := max(earned-balance, 0)
+
+	var remaining []Lot
+	for _, lot := range sorted {
+		used := min(lot.Points, spent)
+		spent -= used
+
+		if left := lot.Points - used; left > 0 {
+			remaining = append(remaining, Lot{Points: left, ExpiresAt: lot.ExpiresAt})
+		}
+	}
+
+	return remaining
+}
@@ -0,0 +1,85 @@
+package loyalty
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"orderService.com/go-orderService-grpc/money"
+)
+
+var now = time.Date(2024, 3, 8, 19, 0, 0, 0, time.UTC)
+
+func TestEarned_WholeUnitsOnly(t *testing.T) {
+	program := NewProgram(Config{PointsPerUnit: 2, PointValue: 25, ExpiryDays: 30})
+
+	assert.Equal(t, int64(250), program.Earned(money.New(12599, "INR")))
+	assert.Equal(t, int64(10), program.Earned(money.New(5, "JPY")))
+	assert.Equal(t, int64(0), program.Earned(money.New(-100, "INR")))
+
+	var disabled *Program
+	assert.Equal(t, int64(0), disabled.Earned(money.New(12599, "INR")))
+}
+
+func TestRedeem_SpendsNoMoreThanTheItemsAreWorth(t *testing.T) {
+	program := NewProgram(Config{PointsPerUnit: 1, PointValue: 25, ExpiryDays: 30})
+
+	spent, discount := program.Redeem(100, money.New(8000, "INR"))
+	assert.Equal(t, int64(100), spent)
+	assert.Equal(t, money.New(2500, "INR"), discount)
+
+	spent, discount = program.Redeem(1000, money.New(8010, "INR"))
+	assert.Equal(t, int64(320), spent)
+	assert.Equal(t, money.New(8000, "INR"), discount)
+}
+
+func TestRedeem_ScalesPointValueToTheCurrency(t *testing.T) {
+	program := NewProgram(Config{PointsPerUnit: 1, PointValue: 25, ExpiryDays: 30})
+
+	// A point is worth a quarter of a yen, as it is a quarter of a rupee.
+	spent, discount := program.Redeem(100, money.New(8000, "JPY"))
+	assert.Equal(t, int64(100), spent)
+	assert.Equal(t, money.New(25, "JPY"), discount)
+
+	spent, discount = program.Redeem(1000, money.New(80, "JPY"))
+	assert.Equal(t, int64(320), spent)
+	assert.Equal(t, money.New(80, "JPY"), discount)
+
+	spent, discount = program.Redeem(100, money.New(80000, "KWD"))
+	assert.Equal(t, int64(100), spent)
+	assert.Equal(t, money.New(25000, "KWD"), discount)
+}
+
+func TestRemaining_SpendsLotsThatExpireFirst(t *testing.T) {
+	lots := []Lot{
+		{Points: 300, ExpiresAt: now.AddDate(0, 0, 10)},
+		{Points: 100, ExpiresAt: now.AddDate(0, 0, -1)},
+		{Points: 200, ExpiresAt: now.AddDate(0, 0, -2)},
+	}
+
+	// 150 of the 600 points were spent, all from the lot expiring first.
+	assert.Equal(t, []Lot{
+		{Points: 50, ExpiresAt: now.AddDate(0, 0, -2)},
+		{Points: 100, ExpiresAt: now.AddDate(0, 0, -1)},
+		{Points: 300, ExpiresAt: now.AddDate(0, 0, 10)},
+	}, Remaining(lots, 450))
+
+	assert.Equal(t, []Lot{{Points: 250, ExpiresAt: now.AddDate(0, 0, 10)}}, Remaining(lots, 250))
+	assert.Empty(t, Remaining(lots, 0))
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "loyalty.json")
+
+	assert.Nil(t, os.WriteFile(path, []byte(`{"expiry_days":90}`), 0o600))
+	config, err := LoadConfig(path)
+	assert.Nil(t, err)
+	assert.Equal(t, Config{PointsPerUnit: 1, PointValue: 25, ExpiryDays: 90}, config)
+
+	assert.Nil(t, os.WriteFile(path, []byte(`{"point_value":0}`), 0o600))
+	_, err = LoadConfig(path)
+	assert.ErrorContains(t, err, "point_value")
+}
@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// Cart holds the menu items a user intends to order. A user has at most one
+// cart, and it only ever contains items of a single restaurant. Prices are
+// not stored; they are looked up in the catalog whenever the cart is read.
+type Cart struct {
+	Id           int64      `json:"id" gorm:"primaryKey;autoIncrement:true"`
+	Username     string     `json:"username" gorm:"uniqueIndex"`
+	RestaurantId string     `json:"restaurant_id"`
+	Items        []CartItem `json:"items" gorm:"foreignKey:CartId"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+type CartItem struct {
+	Id           int64  `json:"id" gorm:"primaryKey;autoIncrement:true"`
+	CartId       int64  `json:"cart_id" gorm:"uniqueIndex:idx_cart_item"`
+	MenuItemName string `json:"menu_item_name" gorm:"uniqueIndex:idx_cart_item"`
+	Quantity     int32  `json:"quantity"`
+}
+
+// MenuItems returns the cart in the shape of an order request.
+func (cart *Cart) MenuItems() map[string]int32 {
+	menuItems := make(map[string]int32, len(cart.Items))
+	for _, item := range cart.Items {
+		menuItems[item.MenuItemName] = item.Quantity
+	}
+
+	return menuItems
+}
@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+type ChatRole string
+
+const (
+	ChatCustomer ChatRole = "CUSTOMER"
+	ChatCourier  ChatRole = "COURIER"
+	ChatSupport  ChatRole = "SUPPORT"
+)
+
+// ChatMessage is a message sent in the chat of an order.
+type ChatMessage struct {
+	Id             int64     `json:"id" gorm:"primaryKey;autoIncrement:true"`
+	OrderId        int64     `json:"order_id" gorm:"index"`
+	SenderUsername string    `json:"sender_username"`
+	SenderRole     ChatRole  `json:"sender_role"`
+	Body           string    `json:"body"`
+	SentAt         time.Time `json:"sent_at"`
+}
+
+// SupportAgent lets a user join the chat of any order.
+type SupportAgent struct {
+	Id       int64  `json:"id" gorm:"primaryKey;autoIncrement:true"`
+	Username string `json:"username" gorm:"uniqueIndex"`
+}
@@ -0,0 +1,60 @@
+package model
+
+import "time"
+
+type GroupOrderStatus string
+
+const (
+	// GroupOrderOpen accepts items from anyone with the invite code.
+	GroupOrderOpen GroupOrderStatus = "OPEN"
+	// GroupOrderLocked is closed for changes and waits for the host to submit.
+	GroupOrderLocked    GroupOrderStatus = "LOCKED"
+	GroupOrderSubmitted GroupOrderStatus = "SUBMITTED"
+)
+
+// GroupOrder is a cart shared through its InviteCode. Participants add their
+// own items; the host submits it as a single Order placed under the host's
+// name and delivered to the host's address.
+type GroupOrder struct {
+	Id           int64            `json:"id" gorm:"primaryKey;autoIncrement:true"`
+	InviteCode   string           `json:"invite_code" gorm:"uniqueIndex;size:16"`
+	HostUsername string           `json:"host_username" gorm:"index"`
+	RestaurantId string           `json:"restaurant_id"`
+	Status       GroupOrderStatus `json:"status"`
+	OrderId      *int64           `json:"order_id"`
+	Items        []GroupOrderItem `json:"items" gorm:"foreignKey:GroupOrderId"`
+	CreatedAt    time.Time        `json:"created_at"`
+}
+
+type GroupOrderItem struct {
+	Id           int64  `json:"id" gorm:"primaryKey;autoIncrement:true"`
+	GroupOrderId int64  `json:"group_order_id" gorm:"uniqueIndex:idx_group_order_item"`
+	Username     string `json:"username" gorm:"uniqueIndex:idx_group_order_item"`
+	MenuItemName string `json:"menu_item_name" gorm:"uniqueIndex:idx_group_order_item"`
+	Quantity     int32  `json:"quantity"`
+}
+
+// MenuItems adds up the quantities of every participant.
+func (groupOrder *GroupOrder) MenuItems() map[string]int32 {
+	menuItems := make(map[string]int32)
+	for _, item := range groupOrder.Items {
+		menuItems[item.MenuItemName] += item.Quantity
+	}
+
+	return menuItems
+}
+
+// Participants returns the usernames that have items in the group order, in
+// the order they first appear.
+func (groupOrder *GroupOrder) Participants() []string {
+	var participants []string
+	seen := make(map[string]bool)
+	for _, item := range groupOrder.Items {
+		if !seen[item.Username] {
+			seen[item.Username] = true
+			participants = append(participants, item.Username)
+		}
+	}
+
+	return participants
+}
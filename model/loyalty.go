@@ -0,0 +1,58 @@
+package model
+
+import "time"
+
+type LoyaltyKind string
+
+const (
+	LoyaltyEarn     LoyaltyKind = "EARN"
+	LoyaltyRedeem   LoyaltyKind = "REDEEM"
+	LoyaltyReversal LoyaltyKind = "REVERSAL"
+	LoyaltyExpiry   LoyaltyKind = "EXPIRY"
+)
+
+// Ledger accounts besides the customers' own. Points earned are drawn from
+// LoyaltyIssuedAccount; points spent or expired go to the other two.
+const (
+	LoyaltyIssuedAccount   = "loyalty:issued"
+	LoyaltyRedeemedAccount = "loyalty:redeemed"
+	LoyaltyExpiredAccount  = "loyalty:expired"
+)
+
+// LoyaltyAccount is the ledger account holding a customer's points.
+func LoyaltyAccount(username string) string {
+	return "customer:" + username
+}
+
+// LoyaltyTransaction moves Points between ledger accounts through its
+// Entries, which sum to zero. ExpiresAt is set on earned points, which are
+// spent oldest first.
+type LoyaltyTransaction struct {
+	Id        int64          `json:"id" gorm:"primaryKey;autoIncrement:true"`
+	Username  string         `json:"username" gorm:"index"`
+	Kind      LoyaltyKind    `json:"kind" gorm:"size:20"`
+	OrderId   int64          `json:"order_id" gorm:"index"`
+	Points    int64          `json:"points"`
+	ExpiresAt *time.Time     `json:"expires_at"`
+	CreatedAt time.Time      `json:"created_at"`
+	Entries   []LoyaltyEntry `json:"entries" gorm:"foreignKey:TransactionId"`
+}
+
+// LoyaltyTransfer moves points from one account to another.
+func LoyaltyTransfer(kind LoyaltyKind, username string, orderId int64, points int64, from string, to string) *LoyaltyTransaction {
+	return &LoyaltyTransaction{
+		Username: username,
+		Kind:     kind,
+		OrderId:  orderId,
+		Points:   points,
+		Entries:  []LoyaltyEntry{{Account: from, Points: -points}, {Account: to, Points: points}},
+	}
+}
+
+// LoyaltyEntry credits Points to Account, or debits it if negative.
+type LoyaltyEntry struct {
+	Id            int64  `json:"id" gorm:"primaryKey;autoIncrement:true"`
+	TransactionId int64  `json:"transaction_id" gorm:"index"`
+	Account       string `json:"account" gorm:"index"`
+	Points        int64  `json:"points"`
+}
@@ -1,9 +1,154 @@
 package model
 
+import "time"
+
+type OrderStatus string
+
+const (
+	// OrderPlaced orders were sent to the fulfillment service when created,
+	// before restaurants had to accept orders.
+	OrderPlaced OrderStatus = "PLACED"
+	// OrderAwaitingAcceptance orders wait for the restaurant to accept them
+	// until AcceptBy, and are rejected automatically after.
+	OrderAwaitingAcceptance OrderStatus = "AWAITING_ACCEPTANCE"
+	OrderRejected           OrderStatus = "REJECTED"
+	// OrderScheduled orders wait for the scheduler to dispatch them ahead of
+	// ScheduledFor.
+	OrderScheduled OrderStatus = "SCHEDULED"
+	// OrderDispatching orders were claimed by a scheduler, or accepted by the
+	// restaurant, at DispatchClaimedAt and are being processed. Scheduled
+	// orders move on to OrderAwaitingAcceptance, accepted ones are sent to the
+	// fulfillment service.
+	OrderDispatching OrderStatus = "DISPATCHING"
+	OrderDispatched  OrderStatus = "DISPATCHED"
+	OrderCancelled   OrderStatus = "CANCELLED"
+
+	// Reported by the fulfillment service once the order was dispatched.
+	OrderCourierAssigned OrderStatus = "COURIER_ASSIGNED"
+	OrderPickedUp        OrderStatus = "PICKED_UP"
+	OrderDelivered       OrderStatus = "DELIVERED"
+)
+
+// PriceChangePolicy decides what happens when the menu prices of a scheduled
+// order changed by the time it is dispatched.
+type PriceChangePolicy string
+
+const (
+	// KeepOrderPrice charges what the customer agreed to when ordering.
+	KeepOrderPrice PriceChangePolicy = "KEEP_ORDER_PRICE"
+	// AcceptNewPrice re-prices the order at current prices.
+	AcceptNewPrice PriceChangePolicy = "ACCEPT_NEW_PRICE"
+	// CancelIfPriceIncreases cancels the order if the items cost more.
+	CancelIfPriceIncreases PriceChangePolicy = "CANCEL_IF_PRICE_INCREASES"
+)
+
+// Order amounts are integers in the minor unit of Currency (ISO 4217), e.g.
+// paise for INR. TotalAmount is what the customer pays: the items subtotal
+// less DiscountAmount, plus tax, fees and tip.
 type Order struct {
-	Id           int64   `json:"id" gorm:"primaryKey;autoIncrement:true"`
-	RestaurantId string  `json:"restaurant_id"`
-	Username     string  `json:"username"`
-	TotalPrice   float64 `json:"total_price"`
-	MenuItems    string  `json:"menu_items"`
+	Id                int64       `json:"id" gorm:"primaryKey;autoIncrement:true"`
+	RestaurantId      string      `json:"restaurant_id" gorm:"index:idx_orders_restaurant_change,priority:1"`
+	Username          string      `json:"username"`
+	Currency          string      `json:"currency" gorm:"size:3"`
+	SubtotalAmount    int64       `json:"subtotal_amount"`
+	TaxAmount         int64       `json:"tax_amount"`
+	TaxBasisPoints    int64       `json:"tax_basis_points"`
+	DeliveryFeeAmount int64       `json:"delivery_fee_amount"`
+	ServiceFeeAmount  int64       `json:"service_fee_amount"`
+	TipAmount         int64       `json:"tip_amount"`
+	DiscountAmount    int64       `json:"discount_amount"`
+	PromoCode         string      `json:"promo_code"`
+	TotalAmount       int64       `json:"total_amount"`
+	Items             []OrderItem `json:"items" gorm:"foreignKey:OrderId"`
+
+	Status             OrderStatus       `json:"status" gorm:"size:20;index"`
+	ScheduledFor       *time.Time        `json:"scheduled_for" gorm:"index"`
+	PriceChangePolicy  PriceChangePolicy `json:"price_change_policy" gorm:"size:30"`
+	DispatchClaimedAt  *time.Time        `json:"dispatch_claimed_at"`
+	DispatchedAt       *time.Time        `json:"dispatched_at"`
+	CancellationReason string            `json:"cancellation_reason"`
+
+	EstimatedDeliveryAt *time.Time `json:"estimated_delivery_at"`
+	CourierName         string     `json:"courier_name"`
+	CourierPhone        string     `json:"courier_phone"`
+
+	AcceptBy        *time.Time `json:"accept_by" gorm:"index"`
+	AcceptedAt      *time.Time `json:"accepted_at"`
+	PrepTimeMinutes int32      `json:"prep_time_minutes"`
+
+	// ChangeSeq is set by the database on every insert and update, from a
+	// sequence that orders the changes of a restaurant's orders in commit
+	// order. Restaurants resume their order feed from it.
+	ChangeSeq int64 `json:"change_seq" gorm:"->;index:idx_orders_restaurant_change,priority:2"`
+
+	// CourierUsername is the courier's account, if the fulfillment service
+	// named one. It lets the courier join the order's chat.
+	CourierUsername string `json:"courier_username"`
+
+	// DeliveryDistanceMeters is the distance from the restaurant to the drop
+	// address, if both ZIP codes are known. EstimatedDeliveryAt is derived
+	// from it until the fulfillment service reports its own estimate.
+	DeliveryDistanceMeters *int64 `json:"delivery_distance_meters"`
+
+	// SurgeBasisPoints is the surge multiplier DeliveryFeeAmount was priced
+	// with, 0 if there was none. It is kept to audit surged fees.
+	SurgeBasisPoints int64 `json:"surge_basis_points"`
+
+	// LoyaltyPointsRedeemed were spent on the order, taking
+	// LoyaltyDiscountAmount off its items. DiscountAmount includes it.
+	LoyaltyPointsRedeemed int64 `json:"loyalty_points_redeemed"`
+	LoyaltyDiscountAmount int64 `json:"loyalty_discount_amount"`
+
+	// WalletAmount of TotalAmount was paid with store credit, in Currency.
+	WalletAmount int64 `json:"wallet_amount"`
+
+	// PromotionDiscountAmount is what the promotion for PromoCode took off
+	// the items, without the loyalty discount or a waived delivery fee.
+	PromotionDiscountAmount int64 `json:"promotion_discount_amount"`
+}
+
+// Final reports whether the order can no longer change.
+func (order *Order) Final() bool {
+	return order.Status == OrderCancelled || order.Status == OrderRejected || order.Status == OrderDelivered
+}
+
+// OrderItem is one line of an order. UnitAmount is a snapshot of the catalog
+// price when the order was placed, so later menu changes don't alter receipts.
+// MenuItemName is the name the catalog shows for the item, MenuItemKey the one
+// it was ordered and is looked up by.
+type OrderItem struct {
+	Id           int64  `json:"id" gorm:"primaryKey;autoIncrement:true"`
+	OrderId      int64  `json:"order_id" gorm:"index"`
+	MenuItemId   string `json:"menu_item_id"`
+	MenuItemName string `json:"menu_item_name" gorm:"index"`
+	MenuItemKey  string `json:"menu_item_key"`
+	Quantity     int32  `json:"quantity"`
+	// UnitAmount includes the price of the chosen options.
+	UnitAmount int64 `json:"unit_amount"`
+	LineAmount int64 `json:"line_amount"`
+	// Options are the chosen modifier options, kept with the line as they
+	// were priced.
+	Options []OrderItemOption `json:"options" gorm:"serializer:json"`
+	// Instructions are the customer's notes for the kitchen.
+	Instructions string `json:"instructions" gorm:"size:200"`
+}
+
+// Key returns the name the item is looked up by in the catalog. Items stored
+// before MenuItemKey was kept have it in MenuItemName.
+func (item *OrderItem) Key() string {
+	if item.MenuItemKey == "" {
+		return item.MenuItemName
+	}
+
+	return item.MenuItemKey
+}
+
+// OrderItemOption is a modifier option chosen for an order line, such as a
+// size. PriceAmount is a snapshot of the catalog price, in the order's
+// currency.
+type OrderItemOption struct {
+	GroupId     string `json:"group_id"`
+	OptionId    string `json:"option_id"`
+	Name        string `json:"name"`
+	PriceAmount int64  `json:"price_amount"`
 }
@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+type PromotionKind string
+
+const (
+	PercentageOff PromotionKind = "PERCENTAGE_OFF"
+	FlatOff       PromotionKind = "FLAT_OFF"
+	FreeDelivery  PromotionKind = "FREE_DELIVERY"
+	BuyXGetY      PromotionKind = "BUY_X_GET_Y"
+)
+
+// Promotion is a coupon customers can apply with a promo code. Amounts are in
+// minor units of Currency. Zero limits and empty restrictions mean unlimited
+// and unrestricted.
+type Promotion struct {
+	Id                    int64         `json:"id" gorm:"primaryKey;autoIncrement:true"`
+	Code                  string        `json:"code" gorm:"uniqueIndex"`
+	Kind                  PromotionKind `json:"kind"`
+	Currency              string        `json:"currency" gorm:"size:3"`
+	PercentOffBasisPoints int64         `json:"percent_off_basis_points"`
+	FlatOffAmount         int64         `json:"flat_off_amount"`
+	MaxDiscountAmount     int64         `json:"max_discount_amount"`
+	BuyMenuItem           string        `json:"buy_menu_item"`
+	BuyQuantity           int32         `json:"buy_quantity"`
+	GetMenuItem           string        `json:"get_menu_item"`
+	GetQuantity           int32         `json:"get_quantity"`
+	MinSubtotalAmount     int64         `json:"min_subtotal_amount"`
+	RestaurantId          string        `json:"restaurant_id"`
+	FirstOrderOnly        bool          `json:"first_order_only"`
+	PerUserLimit          int64         `json:"per_user_limit"`
+	GlobalLimit           int64         `json:"global_limit"`
+	UsageCount            int64         `json:"usage_count"`
+	StartsAt              *time.Time    `json:"starts_at"`
+	EndsAt                *time.Time    `json:"ends_at"`
+}
+
+type PromotionRedemption struct {
+	Id             int64     `json:"id" gorm:"primaryKey;autoIncrement:true"`
+	PromotionId    int64     `json:"promotion_id" gorm:"index:idx_redemption_promotion_user"`
+	Username       string    `json:"username" gorm:"index:idx_redemption_promotion_user"`
+	OrderId        int64     `json:"order_id" gorm:"index"`
+	DiscountAmount int64     `json:"discount_amount"`
+	CreatedAt      time.Time `json:"created_at"`
+}
@@ -0,0 +1,8 @@
+package model
+
+// RestaurantOwner lets a user manage the incoming orders of a restaurant.
+type RestaurantOwner struct {
+	Id           int64  `json:"id" gorm:"primaryKey;autoIncrement:true"`
+	Username     string `json:"username" gorm:"uniqueIndex:idx_restaurant_owner"`
+	RestaurantId string `json:"restaurant_id" gorm:"uniqueIndex:idx_restaurant_owner"`
+}
@@ -1,5 +1,11 @@
 package model
 
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
 type Address struct {
 	Street  string `json:"street"`
 	City    string `json:"city"`
@@ -8,8 +14,20 @@ type Address struct {
 }
 
 type User struct {
-	Id       int64    `gorm:"primaryKey;autoIncrement:true" json:"id"`
-	Username string   `json:"username" gorm:"unique"`
-	Password string   `json:"password"`
-	Address  *Address `json:"address" gorm:"embedded"`
+	Id                 int64    `gorm:"primaryKey;autoIncrement:true" json:"id"`
+	Username           string   `json:"username" gorm:"unique"`
+	NormalizedUsername string   `json:"-" gorm:"uniqueIndex"`
+	Password           string   `json:"password"`
+	Address            *Address `json:"address" gorm:"embedded"`
+}
+
+// NormalizeUsername returns the form used to compare usernames, so that
+// "Alice" and "alice " refer to the same account.
+func NormalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+func (user *User) BeforeSave(_ *gorm.DB) error {
+	user.NormalizedUsername = NormalizeUsername(user.Username)
+	return nil
 }
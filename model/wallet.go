@@ -0,0 +1,83 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+type WalletKind string
+
+const (
+	WalletCredit   WalletKind = "CREDIT"
+	WalletPayment  WalletKind = "PAYMENT"
+	WalletReversal WalletKind = "REVERSAL"
+)
+
+// WalletCreditSource tells why store credit was issued, and which account
+// funds it.
+type WalletCreditSource string
+
+const (
+	WalletRefund   WalletCreditSource = "REFUND"
+	WalletGoodwill WalletCreditSource = "GOODWILL"
+	WalletGiftCard WalletCreditSource = "GIFT_CARD"
+)
+
+// WalletPaymentsAccount receives the store credit spent on orders. The
+// service's accounts are under "wallet:", and customers' under "customer:",
+// so that no username can name one of the service's.
+const WalletPaymentsAccount = "wallet:payments"
+
+// WalletAccount is the ledger account holding a customer's store credit.
+func WalletAccount(username string) string {
+	return "customer:" + username
+}
+
+// WalletSourceAccount is the ledger account store credit of a source is
+// drawn from.
+func WalletSourceAccount(source WalletCreditSource) string {
+	return "wallet:" + strings.ToLower(string(source))
+}
+
+// WalletTransaction moves Amount of Currency between ledger accounts through
+// its Entries, which sum to zero. Credits record their Source, the Reason
+// given for them and the support agent who IssuedBy them. IdempotencyKey is
+// only set on credits, so that a retried request can't issue one twice.
+type WalletTransaction struct {
+	Id             int64              `json:"id" gorm:"primaryKey;autoIncrement:true"`
+	Username       string             `json:"username" gorm:"index"`
+	Kind           WalletKind         `json:"kind" gorm:"size:20"`
+	OrderId        int64              `json:"order_id" gorm:"index"`
+	Currency       string             `json:"currency" gorm:"size:3"`
+	Amount         int64              `json:"amount"`
+	Source         WalletCreditSource `json:"source" gorm:"size:20"`
+	Reason         string             `json:"reason"`
+	IssuedBy       string             `json:"issued_by"`
+	IdempotencyKey *string            `json:"idempotency_key" gorm:"uniqueIndex"`
+	CreatedAt      time.Time          `json:"created_at"`
+	Entries        []WalletEntry      `json:"entries" gorm:"foreignKey:TransactionId"`
+}
+
+// WalletTransfer moves an amount from one account to another.
+func WalletTransfer(kind WalletKind, username string, orderId int64, currency string, amount int64, from string, to string) *WalletTransaction {
+	return &WalletTransaction{
+		Username: username,
+		Kind:     kind,
+		OrderId:  orderId,
+		Currency: currency,
+		Amount:   amount,
+		Entries: []WalletEntry{
+			{Account: from, Currency: currency, Amount: -amount},
+			{Account: to, Currency: currency, Amount: amount},
+		},
+	}
+}
+
+// WalletEntry credits Amount to Account, or debits it if negative.
+type WalletEntry struct {
+	Id            int64  `json:"id" gorm:"primaryKey;autoIncrement:true"`
+	TransactionId int64  `json:"transaction_id" gorm:"index"`
+	Account       string `json:"account" gorm:"index"`
+	Currency      string `json:"currency" gorm:"size:3"`
+	Amount        int64  `json:"amount"`
+}
@@ -0,0 +1,160 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrCurrencyMismatch    = errors.New("currency mismatch")
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+	ErrInvalidAmount       = errors.New("invalid amount")
+)
+
+// exponents holds the number of minor unit digits for the ISO 4217
+// currencies the service accepts.
+var exponents = map[string]int{
+	"AED": 2,
+	"AUD": 2,
+	"BHD": 3,
+	"CAD": 2,
+	"CHF": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"INR": 2,
+	"JPY": 0,
+	"KWD": 3,
+	"SGD": 2,
+	"USD": 2,
+}
+
+// Money is an amount in the minor unit of an ISO 4217 currency, e.g. paise
+// for INR or cents for USD.
+type Money struct {
+	Currency string
+	Amount   int64
+}
+
+func New(amount int64, currency string) Money {
+	return Money{Currency: currency, Amount: amount}
+}
+
+func Exponent(currency string) (int, error) {
+	exponent, ok := exponents[strings.ToUpper(currency)]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnsupportedCurrency, currency)
+	}
+
+	return exponent, nil
+}
+
+// Parse converts a decimal amount in major units, e.g. "12.345", into minor
+// units. Digits beyond the currency's precision are rounded half away from
+// zero, the same way for every price, without going through float64.
+func Parse(amount string, currency string) (Money, error) {
+	currency = strings.ToUpper(currency)
+
+	exponent, err := Exponent(currency)
+	if err != nil {
+		return Money{}, err
+	}
+
+	amount = strings.TrimSpace(amount)
+	negative := strings.HasPrefix(amount, "-")
+	amount = strings.TrimPrefix(amount, "-")
+
+	whole, fraction, _ := strings.Cut(amount, ".")
+	if whole == "" {
+		whole = "0"
+	}
+
+	if !isDigits(whole) || !isDigits(fraction) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+
+	roundUp := false
+	if len(fraction) > exponent {
+		roundUp = fraction[exponent] >= '5'
+		fraction = fraction[:exponent]
+	}
+	fraction += strings.Repeat("0", exponent-len(fraction))
+
+	minor, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+
+	if roundUp {
+		minor++
+	}
+
+	if negative {
+		minor = -minor
+	}
+
+	return Money{Currency: currency, Amount: minor}, nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency == "" {
+		return other, nil
+	}
+
+	if other.Currency != "" && other.Currency != m.Currency {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+
+	return Money{Currency: m.Currency, Amount: m.Amount + other.Amount}, nil
+}
+
+func (m Money) Multiply(quantity int64) Money {
+	return Money{Currency: m.Currency, Amount: m.Amount * quantity}
+}
+
+// ApplyBasisPoints returns amount * basisPoints / 10000 rounded half away
+// from zero, which is how every percentage of an order is rounded.
+func ApplyBasisPoints(amount int64, basisPoints int64) int64 {
+	product := amount * basisPoints
+	if product < 0 {
+		return -((-product + 5000) / 10000)
+	}
+
+	return (product + 5000) / 10000
+}
+
+// Major returns the amount in major units. It is only meant for display and
+// for deprecated floating point fields.
+func (m Money) Major() float64 {
+	exponent, err := Exponent(m.Currency)
+	if err != nil {
+		return float64(m.Amount)
+	}
+
+	return float64(m.Amount) / math.Pow10(exponent)
+}
+
+func (m Money) String() string {
+	exponent, err := Exponent(m.Currency)
+	if err != nil || exponent == 0 {
+		return fmt.Sprintf("%d %s", m.Amount, m.Currency)
+	}
+
+	return fmt.Sprintf("%.*f %s", exponent, m.Major(), m.Currency)
+}
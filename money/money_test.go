@@ -0,0 +1,61 @@
+package money
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   string
+		currency string
+		expected Money
+	}{
+		{name: "Whole Amount", amount: "250", currency: "INR", expected: Money{Currency: "INR", Amount: 25000}},
+		{name: "Fractional Amount", amount: "0.1", currency: "usd", expected: Money{Currency: "USD", Amount: 10}},
+		{name: "Rounds Half Up", amount: "1.005", currency: "USD", expected: Money{Currency: "USD", Amount: 101}},
+		{name: "Rounds Down", amount: "1.004", currency: "USD", expected: Money{Currency: "USD", Amount: 100}},
+		{name: "Zero Exponent", amount: "1200.6", currency: "JPY", expected: Money{Currency: "JPY", Amount: 1201}},
+		{name: "Three Digit Exponent", amount: "1.5", currency: "KWD", expected: Money{Currency: "KWD", Amount: 1500}},
+		{name: "Negative Amount", amount: "-2.50", currency: "EUR", expected: Money{Currency: "EUR", Amount: -250}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.amount, tt.currency)
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestParse_RejectsInvalidInput(t *testing.T) {
+	_, err := Parse("12.5", "XYZ")
+	assert.True(t, errors.Is(err, ErrUnsupportedCurrency))
+
+	_, err = Parse("12,5", "INR")
+	assert.True(t, errors.Is(err, ErrInvalidAmount))
+}
+
+func TestAdd_SumsSameCurrencyAndRejectsMixedCurrencies(t *testing.T) {
+	total, err := Money{}.Add(New(250, "INR"))
+	assert.Nil(t, err)
+
+	total, err = total.Add(New(40, "INR").Multiply(3))
+	assert.Nil(t, err)
+	assert.Equal(t, New(370, "INR"), total)
+	assert.Equal(t, "3.70 INR", total.String())
+
+	_, err = total.Add(New(100, "USD"))
+	assert.True(t, errors.Is(err, ErrCurrencyMismatch))
+}
+
+func TestApplyBasisPoints_RoundsHalfAwayFromZero(t *testing.T) {
+	assert.Equal(t, int64(1235), ApplyBasisPoints(12345, 1000))
+	assert.Equal(t, int64(1234), ApplyBasisPoints(12344, 1000))
+	assert.Equal(t, int64(-1235), ApplyBasisPoints(-12345, 1000))
+}
@@ -0,0 +1,219 @@
+package pricing
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+
+	"orderService.com/go-orderService-grpc/model"
+	"orderService.com/go-orderService-grpc/money"
+)
+
+var ErrNegativeTip = errors.New("tip must not be negative")
+
+const basisPointsPerUnit = 10000
+
+// TaxRate is the sales tax for a state in basis points (1% = 100). Cities may
+// levy an additional rate on top of the state rate.
+type TaxRate struct {
+	StateBasisPoints int64            `json:"state_basis_points"`
+	CityBasisPoints  map[string]int64 `json:"city_basis_points"`
+}
+
+// ZoneDeliveryFees charges by how far apart the pickup and drop addresses are,
+// approximated by whether they share a city or a state.
+type ZoneDeliveryFees struct {
+	SameCity   int64 `json:"same_city"`
+	SameState  int64 `json:"same_state"`
+	OtherState int64 `json:"other_state"`
+}
+
+type ServiceFee struct {
+	BasisPoints int64 `json:"basis_points"`
+	Min         int64 `json:"min"`
+	Max         int64 `json:"max"`
+}
+
+// Config holds the rate tables used by the Engine. Fee amounts are in minor
+// units of the order's currency.
+type Config struct {
+	TaxRates           map[string]TaxRate `json:"tax_rates"`
+	DefaultBasisPoints int64              `json:"default_basis_points"`
+	DeliveryFees       ZoneDeliveryFees   `json:"delivery_fees"`
+	ServiceFee         ServiceFee         `json:"service_fee"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		TaxRates:           map[string]TaxRate{},
+		DefaultBasisPoints: 500,
+		DeliveryFees: ZoneDeliveryFees{
+			SameCity:   3000,
+			SameState:  6000,
+			OtherState: 12000,
+		},
+		ServiceFee: ServiceFee{BasisPoints: 200, Min: 500, Max: 5000},
+	}
+}
+
+// LoadConfig reads a JSON encoded Config, starting from DefaultConfig so that
+// the file only needs to list what it overrides.
+func LoadConfig(path string) (Config, error) {
+	config := DefaultConfig()
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+
+	if err := json.Unmarshal(content, &config); err != nil {
+		return Config{}, err
+	}
+
+	return config, nil
+}
+
+// Input describes an order to price. ItemDiscount and FreeDelivery come from
+// an applied promotion; tax and the service fee are charged on the subtotal
+// after ItemDiscount. SurgeBasisPoints multiplies the delivery fee (10000 is
+// the regular fee); anything up to 10000 leaves the fee as it is.
+type Input struct {
+	Subtotal         money.Money
+	DropAddress      *model.Address
+	PickupAddress    *model.Address
+	Tip              money.Money
+	ItemDiscount     money.Money
+	FreeDelivery     bool
+	SurgeBasisPoints int64
+}
+
+// Breakdown is the line-by-line price of an order. Discount covers both the
+// item discount and a waived delivery fee. Total is the amount the customer
+// pays. SurgeBasisPoints is the surge included in DeliveryFee, 0 if none.
+type Breakdown struct {
+	Subtotal         money.Money
+	Discount         money.Money
+	Tax              money.Money
+	TaxBasisPoints   int64
+	DeliveryFee      money.Money
+	SurgeBasisPoints int64
+	ServiceFee       money.Money
+	Tip              money.Money
+	Total            money.Money
+}
+
+type Engine struct {
+	config Config
+}
+
+func NewEngine(config Config) *Engine {
+	taxRates := make(map[string]TaxRate, len(config.TaxRates))
+	for state, rate := range config.TaxRates {
+		cityRates := make(map[string]int64, len(rate.CityBasisPoints))
+		for city, basisPoints := range rate.CityBasisPoints {
+			cityRates[normalize(city)] = basisPoints
+		}
+		taxRates[normalize(state)] = TaxRate{StateBasisPoints: rate.StateBasisPoints, CityBasisPoints: cityRates}
+	}
+	config.TaxRates = taxRates
+
+	return &Engine{config: config}
+}
+
+func (engine *Engine) Price(input Input) (Breakdown, error) {
+	currency := input.Subtotal.Currency
+
+	if input.Tip.Amount < 0 {
+		return Breakdown{}, ErrNegativeTip
+	}
+
+	tip := money.New(input.Tip.Amount, currency)
+	if input.Tip.Currency != "" && input.Tip.Currency != currency {
+		return Breakdown{}, money.ErrCurrencyMismatch
+	}
+
+	if input.ItemDiscount.Currency != "" && input.ItemDiscount.Currency != currency {
+		return Breakdown{}, money.ErrCurrencyMismatch
+	}
+
+	taxBasisPoints := engine.taxBasisPoints(input.DropAddress)
+	discountedSubtotal := input.Subtotal.Amount - min(input.ItemDiscount.Amount, input.Subtotal.Amount)
+	deliveryFee := engine.deliveryFee(input.PickupAddress, input.DropAddress)
+
+	var surgeBasisPoints int64
+	if input.SurgeBasisPoints > basisPointsPerUnit {
+		surgeBasisPoints = input.SurgeBasisPoints
+		deliveryFee = money.ApplyBasisPoints(deliveryFee, surgeBasisPoints)
+	}
+
+	discount := input.Subtotal.Amount - discountedSubtotal
+	if input.FreeDelivery {
+		discount += deliveryFee
+	}
+
+	breakdown := Breakdown{
+		Subtotal:         input.Subtotal,
+		Discount:         money.New(discount, currency),
+		Tax:              money.New(money.ApplyBasisPoints(discountedSubtotal, taxBasisPoints), currency),
+		TaxBasisPoints:   taxBasisPoints,
+		DeliveryFee:      money.New(deliveryFee, currency),
+		SurgeBasisPoints: surgeBasisPoints,
+		ServiceFee:       money.New(engine.serviceFee(discountedSubtotal), currency),
+		Tip:              tip,
+	}
+
+	breakdown.Total = money.New(
+		breakdown.Subtotal.Amount-breakdown.Discount.Amount+breakdown.Tax.Amount+breakdown.DeliveryFee.Amount+breakdown.ServiceFee.Amount+breakdown.Tip.Amount,
+		currency,
+	)
+
+	return breakdown, nil
+}
+
+func (engine *Engine) taxBasisPoints(address *model.Address) int64 {
+	if address == nil {
+		return engine.config.DefaultBasisPoints
+	}
+
+	rate, ok := engine.config.TaxRates[normalize(address.State)]
+	if !ok {
+		return engine.config.DefaultBasisPoints
+	}
+
+	return rate.StateBasisPoints + rate.CityBasisPoints[normalize(address.City)]
+}
+
+func (engine *Engine) deliveryFee(pickup *model.Address, drop *model.Address) int64 {
+	fees := engine.config.DeliveryFees
+
+	switch {
+	case pickup == nil || drop == nil:
+		return fees.OtherState
+	case normalize(pickup.State) != normalize(drop.State):
+		return fees.OtherState
+	case normalize(pickup.City) != normalize(drop.City):
+		return fees.SameState
+	default:
+		return fees.SameCity
+	}
+}
+
+func (engine *Engine) serviceFee(subtotal int64) int64 {
+	fee := engine.config.ServiceFee
+	amount := money.ApplyBasisPoints(subtotal, fee.BasisPoints)
+
+	if amount < fee.Min {
+		amount = fee.Min
+	}
+
+	if fee.Max > 0 && amount > fee.Max {
+		amount = fee.Max
+	}
+
+	return amount
+}
+
+func normalize(s string) string {
+	return strings.ToUpper(strings.TrimSpace(s))
+}
@@ -0,0 +1,152 @@
+package pricing
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"orderService.com/go-orderService-grpc/model"
+	"orderService.com/go-orderService-grpc/money"
+)
+
+func testConfig() Config {
+	return Config{
+		TaxRates: map[string]TaxRate{
+			"Maharashtra": {StateBasisPoints: 500, CityBasisPoints: map[string]int64{"Mumbai": 125}},
+		},
+		DefaultBasisPoints: 300,
+		DeliveryFees:       ZoneDeliveryFees{SameCity: 3000, SameState: 6000, OtherState: 12000},
+		ServiceFee:         ServiceFee{BasisPoints: 200, Min: 500, Max: 5000},
+	}
+}
+
+func TestPrice_ProducesBreakdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		drop     *model.Address
+		expected Breakdown
+	}{
+		{
+			name: "State And City Tax, Same City",
+			drop: &model.Address{City: "mumbai", State: "MAHARASHTRA"},
+			expected: Breakdown{
+				Subtotal:       money.New(58150, "INR"),
+				Discount:       money.New(0, "INR"),
+				Tax:            money.New(3634, "INR"),
+				TaxBasisPoints: 625,
+				DeliveryFee:    money.New(3000, "INR"),
+				ServiceFee:     money.New(1163, "INR"),
+				Tip:            money.New(2000, "INR"),
+				Total:          money.New(67947, "INR"),
+			},
+		},
+		{
+			name: "State Tax Only, Same State",
+			drop: &model.Address{City: "Pune", State: "Maharashtra"},
+			expected: Breakdown{
+				Subtotal:       money.New(58150, "INR"),
+				Discount:       money.New(0, "INR"),
+				Tax:            money.New(2908, "INR"),
+				TaxBasisPoints: 500,
+				DeliveryFee:    money.New(6000, "INR"),
+				ServiceFee:     money.New(1163, "INR"),
+				Tip:            money.New(2000, "INR"),
+				Total:          money.New(70221, "INR"),
+			},
+		},
+		{
+			name: "Default Tax, Other State",
+			drop: &model.Address{City: "Bengaluru", State: "Karnataka"},
+			expected: Breakdown{
+				Subtotal:       money.New(58150, "INR"),
+				Discount:       money.New(0, "INR"),
+				Tax:            money.New(1745, "INR"),
+				TaxBasisPoints: 300,
+				DeliveryFee:    money.New(12000, "INR"),
+				ServiceFee:     money.New(1163, "INR"),
+				Tip:            money.New(2000, "INR"),
+				Total:          money.New(75058, "INR"),
+			},
+		},
+	}
+
+	engine := NewEngine(testConfig())
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := engine.Price(Input{
+				Subtotal:      money.New(58150, "INR"),
+				PickupAddress: &model.Address{City: "Mumbai", State: "Maharashtra"},
+				DropAddress:   tt.drop,
+				Tip:           money.New(2000, ""),
+			})
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestPrice_ServiceFeeIsClamped(t *testing.T) {
+	engine := NewEngine(testConfig())
+
+	small, err := engine.Price(Input{Subtotal: money.New(1000, "INR")})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(500), small.ServiceFee.Amount)
+
+	large, err := engine.Price(Input{Subtotal: money.New(1000000, "INR")})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5000), large.ServiceFee.Amount)
+}
+
+func TestPrice_RejectsInvalidTip(t *testing.T) {
+	engine := NewEngine(testConfig())
+
+	_, err := engine.Price(Input{Subtotal: money.New(1000, "INR"), Tip: money.New(-1, "INR")})
+	assert.True(t, errors.Is(err, ErrNegativeTip))
+
+	_, err = engine.Price(Input{Subtotal: money.New(1000, "INR"), Tip: money.New(100, "USD")})
+	assert.True(t, errors.Is(err, money.ErrCurrencyMismatch))
+}
+
+func TestPrice_AppliesDiscountBeforeTaxAndWaivesDelivery(t *testing.T) {
+	engine := NewEngine(testConfig())
+
+	got, err := engine.Price(Input{
+		Subtotal:      money.New(60000, "INR"),
+		PickupAddress: &model.Address{City: "Mumbai", State: "Maharashtra"},
+		DropAddress:   &model.Address{City: "Pune", State: "Maharashtra"},
+		ItemDiscount:  money.New(10000, "INR"),
+		FreeDelivery:  true,
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, money.New(16000, "INR"), got.Discount)
+	assert.Equal(t, money.New(2500, "INR"), got.Tax)
+	assert.Equal(t, money.New(6000, "INR"), got.DeliveryFee)
+	assert.Equal(t, money.New(1000, "INR"), got.ServiceFee)
+	assert.Equal(t, money.New(53500, "INR"), got.Total)
+}
+
+func TestPrice_SurgeMultipliesDeliveryFee(t *testing.T) {
+	engine := NewEngine(testConfig())
+	input := Input{
+		Subtotal:      money.New(60000, "INR"),
+		PickupAddress: &model.Address{City: "Mumbai", State: "Maharashtra"},
+		DropAddress:   &model.Address{City: "Pune", State: "Maharashtra"},
+	}
+
+	input.SurgeBasisPoints = 15000
+	surged, err := engine.Price(input)
+	assert.Nil(t, err)
+	assert.Equal(t, money.New(9000, "INR"), surged.DeliveryFee)
+	assert.Equal(t, int64(15000), surged.SurgeBasisPoints)
+
+	input.SurgeBasisPoints = 10000
+	regular, err := engine.Price(input)
+	assert.Nil(t, err)
+	assert.Equal(t, money.New(6000, "INR"), regular.DeliveryFee)
+	assert.Equal(t, int64(0), regular.SurgeBasisPoints)
+	assert.Equal(t, surged.Total.Amount-3000, regular.Total.Amount)
+}
@@ -0,0 +1,79 @@
+package pricing
+
+import "orderService.com/go-orderService-grpc/money"
+
+// Split divides a breakdown between parties in proportion to weights,
+// usually each party's share of the items subtotal. Every amount is split on
+// its own with the largest remainder method, so each line of the shares adds
+// up exactly to the same line of the breakdown. If all weights are zero the
+// breakdown is split evenly. Every share keeps the tax and surge rates of the
+// breakdown.
+func (breakdown Breakdown) Split(weights []int64) []Breakdown {
+	currency := breakdown.Total.Currency
+	shares := make([]Breakdown, len(weights))
+	if len(weights) == 0 {
+		return shares
+	}
+
+	subtotals := allocate(breakdown.Subtotal.Amount, weights)
+	discounts := allocate(breakdown.Discount.Amount, weights)
+	taxes := allocate(breakdown.Tax.Amount, weights)
+	deliveryFees := allocate(breakdown.DeliveryFee.Amount, weights)
+	serviceFees := allocate(breakdown.ServiceFee.Amount, weights)
+	tips := allocate(breakdown.Tip.Amount, weights)
+
+	for i := range shares {
+		shares[i] = Breakdown{
+			Subtotal:         money.New(subtotals[i], currency),
+			Discount:         money.New(discounts[i], currency),
+			Tax:              money.New(taxes[i], currency),
+			TaxBasisPoints:   breakdown.TaxBasisPoints,
+			DeliveryFee:      money.New(deliveryFees[i], currency),
+			SurgeBasisPoints: breakdown.SurgeBasisPoints,
+			ServiceFee:       money.New(serviceFees[i], currency),
+			Tip:              money.New(tips[i], currency),
+			Total:            money.New(subtotals[i]-discounts[i]+taxes[i]+deliveryFees[i]+serviceFees[i]+tips[i], currency),
+		}
+	}
+
+	return shares
+}
+
+// allocate splits amount in proportion to weights. The minor units lost to
+// rounding down go one each to the largest remainders, earliest first on ties.
+func allocate(amount int64, weights []int64) []int64 {
+	var totalWeight int64
+	for _, weight := range weights {
+		totalWeight += weight
+	}
+
+	if totalWeight == 0 {
+		weights = make([]int64, len(weights))
+		for i := range weights {
+			weights[i] = 1
+		}
+		totalWeight = int64(len(weights))
+	}
+
+	parts := make([]int64, len(weights))
+	remainders := make([]int64, len(weights))
+	allocated := int64(0)
+	for i, weight := range weights {
+		parts[i] = amount * weight / totalWeight
+		remainders[i] = amount * weight % totalWeight
+		allocated += parts[i]
+	}
+
+	for left := amount - allocated; left > 0; left-- {
+		largest := 0
+		for i := range remainders {
+			if remainders[i] > remainders[largest] {
+				largest = i
+			}
+		}
+		parts[largest]++
+		remainders[largest] = -1
+	}
+
+	return parts
+}
@@ -0,0 +1,84 @@
+package pricing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"orderService.com/go-orderService-grpc/money"
+)
+
+func TestSplit_SharesAddUpToBreakdown(t *testing.T) {
+	breakdown := Breakdown{
+		Subtotal:       money.New(30000, "INR"),
+		Discount:       money.New(1000, "INR"),
+		Tax:            money.New(1450, "INR"),
+		TaxBasisPoints: 500,
+		DeliveryFee:    money.New(4000, "INR"),
+		ServiceFee:     money.New(601, "INR"),
+		Tip:            money.New(1000, "INR"),
+		Total:          money.New(36051, "INR"),
+	}
+
+	shares := breakdown.Split([]int64{10000, 10000, 10000})
+
+	assert.Equal(t, Breakdown{
+		Subtotal:       money.New(10000, "INR"),
+		Discount:       money.New(334, "INR"),
+		Tax:            money.New(484, "INR"),
+		TaxBasisPoints: 500,
+		DeliveryFee:    money.New(1334, "INR"),
+		ServiceFee:     money.New(201, "INR"),
+		Tip:            money.New(334, "INR"),
+		Total:          money.New(12019, "INR"),
+	}, shares[0])
+
+	var total int64
+	for _, share := range shares {
+		total += share.Total.Amount
+	}
+	assert.Equal(t, breakdown.Total.Amount, total)
+}
+
+func TestSplit_IsProportionalToWeights(t *testing.T) {
+	breakdown := Breakdown{
+		Subtotal:    money.New(40000, "INR"),
+		DeliveryFee: money.New(4000, "INR"),
+		Total:       money.New(44000, "INR"),
+	}
+
+	shares := breakdown.Split([]int64{30000, 10000})
+
+	assert.Equal(t, money.New(33000, "INR"), shares[0].Total)
+	assert.Equal(t, money.New(11000, "INR"), shares[1].Total)
+}
+
+func TestSplit_KeepsSurge(t *testing.T) {
+	breakdown := Breakdown{
+		Subtotal:         money.New(40000, "INR"),
+		DeliveryFee:      money.New(6000, "INR"),
+		SurgeBasisPoints: 15000,
+		Total:            money.New(46000, "INR"),
+	}
+
+	shares := breakdown.Split([]int64{30000, 10000})
+
+	assert.Equal(t, Breakdown{
+		Subtotal:         money.New(30000, "INR"),
+		Discount:         money.New(0, "INR"),
+		Tax:              money.New(0, "INR"),
+		DeliveryFee:      money.New(4500, "INR"),
+		SurgeBasisPoints: 15000,
+		ServiceFee:       money.New(0, "INR"),
+		Tip:              money.New(0, "INR"),
+		Total:            money.New(34500, "INR"),
+	}, shares[0])
+	assert.Equal(t, int64(15000), shares[1].SurgeBasisPoints)
+}
+
+func TestAllocate(t *testing.T) {
+	assert.Equal(t, []int64{4, 3, 3}, allocate(10, []int64{1, 1, 1}))
+	assert.Equal(t, []int64{1, 2}, allocate(3, []int64{1, 2}))
+	assert.Equal(t, []int64{5, 5}, allocate(10, []int64{0, 0}))
+	assert.Equal(t, []int64{0, 7}, allocate(7, []int64{0, 5}))
+}
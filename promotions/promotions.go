@@ -0,0 +1,154 @@
+package promotions
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"orderService.com/go-orderService-grpc/model"
+	"orderService.com/go-orderService-grpc/money"
+)
+
+var (
+	ErrNotStarted        = errors.New("promotion has not started yet")
+	ErrExpired           = errors.New("promotion has expired")
+	ErrWrongRestaurant   = errors.New("promotion is not valid for this restaurant")
+	ErrWrongCurrency     = errors.New("promotion is not valid for this currency")
+	ErrMinSubtotal       = errors.New("order subtotal is below the promotion minimum")
+	ErrFirstOrderOnly    = errors.New("promotion is only valid on a first order")
+	ErrUsageLimitReached = errors.New("promotion usage limit reached")
+	ErrUserLimitReached  = errors.New("promotion already used the maximum number of times")
+	ErrNoQualifyingItems = errors.New("order has no items the promotion applies to")
+	ErrUnknownKind       = errors.New("unknown promotion kind")
+)
+
+// Line is an order line as seen by a promotion. MenuItem is the name the item
+// is looked up by in the catalog, as in BuyMenuItem and GetMenuItem.
+type Line struct {
+	MenuItem   string
+	Quantity   int32
+	UnitAmount int64
+}
+
+// Cart is everything a promotion is checked against.
+type Cart struct {
+	RestaurantId    string
+	Subtotal        money.Money
+	Lines           []Line
+	Now             time.Time
+	PreviousOrders  int64
+	UserRedemptions int64
+}
+
+// Discount is what a promotion takes off an order. Items is subtracted from
+// the items subtotal, FreeDelivery waives the delivery fee.
+type Discount struct {
+	Items        money.Money
+	FreeDelivery bool
+}
+
+// NormalizeCode makes promo codes case-insensitive.
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Evaluate checks the promotion's constraints against the cart and computes
+// the discount it grants. Global usage is checked again when the promotion is
+// redeemed, since it can change concurrently.
+func Evaluate(promotion model.Promotion, cart Cart) (Discount, error) {
+	if err := checkConstraints(promotion, cart); err != nil {
+		return Discount{}, err
+	}
+
+	currency := cart.Subtotal.Currency
+
+	switch promotion.Kind {
+	case model.PercentageOff:
+		amount := money.ApplyBasisPoints(cart.Subtotal.Amount, promotion.PercentOffBasisPoints)
+		return Discount{Items: money.New(capDiscount(promotion, amount, cart.Subtotal.Amount), currency)}, nil
+	case model.FlatOff:
+		return Discount{Items: money.New(capDiscount(promotion, promotion.FlatOffAmount, cart.Subtotal.Amount), currency)}, nil
+	case model.FreeDelivery:
+		return Discount{Items: money.New(0, currency), FreeDelivery: true}, nil
+	case model.BuyXGetY:
+		amount := buyXGetYAmount(promotion, cart.Lines)
+		if amount == 0 {
+			return Discount{}, ErrNoQualifyingItems
+		}
+		return Discount{Items: money.New(capDiscount(promotion, amount, cart.Subtotal.Amount), currency)}, nil
+	default:
+		return Discount{}, ErrUnknownKind
+	}
+}
+
+func checkConstraints(promotion model.Promotion, cart Cart) error {
+	switch {
+	case promotion.StartsAt != nil && cart.Now.Before(*promotion.StartsAt):
+		return ErrNotStarted
+	case promotion.EndsAt != nil && !cart.Now.Before(*promotion.EndsAt):
+		return ErrExpired
+	case promotion.RestaurantId != "" && promotion.RestaurantId != cart.RestaurantId:
+		return ErrWrongRestaurant
+	case promotion.Currency != "" && promotion.Currency != cart.Subtotal.Currency:
+		return ErrWrongCurrency
+	case cart.Subtotal.Amount < promotion.MinSubtotalAmount:
+		return ErrMinSubtotal
+	case promotion.FirstOrderOnly && cart.PreviousOrders > 0:
+		return ErrFirstOrderOnly
+	}
+
+	return CheckUsage(promotion, cart.UserRedemptions)
+}
+
+// buyXGetYAmount returns the value of the free items. When the bought and the
+// free item are the same, every group of BuyQuantity+GetQuantity items gets
+// GetQuantity of them free.
+func buyXGetYAmount(promotion model.Promotion, lines []Line) int64 {
+	if promotion.BuyQuantity <= 0 || promotion.GetQuantity <= 0 {
+		return 0
+	}
+
+	var bought, free *Line
+	for i := range lines {
+		if strings.EqualFold(lines[i].MenuItem, promotion.BuyMenuItem) {
+			bought = &lines[i]
+		}
+		if strings.EqualFold(lines[i].MenuItem, promotion.GetMenuItem) {
+			free = &lines[i]
+		}
+	}
+
+	if bought == nil || free == nil {
+		return 0
+	}
+
+	var freeQuantity int32
+	if bought == free {
+		freeQuantity = bought.Quantity / (promotion.BuyQuantity + promotion.GetQuantity) * promotion.GetQuantity
+	} else {
+		freeQuantity = min(bought.Quantity/promotion.BuyQuantity*promotion.GetQuantity, free.Quantity)
+	}
+
+	return int64(freeQuantity) * free.UnitAmount
+}
+
+func capDiscount(promotion model.Promotion, amount int64, subtotal int64) int64 {
+	if promotion.MaxDiscountAmount > 0 && amount > promotion.MaxDiscountAmount {
+		amount = promotion.MaxDiscountAmount
+	}
+
+	return min(amount, subtotal)
+}
+
+// CheckUsage re-checks the usage limits against counts read while the
+// promotion row is locked, right before it is redeemed.
+func CheckUsage(promotion model.Promotion, userRedemptions int64) error {
+	switch {
+	case promotion.GlobalLimit > 0 && promotion.UsageCount >= promotion.GlobalLimit:
+		return ErrUsageLimitReached
+	case promotion.PerUserLimit > 0 && userRedemptions >= promotion.PerUserLimit:
+		return ErrUserLimitReached
+	}
+
+	return nil
+}
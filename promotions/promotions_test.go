@@ -0,0 +1,130 @@
+package promotions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"orderService.com/go-orderService-grpc/model"
+	"orderService.com/go-orderService-grpc/money"
+)
+
+func cart() Cart {
+	return Cart{
+		RestaurantId: "1",
+		Subtotal:     money.New(60000, "INR"),
+		Lines: []Line{
+			{MenuItem: "Naan", Quantity: 5, UnitAmount: 4000},
+			{MenuItem: "Paneer Tikka", Quantity: 2, UnitAmount: 20000},
+		},
+		Now: time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestEvaluate_Discounts(t *testing.T) {
+	tests := []struct {
+		name      string
+		promotion model.Promotion
+		expected  Discount
+	}{
+		{
+			name:      "Percentage Off",
+			promotion: model.Promotion{Kind: model.PercentageOff, PercentOffBasisPoints: 1500},
+			expected:  Discount{Items: money.New(9000, "INR")},
+		},
+		{
+			name:      "Percentage Off With Cap",
+			promotion: model.Promotion{Kind: model.PercentageOff, PercentOffBasisPoints: 5000, MaxDiscountAmount: 10000},
+			expected:  Discount{Items: money.New(10000, "INR")},
+		},
+		{
+			name:      "Flat Off Never Exceeds Subtotal",
+			promotion: model.Promotion{Kind: model.FlatOff, FlatOffAmount: 100000, Currency: "INR"},
+			expected:  Discount{Items: money.New(60000, "INR")},
+		},
+		{
+			name:      "Free Delivery",
+			promotion: model.Promotion{Kind: model.FreeDelivery},
+			expected:  Discount{Items: money.New(0, "INR"), FreeDelivery: true},
+		},
+		{
+			name:      "Buy Two Naan Get One Free",
+			promotion: model.Promotion{Kind: model.BuyXGetY, BuyMenuItem: "naan", BuyQuantity: 2, GetMenuItem: "Naan", GetQuantity: 1},
+			expected:  Discount{Items: money.New(4000, "INR")},
+		},
+		{
+			name:      "Buy One Paneer Tikka Get Naan Free",
+			promotion: model.Promotion{Kind: model.BuyXGetY, BuyMenuItem: "Paneer Tikka", BuyQuantity: 1, GetMenuItem: "Naan", GetQuantity: 1},
+			expected:  Discount{Items: money.New(8000, "INR")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Evaluate(tt.promotion, cart())
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestEvaluate_PercentageOff_RoundsLikePricing(t *testing.T) {
+	odd := cart()
+	odd.Subtotal = money.New(12345, "INR")
+
+	got, err := Evaluate(model.Promotion{Kind: model.PercentageOff, PercentOffBasisPoints: 1000}, odd)
+
+	assert.Nil(t, err)
+	assert.Equal(t, money.New(1235, "INR"), got.Items)
+}
+
+func TestEvaluate_Constraints(t *testing.T) {
+	before := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	after := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		promotion model.Promotion
+		adjust    func(*Cart)
+		expected  error
+	}{
+		{name: "Not Started", promotion: model.Promotion{Kind: model.FreeDelivery, StartsAt: &after}, expected: ErrNotStarted},
+		{name: "Expired", promotion: model.Promotion{Kind: model.FreeDelivery, EndsAt: &before}, expected: ErrExpired},
+		{name: "Other Restaurant", promotion: model.Promotion{Kind: model.FreeDelivery, RestaurantId: "2"}, expected: ErrWrongRestaurant},
+		{name: "Other Currency", promotion: model.Promotion{Kind: model.FlatOff, Currency: "USD", FlatOffAmount: 100}, expected: ErrWrongCurrency},
+		{name: "Below Minimum", promotion: model.Promotion{Kind: model.FreeDelivery, MinSubtotalAmount: 60001}, expected: ErrMinSubtotal},
+		{
+			name:      "Not First Order",
+			promotion: model.Promotion{Kind: model.FreeDelivery, FirstOrderOnly: true},
+			adjust:    func(c *Cart) { c.PreviousOrders = 1 },
+			expected:  ErrFirstOrderOnly,
+		},
+		{name: "Global Limit", promotion: model.Promotion{Kind: model.FreeDelivery, GlobalLimit: 10, UsageCount: 10}, expected: ErrUsageLimitReached},
+		{
+			name:      "Per User Limit",
+			promotion: model.Promotion{Kind: model.FreeDelivery, PerUserLimit: 1},
+			adjust:    func(c *Cart) { c.UserRedemptions = 1 },
+			expected:  ErrUserLimitReached,
+		},
+		{
+			name:      "No Qualifying Items",
+			promotion: model.Promotion{Kind: model.BuyXGetY, BuyMenuItem: "Biryani", BuyQuantity: 1, GetMenuItem: "Naan", GetQuantity: 1},
+			expected:  ErrNoQualifyingItems,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := cart()
+			if tt.adjust != nil {
+				tt.adjust(&c)
+			}
+
+			_, err := Evaluate(tt.promotion, c)
+
+			assert.Equal(t, tt.expected, err)
+		})
+	}
+}
@@ -23,6 +23,25 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type OrderServiceClient interface {
 	Create(ctx context.Context, in *CreateOrderRequest, opts ...grpc.CallOption) (*CreateOrderResponse, error)
+	QuoteOrder(ctx context.Context, in *QuoteOrderRequest, opts ...grpc.CallOption) (*QuoteOrderResponse, error)
+	// CheckServiceability tells whether the restaurant delivers to the
+	// caller's address. Create and QuoteOrder fail with OUTSIDE_DELIVERY_ZONE
+	// where it says no.
+	CheckServiceability(ctx context.Context, in *CheckServiceabilityRequest, opts ...grpc.CallOption) (*CheckServiceabilityResponse, error)
+	// Reorder places a previous order of the caller again, at current prices.
+	// If items were removed from the menu or changed price, nothing is placed
+	// and the changes are returned, unless accept_changes is set.
+	Reorder(ctx context.Context, in *ReorderRequest, opts ...grpc.CallOption) (*ReorderResponse, error)
+	GetOrder(ctx context.Context, in *GetOrderRequest, opts ...grpc.CallOption) (*Order, error)
+	// WatchOrder sends the order's current state, then the new state whenever
+	// it changes, until the order is final or the client goes away. While
+	// nothing changes, a heartbeat is sent every 15 seconds.
+	WatchOrder(ctx context.Context, in *WatchOrderRequest, opts ...grpc.CallOption) (OrderService_WatchOrderClient, error)
+	// OrderChat lets the order's customer, its courier and support staff
+	// message each other. The first request names the order; the chat's
+	// history is then sent, followed by every new message, including the
+	// caller's own. The chat is closed once the order is final.
+	OrderChat(ctx context.Context, opts ...grpc.CallOption) (OrderService_OrderChatClient, error)
 }
 
 type orderServiceClient struct {
@@ -42,11 +61,129 @@ func (c *orderServiceClient) Create(ctx context.Context, in *CreateOrderRequest,
 	return out, nil
 }
 
+func (c *orderServiceClient) QuoteOrder(ctx context.Context, in *QuoteOrderRequest, opts ...grpc.CallOption) (*QuoteOrderResponse, error) {
+	out := new(QuoteOrderResponse)
+	err := c.cc.Invoke(ctx, "/proto.OrderService/QuoteOrder", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) CheckServiceability(ctx context.Context, in *CheckServiceabilityRequest, opts ...grpc.CallOption) (*CheckServiceabilityResponse, error) {
+	out := new(CheckServiceabilityResponse)
+	err := c.cc.Invoke(ctx, "/proto.OrderService/CheckServiceability", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) Reorder(ctx context.Context, in *ReorderRequest, opts ...grpc.CallOption) (*ReorderResponse, error) {
+	out := new(ReorderResponse)
+	err := c.cc.Invoke(ctx, "/proto.OrderService/Reorder", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) GetOrder(ctx context.Context, in *GetOrderRequest, opts ...grpc.CallOption) (*Order, error) {
+	out := new(Order)
+	err := c.cc.Invoke(ctx, "/proto.OrderService/GetOrder", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) WatchOrder(ctx context.Context, in *WatchOrderRequest, opts ...grpc.CallOption) (OrderService_WatchOrderClient, error) {
+	stream, err := c.cc.NewStream(ctx, &OrderService_ServiceDesc.Streams[0], "/proto.OrderService/WatchOrder", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &orderServiceWatchOrderClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type OrderService_WatchOrderClient interface {
+	Recv() (*OrderUpdate, error)
+	grpc.ClientStream
+}
+
+type orderServiceWatchOrderClient struct {
+	grpc.ClientStream
+}
+
+func (x *orderServiceWatchOrderClient) Recv() (*OrderUpdate, error) {
+	m := new(OrderUpdate)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *orderServiceClient) OrderChat(ctx context.Context, opts ...grpc.CallOption) (OrderService_OrderChatClient, error) {
+	stream, err := c.cc.NewStream(ctx, &OrderService_ServiceDesc.Streams[1], "/proto.OrderService/OrderChat", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &orderServiceOrderChatClient{stream}
+	return x, nil
+}
+
+type OrderService_OrderChatClient interface {
+	Send(*OrderChatRequest) error
+	Recv() (*OrderChatEvent, error)
+	grpc.ClientStream
+}
+
+type orderServiceOrderChatClient struct {
+	grpc.ClientStream
+}
+
+func (x *orderServiceOrderChatClient) Send(m *OrderChatRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *orderServiceOrderChatClient) Recv() (*OrderChatEvent, error) {
+	m := new(OrderChatEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // OrderServiceServer is the server API for OrderService service.
 // All implementations must embed UnimplementedOrderServiceServer
 // for forward compatibility
 type OrderServiceServer interface {
 	Create(context.Context, *CreateOrderRequest) (*CreateOrderResponse, error)
+	QuoteOrder(context.Context, *QuoteOrderRequest) (*QuoteOrderResponse, error)
+	// CheckServiceability tells whether the restaurant delivers to the
+	// caller's address. Create and QuoteOrder fail with OUTSIDE_DELIVERY_ZONE
+	// where it says no.
+	CheckServiceability(context.Context, *CheckServiceabilityRequest) (*CheckServiceabilityResponse, error)
+	// Reorder places a previous order of the caller again, at current prices.
+	// If items were removed from the menu or changed price, nothing is placed
+	// and the changes are returned, unless accept_changes is set.
+	Reorder(context.Context, *ReorderRequest) (*ReorderResponse, error)
+	GetOrder(context.Context, *GetOrderRequest) (*Order, error)
+	// WatchOrder sends the order's current state, then the new state whenever
+	// it changes, until the order is final or the client goes away. While
+	// nothing changes, a heartbeat is sent every 15 seconds.
+	WatchOrder(*WatchOrderRequest, OrderService_WatchOrderServer) error
+	// OrderChat lets the order's customer, its courier and support staff
+	// message each other. The first request names the order; the chat's
+	// history is then sent, followed by every new message, including the
+	// caller's own. The chat is closed once the order is final.
+	OrderChat(OrderService_OrderChatServer) error
 	mustEmbedUnimplementedOrderServiceServer()
 }
 
@@ -57,6 +194,24 @@ type UnimplementedOrderServiceServer struct {
 func (UnimplementedOrderServiceServer) Create(context.Context, *CreateOrderRequest) (*CreateOrderResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Create not implemented")
 }
+func (UnimplementedOrderServiceServer) QuoteOrder(context.Context, *QuoteOrderRequest) (*QuoteOrderResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QuoteOrder not implemented")
+}
+func (UnimplementedOrderServiceServer) CheckServiceability(context.Context, *CheckServiceabilityRequest) (*CheckServiceabilityResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CheckServiceability not implemented")
+}
+func (UnimplementedOrderServiceServer) Reorder(context.Context, *ReorderRequest) (*ReorderResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Reorder not implemented")
+}
+func (UnimplementedOrderServiceServer) GetOrder(context.Context, *GetOrderRequest) (*Order, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOrder not implemented")
+}
+func (UnimplementedOrderServiceServer) WatchOrder(*WatchOrderRequest, OrderService_WatchOrderServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchOrder not implemented")
+}
+func (UnimplementedOrderServiceServer) OrderChat(OrderService_OrderChatServer) error {
+	return status.Errorf(codes.Unimplemented, "method OrderChat not implemented")
+}
 func (UnimplementedOrderServiceServer) mustEmbedUnimplementedOrderServiceServer() {}
 
 // UnsafeOrderServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -88,6 +243,125 @@ func _OrderService_Create_Handler(srv interface{}, ctx context.Context, dec func
 	return interceptor(ctx, in, info, handler)
 }
 
+func _OrderService_QuoteOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QuoteOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).QuoteOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.OrderService/QuoteOrder",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).QuoteOrder(ctx, req.(*QuoteOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_CheckServiceability_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckServiceabilityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).CheckServiceability(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.OrderService/CheckServiceability",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).CheckServiceability(ctx, req.(*CheckServiceabilityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_Reorder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReorderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).Reorder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.OrderService/Reorder",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).Reorder(ctx, req.(*ReorderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_GetOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).GetOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.OrderService/GetOrder",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).GetOrder(ctx, req.(*GetOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_WatchOrder_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchOrderRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(OrderServiceServer).WatchOrder(m, &orderServiceWatchOrderServer{stream})
+}
+
+type OrderService_WatchOrderServer interface {
+	Send(*OrderUpdate) error
+	grpc.ServerStream
+}
+
+type orderServiceWatchOrderServer struct {
+	grpc.ServerStream
+}
+
+func (x *orderServiceWatchOrderServer) Send(m *OrderUpdate) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _OrderService_OrderChat_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(OrderServiceServer).OrderChat(&orderServiceOrderChatServer{stream})
+}
+
+type OrderService_OrderChatServer interface {
+	Send(*OrderChatEvent) error
+	Recv() (*OrderChatRequest, error)
+	grpc.ServerStream
+}
+
+type orderServiceOrderChatServer struct {
+	grpc.ServerStream
+}
+
+func (x *orderServiceOrderChatServer) Send(m *OrderChatEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *orderServiceOrderChatServer) Recv() (*OrderChatRequest, error) {
+	m := new(OrderChatRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // OrderService_ServiceDesc is the grpc.ServiceDesc for OrderService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -99,7 +373,1193 @@ var OrderService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Create",
 			Handler:    _OrderService_Create_Handler,
 		},
+		{
+			MethodName: "QuoteOrder",
+			Handler:    _OrderService_QuoteOrder_Handler,
+		},
+		{
+			MethodName: "CheckServiceability",
+			Handler:    _OrderService_CheckServiceability_Handler,
+		},
+		{
+			MethodName: "Reorder",
+			Handler:    _OrderService_Reorder_Handler,
+		},
+		{
+			MethodName: "GetOrder",
+			Handler:    _OrderService_GetOrder_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchOrder",
+			Handler:       _OrderService_WatchOrder_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "OrderChat",
+			Handler:       _OrderService_OrderChat_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "proto/order.proto",
+}
+
+// CartServiceClient is the client API for CartService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type CartServiceClient interface {
+	AddItem(ctx context.Context, in *AddCartItemRequest, opts ...grpc.CallOption) (*Cart, error)
+	RemoveItem(ctx context.Context, in *RemoveCartItemRequest, opts ...grpc.CallOption) (*Cart, error)
+	UpdateQuantity(ctx context.Context, in *UpdateCartItemQuantityRequest, opts ...grpc.CallOption) (*Cart, error)
+	GetCart(ctx context.Context, in *GetCartRequest, opts ...grpc.CallOption) (*Cart, error)
+	ClearCart(ctx context.Context, in *ClearCartRequest, opts ...grpc.CallOption) (*Cart, error)
+	// Checkout places an order for the cart's items and empties the cart.
+	Checkout(ctx context.Context, in *CheckoutRequest, opts ...grpc.CallOption) (*CreateOrderResponse, error)
+}
+
+type cartServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCartServiceClient(cc grpc.ClientConnInterface) CartServiceClient {
+	return &cartServiceClient{cc}
+}
+
+func (c *cartServiceClient) AddItem(ctx context.Context, in *AddCartItemRequest, opts ...grpc.CallOption) (*Cart, error) {
+	out := new(Cart)
+	err := c.cc.Invoke(ctx, "/proto.CartService/AddItem", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cartServiceClient) RemoveItem(ctx context.Context, in *RemoveCartItemRequest, opts ...grpc.CallOption) (*Cart, error) {
+	out := new(Cart)
+	err := c.cc.Invoke(ctx, "/proto.CartService/RemoveItem", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cartServiceClient) UpdateQuantity(ctx context.Context, in *UpdateCartItemQuantityRequest, opts ...grpc.CallOption) (*Cart, error) {
+	out := new(Cart)
+	err := c.cc.Invoke(ctx, "/proto.CartService/UpdateQuantity", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cartServiceClient) GetCart(ctx context.Context, in *GetCartRequest, opts ...grpc.CallOption) (*Cart, error) {
+	out := new(Cart)
+	err := c.cc.Invoke(ctx, "/proto.CartService/GetCart", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cartServiceClient) ClearCart(ctx context.Context, in *ClearCartRequest, opts ...grpc.CallOption) (*Cart, error) {
+	out := new(Cart)
+	err := c.cc.Invoke(ctx, "/proto.CartService/ClearCart", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cartServiceClient) Checkout(ctx context.Context, in *CheckoutRequest, opts ...grpc.CallOption) (*CreateOrderResponse, error) {
+	out := new(CreateOrderResponse)
+	err := c.cc.Invoke(ctx, "/proto.CartService/Checkout", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CartServiceServer is the server API for CartService service.
+// All implementations must embed UnimplementedCartServiceServer
+// for forward compatibility
+type CartServiceServer interface {
+	AddItem(context.Context, *AddCartItemRequest) (*Cart, error)
+	RemoveItem(context.Context, *RemoveCartItemRequest) (*Cart, error)
+	UpdateQuantity(context.Context, *UpdateCartItemQuantityRequest) (*Cart, error)
+	GetCart(context.Context, *GetCartRequest) (*Cart, error)
+	ClearCart(context.Context, *ClearCartRequest) (*Cart, error)
+	// Checkout places an order for the cart's items and empties the cart.
+	Checkout(context.Context, *CheckoutRequest) (*CreateOrderResponse, error)
+	mustEmbedUnimplementedCartServiceServer()
+}
+
+// UnimplementedCartServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedCartServiceServer struct {
+}
+
+func (UnimplementedCartServiceServer) AddItem(context.Context, *AddCartItemRequest) (*Cart, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddItem not implemented")
+}
+func (UnimplementedCartServiceServer) RemoveItem(context.Context, *RemoveCartItemRequest) (*Cart, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveItem not implemented")
+}
+func (UnimplementedCartServiceServer) UpdateQuantity(context.Context, *UpdateCartItemQuantityRequest) (*Cart, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateQuantity not implemented")
+}
+func (UnimplementedCartServiceServer) GetCart(context.Context, *GetCartRequest) (*Cart, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCart not implemented")
+}
+func (UnimplementedCartServiceServer) ClearCart(context.Context, *ClearCartRequest) (*Cart, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ClearCart not implemented")
+}
+func (UnimplementedCartServiceServer) Checkout(context.Context, *CheckoutRequest) (*CreateOrderResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Checkout not implemented")
+}
+func (UnimplementedCartServiceServer) mustEmbedUnimplementedCartServiceServer() {}
+
+// UnsafeCartServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CartServiceServer will
+// result in compilation errors.
+type UnsafeCartServiceServer interface {
+	mustEmbedUnimplementedCartServiceServer()
+}
+
+func RegisterCartServiceServer(s grpc.ServiceRegistrar, srv CartServiceServer) {
+	s.RegisterService(&CartService_ServiceDesc, srv)
+}
+
+func _CartService_AddItem_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddCartItemRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CartServiceServer).AddItem(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.CartService/AddItem",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CartServiceServer).AddItem(ctx, req.(*AddCartItemRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CartService_RemoveItem_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveCartItemRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CartServiceServer).RemoveItem(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.CartService/RemoveItem",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CartServiceServer).RemoveItem(ctx, req.(*RemoveCartItemRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CartService_UpdateQuantity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateCartItemQuantityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CartServiceServer).UpdateQuantity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.CartService/UpdateQuantity",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CartServiceServer).UpdateQuantity(ctx, req.(*UpdateCartItemQuantityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CartService_GetCart_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCartRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CartServiceServer).GetCart(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.CartService/GetCart",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CartServiceServer).GetCart(ctx, req.(*GetCartRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CartService_ClearCart_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClearCartRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CartServiceServer).ClearCart(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.CartService/ClearCart",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CartServiceServer).ClearCart(ctx, req.(*ClearCartRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CartService_Checkout_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckoutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CartServiceServer).Checkout(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.CartService/Checkout",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CartServiceServer).Checkout(ctx, req.(*CheckoutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// CartService_ServiceDesc is the grpc.ServiceDesc for CartService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CartService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "proto.CartService",
+	HandlerType: (*CartServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AddItem",
+			Handler:    _CartService_AddItem_Handler,
+		},
+		{
+			MethodName: "RemoveItem",
+			Handler:    _CartService_RemoveItem_Handler,
+		},
+		{
+			MethodName: "UpdateQuantity",
+			Handler:    _CartService_UpdateQuantity_Handler,
+		},
+		{
+			MethodName: "GetCart",
+			Handler:    _CartService_GetCart_Handler,
+		},
+		{
+			MethodName: "ClearCart",
+			Handler:    _CartService_ClearCart_Handler,
+		},
+		{
+			MethodName: "Checkout",
+			Handler:    _CartService_Checkout_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/order.proto",
+}
+
+// GroupOrderServiceClient is the client API for GroupOrderService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type GroupOrderServiceClient interface {
+	CreateGroupOrder(ctx context.Context, in *CreateGroupOrderRequest, opts ...grpc.CallOption) (*GroupOrder, error)
+	GetGroupOrder(ctx context.Context, in *GetGroupOrderRequest, opts ...grpc.CallOption) (*GroupOrder, error)
+	AddGroupOrderItem(ctx context.Context, in *AddGroupOrderItemRequest, opts ...grpc.CallOption) (*GroupOrder, error)
+	RemoveGroupOrderItem(ctx context.Context, in *RemoveGroupOrderItemRequest, opts ...grpc.CallOption) (*GroupOrder, error)
+	// LockGroupOrder stops participants from changing their items. Host only.
+	LockGroupOrder(ctx context.Context, in *LockGroupOrderRequest, opts ...grpc.CallOption) (*GroupOrder, error)
+	// SubmitGroupOrder places a locked group order. Host only.
+	SubmitGroupOrder(ctx context.Context, in *SubmitGroupOrderRequest, opts ...grpc.CallOption) (*SubmitGroupOrderResponse, error)
+}
+
+type groupOrderServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGroupOrderServiceClient(cc grpc.ClientConnInterface) GroupOrderServiceClient {
+	return &groupOrderServiceClient{cc}
+}
+
+func (c *groupOrderServiceClient) CreateGroupOrder(ctx context.Context, in *CreateGroupOrderRequest, opts ...grpc.CallOption) (*GroupOrder, error) {
+	out := new(GroupOrder)
+	err := c.cc.Invoke(ctx, "/proto.GroupOrderService/CreateGroupOrder", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *groupOrderServiceClient) GetGroupOrder(ctx context.Context, in *GetGroupOrderRequest, opts ...grpc.CallOption) (*GroupOrder, error) {
+	out := new(GroupOrder)
+	err := c.cc.Invoke(ctx, "/proto.GroupOrderService/GetGroupOrder", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *groupOrderServiceClient) AddGroupOrderItem(ctx context.Context, in *AddGroupOrderItemRequest, opts ...grpc.CallOption) (*GroupOrder, error) {
+	out := new(GroupOrder)
+	err := c.cc.Invoke(ctx, "/proto.GroupOrderService/AddGroupOrderItem", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *groupOrderServiceClient) RemoveGroupOrderItem(ctx context.Context, in *RemoveGroupOrderItemRequest, opts ...grpc.CallOption) (*GroupOrder, error) {
+	out := new(GroupOrder)
+	err := c.cc.Invoke(ctx, "/proto.GroupOrderService/RemoveGroupOrderItem", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *groupOrderServiceClient) LockGroupOrder(ctx context.Context, in *LockGroupOrderRequest, opts ...grpc.CallOption) (*GroupOrder, error) {
+	out := new(GroupOrder)
+	err := c.cc.Invoke(ctx, "/proto.GroupOrderService/LockGroupOrder", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *groupOrderServiceClient) SubmitGroupOrder(ctx context.Context, in *SubmitGroupOrderRequest, opts ...grpc.CallOption) (*SubmitGroupOrderResponse, error) {
+	out := new(SubmitGroupOrderResponse)
+	err := c.cc.Invoke(ctx, "/proto.GroupOrderService/SubmitGroupOrder", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GroupOrderServiceServer is the server API for GroupOrderService service.
+// All implementations must embed UnimplementedGroupOrderServiceServer
+// for forward compatibility
+type GroupOrderServiceServer interface {
+	CreateGroupOrder(context.Context, *CreateGroupOrderRequest) (*GroupOrder, error)
+	GetGroupOrder(context.Context, *GetGroupOrderRequest) (*GroupOrder, error)
+	AddGroupOrderItem(context.Context, *AddGroupOrderItemRequest) (*GroupOrder, error)
+	RemoveGroupOrderItem(context.Context, *RemoveGroupOrderItemRequest) (*GroupOrder, error)
+	// LockGroupOrder stops participants from changing their items. Host only.
+	LockGroupOrder(context.Context, *LockGroupOrderRequest) (*GroupOrder, error)
+	// SubmitGroupOrder places a locked group order. Host only.
+	SubmitGroupOrder(context.Context, *SubmitGroupOrderRequest) (*SubmitGroupOrderResponse, error)
+	mustEmbedUnimplementedGroupOrderServiceServer()
+}
+
+// UnimplementedGroupOrderServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedGroupOrderServiceServer struct {
+}
+
+func (UnimplementedGroupOrderServiceServer) CreateGroupOrder(context.Context, *CreateGroupOrderRequest) (*GroupOrder, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateGroupOrder not implemented")
+}
+func (UnimplementedGroupOrderServiceServer) GetGroupOrder(context.Context, *GetGroupOrderRequest) (*GroupOrder, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetGroupOrder not implemented")
+}
+func (UnimplementedGroupOrderServiceServer) AddGroupOrderItem(context.Context, *AddGroupOrderItemRequest) (*GroupOrder, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddGroupOrderItem not implemented")
+}
+func (UnimplementedGroupOrderServiceServer) RemoveGroupOrderItem(context.Context, *RemoveGroupOrderItemRequest) (*GroupOrder, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveGroupOrderItem not implemented")
+}
+func (UnimplementedGroupOrderServiceServer) LockGroupOrder(context.Context, *LockGroupOrderRequest) (*GroupOrder, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LockGroupOrder not implemented")
+}
+func (UnimplementedGroupOrderServiceServer) SubmitGroupOrder(context.Context, *SubmitGroupOrderRequest) (*SubmitGroupOrderResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubmitGroupOrder not implemented")
+}
+func (UnimplementedGroupOrderServiceServer) mustEmbedUnimplementedGroupOrderServiceServer() {}
+
+// UnsafeGroupOrderServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to GroupOrderServiceServer will
+// result in compilation errors.
+type UnsafeGroupOrderServiceServer interface {
+	mustEmbedUnimplementedGroupOrderServiceServer()
+}
+
+func RegisterGroupOrderServiceServer(s grpc.ServiceRegistrar, srv GroupOrderServiceServer) {
+	s.RegisterService(&GroupOrderService_ServiceDesc, srv)
+}
+
+func _GroupOrderService_CreateGroupOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateGroupOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GroupOrderServiceServer).CreateGroupOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GroupOrderService/CreateGroupOrder",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GroupOrderServiceServer).CreateGroupOrder(ctx, req.(*CreateGroupOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GroupOrderService_GetGroupOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetGroupOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GroupOrderServiceServer).GetGroupOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GroupOrderService/GetGroupOrder",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GroupOrderServiceServer).GetGroupOrder(ctx, req.(*GetGroupOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GroupOrderService_AddGroupOrderItem_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddGroupOrderItemRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GroupOrderServiceServer).AddGroupOrderItem(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GroupOrderService/AddGroupOrderItem",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GroupOrderServiceServer).AddGroupOrderItem(ctx, req.(*AddGroupOrderItemRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GroupOrderService_RemoveGroupOrderItem_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveGroupOrderItemRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GroupOrderServiceServer).RemoveGroupOrderItem(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GroupOrderService/RemoveGroupOrderItem",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GroupOrderServiceServer).RemoveGroupOrderItem(ctx, req.(*RemoveGroupOrderItemRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GroupOrderService_LockGroupOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LockGroupOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GroupOrderServiceServer).LockGroupOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GroupOrderService/LockGroupOrder",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GroupOrderServiceServer).LockGroupOrder(ctx, req.(*LockGroupOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GroupOrderService_SubmitGroupOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubmitGroupOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GroupOrderServiceServer).SubmitGroupOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.GroupOrderService/SubmitGroupOrder",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GroupOrderServiceServer).SubmitGroupOrder(ctx, req.(*SubmitGroupOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// GroupOrderService_ServiceDesc is the grpc.ServiceDesc for GroupOrderService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var GroupOrderService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "proto.GroupOrderService",
+	HandlerType: (*GroupOrderServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateGroupOrder",
+			Handler:    _GroupOrderService_CreateGroupOrder_Handler,
+		},
+		{
+			MethodName: "GetGroupOrder",
+			Handler:    _GroupOrderService_GetGroupOrder_Handler,
+		},
+		{
+			MethodName: "AddGroupOrderItem",
+			Handler:    _GroupOrderService_AddGroupOrderItem_Handler,
+		},
+		{
+			MethodName: "RemoveGroupOrderItem",
+			Handler:    _GroupOrderService_RemoveGroupOrderItem_Handler,
+		},
+		{
+			MethodName: "LockGroupOrder",
+			Handler:    _GroupOrderService_LockGroupOrder_Handler,
+		},
+		{
+			MethodName: "SubmitGroupOrder",
+			Handler:    _GroupOrderService_SubmitGroupOrder_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/order.proto",
+}
+
+// LoyaltyServiceClient is the client API for LoyaltyService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type LoyaltyServiceClient interface {
+	// GetLoyaltyBalance returns the caller's points, after taking off those
+	// that expired.
+	GetLoyaltyBalance(ctx context.Context, in *GetLoyaltyBalanceRequest, opts ...grpc.CallOption) (*LoyaltyBalance, error)
+	// ListLoyaltyTransactions returns the caller's transactions, newest first.
+	ListLoyaltyTransactions(ctx context.Context, in *ListLoyaltyTransactionsRequest, opts ...grpc.CallOption) (*ListLoyaltyTransactionsResponse, error)
+}
+
+type loyaltyServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewLoyaltyServiceClient(cc grpc.ClientConnInterface) LoyaltyServiceClient {
+	return &loyaltyServiceClient{cc}
+}
+
+func (c *loyaltyServiceClient) GetLoyaltyBalance(ctx context.Context, in *GetLoyaltyBalanceRequest, opts ...grpc.CallOption) (*LoyaltyBalance, error) {
+	out := new(LoyaltyBalance)
+	err := c.cc.Invoke(ctx, "/proto.LoyaltyService/GetLoyaltyBalance", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *loyaltyServiceClient) ListLoyaltyTransactions(ctx context.Context, in *ListLoyaltyTransactionsRequest, opts ...grpc.CallOption) (*ListLoyaltyTransactionsResponse, error) {
+	out := new(ListLoyaltyTransactionsResponse)
+	err := c.cc.Invoke(ctx, "/proto.LoyaltyService/ListLoyaltyTransactions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// LoyaltyServiceServer is the server API for LoyaltyService service.
+// All implementations must embed UnimplementedLoyaltyServiceServer
+// for forward compatibility
+type LoyaltyServiceServer interface {
+	// GetLoyaltyBalance returns the caller's points, after taking off those
+	// that expired.
+	GetLoyaltyBalance(context.Context, *GetLoyaltyBalanceRequest) (*LoyaltyBalance, error)
+	// ListLoyaltyTransactions returns the caller's transactions, newest first.
+	ListLoyaltyTransactions(context.Context, *ListLoyaltyTransactionsRequest) (*ListLoyaltyTransactionsResponse, error)
+	mustEmbedUnimplementedLoyaltyServiceServer()
+}
+
+// UnimplementedLoyaltyServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedLoyaltyServiceServer struct {
+}
+
+func (UnimplementedLoyaltyServiceServer) GetLoyaltyBalance(context.Context, *GetLoyaltyBalanceRequest) (*LoyaltyBalance, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLoyaltyBalance not implemented")
+}
+func (UnimplementedLoyaltyServiceServer) ListLoyaltyTransactions(context.Context, *ListLoyaltyTransactionsRequest) (*ListLoyaltyTransactionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListLoyaltyTransactions not implemented")
+}
+func (UnimplementedLoyaltyServiceServer) mustEmbedUnimplementedLoyaltyServiceServer() {}
+
+// UnsafeLoyaltyServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to LoyaltyServiceServer will
+// result in compilation errors.
+type UnsafeLoyaltyServiceServer interface {
+	mustEmbedUnimplementedLoyaltyServiceServer()
+}
+
+func RegisterLoyaltyServiceServer(s grpc.ServiceRegistrar, srv LoyaltyServiceServer) {
+	s.RegisterService(&LoyaltyService_ServiceDesc, srv)
+}
+
+func _LoyaltyService_GetLoyaltyBalance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLoyaltyBalanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LoyaltyServiceServer).GetLoyaltyBalance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.LoyaltyService/GetLoyaltyBalance",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LoyaltyServiceServer).GetLoyaltyBalance(ctx, req.(*GetLoyaltyBalanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LoyaltyService_ListLoyaltyTransactions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListLoyaltyTransactionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LoyaltyServiceServer).ListLoyaltyTransactions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.LoyaltyService/ListLoyaltyTransactions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LoyaltyServiceServer).ListLoyaltyTransactions(ctx, req.(*ListLoyaltyTransactionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// LoyaltyService_ServiceDesc is the grpc.ServiceDesc for LoyaltyService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var LoyaltyService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "proto.LoyaltyService",
+	HandlerType: (*LoyaltyServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetLoyaltyBalance",
+			Handler:    _LoyaltyService_GetLoyaltyBalance_Handler,
+		},
+		{
+			MethodName: "ListLoyaltyTransactions",
+			Handler:    _LoyaltyService_ListLoyaltyTransactions_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/order.proto",
+}
+
+// WalletServiceClient is the client API for WalletService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type WalletServiceClient interface {
+	// GetWallet returns the caller's balance in each currency they hold.
+	GetWallet(ctx context.Context, in *GetWalletRequest, opts ...grpc.CallOption) (*Wallet, error)
+	// ListWalletTransactions returns the caller's transactions, newest first.
+	ListWalletTransactions(ctx context.Context, in *ListWalletTransactionsRequest, opts ...grpc.CallOption) (*ListWalletTransactionsResponse, error)
+}
+
+type walletServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewWalletServiceClient(cc grpc.ClientConnInterface) WalletServiceClient {
+	return &walletServiceClient{cc}
+}
+
+func (c *walletServiceClient) GetWallet(ctx context.Context, in *GetWalletRequest, opts ...grpc.CallOption) (*Wallet, error) {
+	out := new(Wallet)
+	err := c.cc.Invoke(ctx, "/proto.WalletService/GetWallet", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) ListWalletTransactions(ctx context.Context, in *ListWalletTransactionsRequest, opts ...grpc.CallOption) (*ListWalletTransactionsResponse, error) {
+	out := new(ListWalletTransactionsResponse)
+	err := c.cc.Invoke(ctx, "/proto.WalletService/ListWalletTransactions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// WalletServiceServer is the server API for WalletService service.
+// All implementations must embed UnimplementedWalletServiceServer
+// for forward compatibility
+type WalletServiceServer interface {
+	// GetWallet returns the caller's balance in each currency they hold.
+	GetWallet(context.Context, *GetWalletRequest) (*Wallet, error)
+	// ListWalletTransactions returns the caller's transactions, newest first.
+	ListWalletTransactions(context.Context, *ListWalletTransactionsRequest) (*ListWalletTransactionsResponse, error)
+	mustEmbedUnimplementedWalletServiceServer()
+}
+
+// UnimplementedWalletServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedWalletServiceServer struct {
+}
+
+func (UnimplementedWalletServiceServer) GetWallet(context.Context, *GetWalletRequest) (*Wallet, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetWallet not implemented")
+}
+func (UnimplementedWalletServiceServer) ListWalletTransactions(context.Context, *ListWalletTransactionsRequest) (*ListWalletTransactionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListWalletTransactions not implemented")
+}
+func (UnimplementedWalletServiceServer) mustEmbedUnimplementedWalletServiceServer() {}
+
+// UnsafeWalletServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to WalletServiceServer will
+// result in compilation errors.
+type UnsafeWalletServiceServer interface {
+	mustEmbedUnimplementedWalletServiceServer()
+}
+
+func RegisterWalletServiceServer(s grpc.ServiceRegistrar, srv WalletServiceServer) {
+	s.RegisterService(&WalletService_ServiceDesc, srv)
+}
+
+func _WalletService_GetWallet_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetWalletRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).GetWallet(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.WalletService/GetWallet",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).GetWallet(ctx, req.(*GetWalletRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_ListWalletTransactions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListWalletTransactionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).ListWalletTransactions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.WalletService/ListWalletTransactions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).ListWalletTransactions(ctx, req.(*ListWalletTransactionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// WalletService_ServiceDesc is the grpc.ServiceDesc for WalletService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var WalletService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "proto.WalletService",
+	HandlerType: (*WalletServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetWallet",
+			Handler:    _WalletService_GetWallet_Handler,
+		},
+		{
+			MethodName: "ListWalletTransactions",
+			Handler:    _WalletService_ListWalletTransactions_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/order.proto",
+}
+
+// WalletAdminServiceClient is the client API for WalletAdminService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type WalletAdminServiceClient interface {
+	// IssueWalletCredit credits a customer's wallet. Sending the same
+	// idempotency_key again returns the credit already issued instead of
+	// issuing another.
+	IssueWalletCredit(ctx context.Context, in *IssueWalletCreditRequest, opts ...grpc.CallOption) (*WalletTransaction, error)
+	// GetCustomerWallet returns a customer's balance in each currency.
+	GetCustomerWallet(ctx context.Context, in *GetCustomerWalletRequest, opts ...grpc.CallOption) (*Wallet, error)
+}
+
+type walletAdminServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewWalletAdminServiceClient(cc grpc.ClientConnInterface) WalletAdminServiceClient {
+	return &walletAdminServiceClient{cc}
+}
+
+func (c *walletAdminServiceClient) IssueWalletCredit(ctx context.Context, in *IssueWalletCreditRequest, opts ...grpc.CallOption) (*WalletTransaction, error) {
+	out := new(WalletTransaction)
+	err := c.cc.Invoke(ctx, "/proto.WalletAdminService/IssueWalletCredit", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletAdminServiceClient) GetCustomerWallet(ctx context.Context, in *GetCustomerWalletRequest, opts ...grpc.CallOption) (*Wallet, error) {
+	out := new(Wallet)
+	err := c.cc.Invoke(ctx, "/proto.WalletAdminService/GetCustomerWallet", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// WalletAdminServiceServer is the server API for WalletAdminService service.
+// All implementations must embed UnimplementedWalletAdminServiceServer
+// for forward compatibility
+type WalletAdminServiceServer interface {
+	// IssueWalletCredit credits a customer's wallet. Sending the same
+	// idempotency_key again returns the credit already issued instead of
+	// issuing another.
+	IssueWalletCredit(context.Context, *IssueWalletCreditRequest) (*WalletTransaction, error)
+	// GetCustomerWallet returns a customer's balance in each currency.
+	GetCustomerWallet(context.Context, *GetCustomerWalletRequest) (*Wallet, error)
+	mustEmbedUnimplementedWalletAdminServiceServer()
+}
+
+// UnimplementedWalletAdminServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedWalletAdminServiceServer struct {
+}
+
+func (UnimplementedWalletAdminServiceServer) IssueWalletCredit(context.Context, *IssueWalletCreditRequest) (*WalletTransaction, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IssueWalletCredit not implemented")
+}
+func (UnimplementedWalletAdminServiceServer) GetCustomerWallet(context.Context, *GetCustomerWalletRequest) (*Wallet, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCustomerWallet not implemented")
+}
+func (UnimplementedWalletAdminServiceServer) mustEmbedUnimplementedWalletAdminServiceServer() {}
+
+// UnsafeWalletAdminServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to WalletAdminServiceServer will
+// result in compilation errors.
+type UnsafeWalletAdminServiceServer interface {
+	mustEmbedUnimplementedWalletAdminServiceServer()
+}
+
+func RegisterWalletAdminServiceServer(s grpc.ServiceRegistrar, srv WalletAdminServiceServer) {
+	s.RegisterService(&WalletAdminService_ServiceDesc, srv)
+}
+
+func _WalletAdminService_IssueWalletCredit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IssueWalletCreditRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletAdminServiceServer).IssueWalletCredit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.WalletAdminService/IssueWalletCredit",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletAdminServiceServer).IssueWalletCredit(ctx, req.(*IssueWalletCreditRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletAdminService_GetCustomerWallet_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCustomerWalletRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletAdminServiceServer).GetCustomerWallet(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.WalletAdminService/GetCustomerWallet",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletAdminServiceServer).GetCustomerWallet(ctx, req.(*GetCustomerWalletRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// WalletAdminService_ServiceDesc is the grpc.ServiceDesc for WalletAdminService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var WalletAdminService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "proto.WalletAdminService",
+	HandlerType: (*WalletAdminServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "IssueWalletCredit",
+			Handler:    _WalletAdminService_IssueWalletCredit_Handler,
+		},
+		{
+			MethodName: "GetCustomerWallet",
+			Handler:    _WalletAdminService_GetCustomerWallet_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/order.proto",
+}
+
+// RestaurantServiceClient is the client API for RestaurantService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type RestaurantServiceClient interface {
+	// ListIncomingOrders returns the orders waiting for acceptance, oldest
+	// first.
+	ListIncomingOrders(ctx context.Context, in *ListIncomingOrdersRequest, opts ...grpc.CallOption) (*ListIncomingOrdersResponse, error)
+	AcceptOrder(ctx context.Context, in *AcceptOrderRequest, opts ...grpc.CallOption) (*Order, error)
+	RejectOrder(ctx context.Context, in *RejectOrderRequest, opts ...grpc.CallOption) (*Order, error)
+	// StreamRestaurantOrders sends the restaurant's open orders, then every
+	// new or changed order, until the client goes away. Each event carries a
+	// cursor; a client that reconnects with the last cursor it received gets
+	// the orders that changed since, in their current state. While nothing
+	// changes, a heartbeat is sent every 15 seconds.
+	StreamRestaurantOrders(ctx context.Context, in *StreamRestaurantOrdersRequest, opts ...grpc.CallOption) (RestaurantService_StreamRestaurantOrdersClient, error)
+}
+
+type restaurantServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRestaurantServiceClient(cc grpc.ClientConnInterface) RestaurantServiceClient {
+	return &restaurantServiceClient{cc}
+}
+
+func (c *restaurantServiceClient) ListIncomingOrders(ctx context.Context, in *ListIncomingOrdersRequest, opts ...grpc.CallOption) (*ListIncomingOrdersResponse, error) {
+	out := new(ListIncomingOrdersResponse)
+	err := c.cc.Invoke(ctx, "/proto.RestaurantService/ListIncomingOrders", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *restaurantServiceClient) AcceptOrder(ctx context.Context, in *AcceptOrderRequest, opts ...grpc.CallOption) (*Order, error) {
+	out := new(Order)
+	err := c.cc.Invoke(ctx, "/proto.RestaurantService/AcceptOrder", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *restaurantServiceClient) RejectOrder(ctx context.Context, in *RejectOrderRequest, opts ...grpc.CallOption) (*Order, error) {
+	out := new(Order)
+	err := c.cc.Invoke(ctx, "/proto.RestaurantService/RejectOrder", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *restaurantServiceClient) StreamRestaurantOrders(ctx context.Context, in *StreamRestaurantOrdersRequest, opts ...grpc.CallOption) (RestaurantService_StreamRestaurantOrdersClient, error) {
+	stream, err := c.cc.NewStream(ctx, &RestaurantService_ServiceDesc.Streams[0], "/proto.RestaurantService/StreamRestaurantOrders", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &restaurantServiceStreamRestaurantOrdersClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type RestaurantService_StreamRestaurantOrdersClient interface {
+	Recv() (*RestaurantOrderEvent, error)
+	grpc.ClientStream
+}
+
+type restaurantServiceStreamRestaurantOrdersClient struct {
+	grpc.ClientStream
+}
+
+func (x *restaurantServiceStreamRestaurantOrdersClient) Recv() (*RestaurantOrderEvent, error) {
+	m := new(RestaurantOrderEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// RestaurantServiceServer is the server API for RestaurantService service.
+// All implementations must embed UnimplementedRestaurantServiceServer
+// for forward compatibility
+type RestaurantServiceServer interface {
+	// ListIncomingOrders returns the orders waiting for acceptance, oldest
+	// first.
+	ListIncomingOrders(context.Context, *ListIncomingOrdersRequest) (*ListIncomingOrdersResponse, error)
+	AcceptOrder(context.Context, *AcceptOrderRequest) (*Order, error)
+	RejectOrder(context.Context, *RejectOrderRequest) (*Order, error)
+	// StreamRestaurantOrders sends the restaurant's open orders, then every
+	// new or changed order, until the client goes away. Each event carries a
+	// cursor; a client that reconnects with the last cursor it received gets
+	// the orders that changed since, in their current state. While nothing
+	// changes, a heartbeat is sent every 15 seconds.
+	StreamRestaurantOrders(*StreamRestaurantOrdersRequest, RestaurantService_StreamRestaurantOrdersServer) error
+	mustEmbedUnimplementedRestaurantServiceServer()
+}
+
+// UnimplementedRestaurantServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedRestaurantServiceServer struct {
+}
+
+func (UnimplementedRestaurantServiceServer) ListIncomingOrders(context.Context, *ListIncomingOrdersRequest) (*ListIncomingOrdersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListIncomingOrders not implemented")
+}
+func (UnimplementedRestaurantServiceServer) AcceptOrder(context.Context, *AcceptOrderRequest) (*Order, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AcceptOrder not implemented")
+}
+func (UnimplementedRestaurantServiceServer) RejectOrder(context.Context, *RejectOrderRequest) (*Order, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RejectOrder not implemented")
+}
+func (UnimplementedRestaurantServiceServer) StreamRestaurantOrders(*StreamRestaurantOrdersRequest, RestaurantService_StreamRestaurantOrdersServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamRestaurantOrders not implemented")
+}
+func (UnimplementedRestaurantServiceServer) mustEmbedUnimplementedRestaurantServiceServer() {}
+
+// UnsafeRestaurantServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RestaurantServiceServer will
+// result in compilation errors.
+type UnsafeRestaurantServiceServer interface {
+	mustEmbedUnimplementedRestaurantServiceServer()
+}
+
+func RegisterRestaurantServiceServer(s grpc.ServiceRegistrar, srv RestaurantServiceServer) {
+	s.RegisterService(&RestaurantService_ServiceDesc, srv)
+}
+
+func _RestaurantService_ListIncomingOrders_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListIncomingOrdersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RestaurantServiceServer).ListIncomingOrders(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.RestaurantService/ListIncomingOrders",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RestaurantServiceServer).ListIncomingOrders(ctx, req.(*ListIncomingOrdersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RestaurantService_AcceptOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AcceptOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RestaurantServiceServer).AcceptOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.RestaurantService/AcceptOrder",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RestaurantServiceServer).AcceptOrder(ctx, req.(*AcceptOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RestaurantService_RejectOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RejectOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RestaurantServiceServer).RejectOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.RestaurantService/RejectOrder",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RestaurantServiceServer).RejectOrder(ctx, req.(*RejectOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RestaurantService_StreamRestaurantOrders_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamRestaurantOrdersRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RestaurantServiceServer).StreamRestaurantOrders(m, &restaurantServiceStreamRestaurantOrdersServer{stream})
+}
+
+type RestaurantService_StreamRestaurantOrdersServer interface {
+	Send(*RestaurantOrderEvent) error
+	grpc.ServerStream
+}
+
+type restaurantServiceStreamRestaurantOrdersServer struct {
+	grpc.ServerStream
+}
+
+func (x *restaurantServiceStreamRestaurantOrdersServer) Send(m *RestaurantOrderEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// RestaurantService_ServiceDesc is the grpc.ServiceDesc for RestaurantService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RestaurantService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "proto.RestaurantService",
+	HandlerType: (*RestaurantServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListIncomingOrders",
+			Handler:    _RestaurantService_ListIncomingOrders_Handler,
+		},
+		{
+			MethodName: "AcceptOrder",
+			Handler:    _RestaurantService_AcceptOrder_Handler,
+		},
+		{
+			MethodName: "RejectOrder",
+			Handler:    _RestaurantService_RejectOrder_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamRestaurantOrders",
+			Handler:       _RestaurantService_StreamRestaurantOrders_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/order.proto",
 }
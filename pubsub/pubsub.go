@@ -0,0 +1,83 @@
+// Package pubsub notifies in-process subscribers that something identified by
+// a key, such as an order, has changed. Notifications carry no data:
+// subscribers re-read the current state, so a slow subscriber only ever has
+// one pending notification and never holds up a publisher.
+package pubsub
+
+import "sync"
+
+type Hub[K comparable] struct {
+	mu          sync.Mutex
+	subscribers map[K]map[*Subscription[K]]struct{}
+}
+
+func NewHub[K comparable]() *Hub[K] {
+	return &Hub[K]{subscribers: make(map[K]map[*Subscription[K]]struct{})}
+}
+
+// Subscription receives a value on C after each change to its key. Changes
+// published while a value is pending are merged into it.
+type Subscription[K comparable] struct {
+	C <-chan struct{}
+
+	hub     *Hub[K]
+	key     K
+	notify  chan struct{}
+	closing sync.Once
+}
+
+func (hub *Hub[K]) Subscribe(key K) *Subscription[K] {
+	notify := make(chan struct{}, 1)
+	subscription := &Subscription[K]{C: notify, hub: hub, key: key, notify: notify}
+
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	if hub.subscribers[key] == nil {
+		hub.subscribers[key] = make(map[*Subscription[K]]struct{})
+	}
+	hub.subscribers[key][subscription] = struct{}{}
+
+	return subscription
+}
+
+// Publish notifies the subscribers of key. It is safe to call on a nil Hub,
+// which has no subscribers.
+func (hub *Hub[K]) Publish(key K) {
+	if hub == nil {
+		return
+	}
+
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	for subscription := range hub.subscribers[key] {
+		select {
+		case subscription.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Subscribers returns how many subscriptions key has.
+func (hub *Hub[K]) Subscribers(key K) int {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	return len(hub.subscribers[key])
+}
+
+// Close stops notifications. It may be called more than once.
+func (subscription *Subscription[K]) Close() {
+	subscription.closing.Do(func() {
+		hub := subscription.hub
+
+		hub.mu.Lock()
+		defer hub.mu.Unlock()
+
+		delete(hub.subscribers[subscription.key], subscription)
+		if len(hub.subscribers[subscription.key]) == 0 {
+			delete(hub.subscribers, subscription.key)
+		}
+	})
+}
@@ -0,0 +1,60 @@
+package pubsub
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func pending(subscription *Subscription[int64]) int {
+	count := 0
+	for {
+		select {
+		case <-subscription.C:
+			count++
+		default:
+			return count
+		}
+	}
+}
+
+func TestPublish_NotifiesSubscribersOfKeyOnly(t *testing.T) {
+	hub := NewHub[int64]()
+	first := hub.Subscribe(1)
+	second := hub.Subscribe(1)
+	other := hub.Subscribe(2)
+
+	hub.Publish(1)
+
+	assert.Equal(t, 1, pending(first))
+	assert.Equal(t, 1, pending(second))
+	assert.Equal(t, 0, pending(other))
+}
+
+func TestPublish_MergesPendingNotifications(t *testing.T) {
+	hub := NewHub[int64]()
+	subscription := hub.Subscribe(1)
+
+	hub.Publish(1)
+	hub.Publish(1)
+	hub.Publish(1)
+
+	assert.Equal(t, 1, pending(subscription))
+}
+
+func TestClose_Unsubscribes(t *testing.T) {
+	hub := NewHub[int64]()
+	subscription := hub.Subscribe(1)
+
+	subscription.Close()
+	subscription.Close()
+	hub.Publish(1)
+
+	assert.Equal(t, 0, pending(subscription))
+	assert.Equal(t, 0, hub.Subscribers(1))
+}
+
+func TestPublish_NilHub(t *testing.T) {
+	var hub *Hub[int64]
+	assert.NotPanics(t, func() { hub.Publish(1) })
+}
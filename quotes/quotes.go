@@ -0,0 +1,105 @@
+package quotes
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"orderService.com/go-orderService-grpc/model"
+	"orderService.com/go-orderService-grpc/pricing"
+)
+
+var (
+	ErrInvalidToken = errors.New("quote token is invalid")
+	ErrExpired      = errors.New("quote has expired")
+)
+
+// Quote is a priced order that the customer may place unchanged until
+// ExpiresAt. It is carried by the client as a signed token, so nothing about
+// it is stored server side.
+type Quote struct {
+	Username     string            `json:"username"`
+	RestaurantId string            `json:"restaurant_id"`
+	MenuItems    map[string]int32  `json:"menu_items"`
+	Lines        []Line            `json:"lines,omitempty"`
+	PromoCode    string            `json:"promo_code,omitempty"`
+	PromotionId  int64             `json:"promotion_id,omitempty"`
+	Items        []model.OrderItem `json:"items"`
+	Breakdown    pricing.Breakdown `json:"breakdown"`
+	ExpiresAt    time.Time         `json:"expires_at"`
+
+	// PromotionDiscount is what the promotion took off the items.
+	PromotionDiscount int64 `json:"promotion_discount,omitempty"`
+
+	// RedeemPoints were asked for; LoyaltyPoints of them were spent, taking
+	// LoyaltyDiscount off the items.
+	RedeemPoints    int64 `json:"redeem_points,omitempty"`
+	LoyaltyPoints   int64 `json:"loyalty_points,omitempty"`
+	LoyaltyDiscount int64 `json:"loyalty_discount,omitempty"`
+}
+
+// Line is a requested line with modifier options or instructions, as sent to
+// QuoteOrder.
+type Line struct {
+	MenuItemId   string   `json:"menu_item_id"`
+	Quantity     int32    `json:"quantity"`
+	OptionIds    []string `json:"option_ids,omitempty"`
+	Instructions string   `json:"instructions,omitempty"`
+}
+
+// Signer issues and verifies quote tokens with HMAC-SHA256.
+type Signer struct {
+	key []byte
+	ttl time.Duration
+	now func() time.Time
+}
+
+func NewSigner(key []byte, ttl time.Duration) *Signer {
+	return &Signer{key: key, ttl: ttl, now: time.Now}
+}
+
+// Sign stamps the quote with its expiry and returns the token for it.
+func (signer *Signer) Sign(quote Quote) (string, Quote, error) {
+	quote.ExpiresAt = signer.now().Add(signer.ttl).UTC().Truncate(time.Second)
+
+	payload, err := json.Marshal(quote)
+	if err != nil {
+		return "", Quote{}, err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + signer.signature(encoded), quote, nil
+}
+
+func (signer *Signer) Verify(token string) (Quote, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(signer.signature(encoded))) {
+		return Quote{}, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Quote{}, ErrInvalidToken
+	}
+
+	var quote Quote
+	if err := json.Unmarshal(payload, &quote); err != nil {
+		return Quote{}, ErrInvalidToken
+	}
+
+	if !signer.now().Before(quote.ExpiresAt) {
+		return Quote{}, ErrExpired
+	}
+
+	return quote, nil
+}
+
+func (signer *Signer) signature(encoded string) string {
+	mac := hmac.New(sha256.New, signer.key)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,49 @@
+package quotes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"orderService.com/go-orderService-grpc/money"
+	"orderService.com/go-orderService-grpc/pricing"
+)
+
+func TestSignAndVerify_RoundTrips(t *testing.T) {
+	signer := NewSigner([]byte("secret"), 5*time.Minute)
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	signer.now = func() time.Time { return now }
+
+	token, signed, err := signer.Sign(Quote{
+		Username:     "username",
+		RestaurantId: "1",
+		MenuItems:    map[string]int32{"Naan": 2},
+		Breakdown:    pricing.Breakdown{Total: money.New(8000, "INR")},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, now.Add(5*time.Minute), signed.ExpiresAt)
+
+	got, err := signer.Verify(token)
+	assert.Nil(t, err)
+	assert.Equal(t, signed, got)
+}
+
+func TestVerify_RejectsTamperedForeignAndExpiredTokens(t *testing.T) {
+	signer := NewSigner([]byte("secret"), 5*time.Minute)
+	token, _, err := signer.Sign(Quote{Username: "username"})
+	assert.Nil(t, err)
+
+	_, err = signer.Verify("x" + token)
+	assert.Equal(t, ErrInvalidToken, err)
+
+	_, err = NewSigner([]byte("other secret"), 5*time.Minute).Verify(token)
+	assert.Equal(t, ErrInvalidToken, err)
+
+	_, err = signer.Verify("not a token")
+	assert.Equal(t, ErrInvalidToken, err)
+
+	signer.now = func() time.Time { return time.Now().Add(6 * time.Minute) }
+	_, err = signer.Verify(token)
+	assert.Equal(t, ErrExpired, err)
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"maps"
+
+	"gorm.io/gorm"
+	database "orderService.com/go-orderService-grpc/db"
+	"orderService.com/go-orderService-grpc/model"
+	o "orderService.com/go-orderService-grpc/proto/order"
+)
+
+const (
+	maxCartItems        = 50
+	maxCartItemQuantity = 99
+)
+
+// CartServiceServer shares the OrderServiceServer's database, catalog and
+// pricing, and places orders through it on Checkout.
+type CartServiceServer struct {
+	Orders *OrderServiceServer
+	o.CartServiceServer
+}
+
+func (cartServer *CartServiceServer) AddItem(ctx context.Context, req *o.AddCartItemRequest) (*o.Cart, error) {
+	user, err := cartServer.Orders.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Checks that the restaurant sells the item before it goes in the cart.
+	_, _, err = calculateOrderTotal(&o.CreateOrderRequest{
+		RestaurantId: req.RestaurantId,
+		MenuItems:    map[string]int32{req.MenuItem: req.Quantity},
+	}, cartServer.Orders.CatalogServiceAPI)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	err = cartServer.Orders.DB.Transaction(func(tx *gorm.DB) error {
+		cart, err := database.LockCart(tx, user.Username, req.RestaurantId)
+		if err != nil {
+			return err
+		}
+
+		if cart.RestaurantId != req.RestaurantId {
+			if len(cart.Items) > 0 && !req.ReplaceCart {
+				return errCartRestaurantMismatch(cart.RestaurantId, req.RestaurantId)
+			}
+
+			if err := database.ResetCart(tx, cart.Id, req.RestaurantId); err != nil {
+				return err
+			}
+			cart.Items = nil
+		}
+
+		quantity, inCart := cart.MenuItems()[req.MenuItem]
+		if !inCart && len(cart.Items) >= maxCartItems {
+			return errCartFull(maxCartItems)
+		}
+
+		quantity += req.Quantity
+		if quantity > maxCartItemQuantity {
+			return errInvalidArgument("Invalid quantity", fieldViolation("quantity", "cart can hold at most 99 of an item"))
+		}
+
+		return database.SetCartItemQuantity(tx, cart.Id, req.MenuItem, quantity)
+	})
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return cartServer.pricedCart(user)
+}
+
+// RemoveItem is idempotent: removing an item that is not in the cart succeeds.
+func (cartServer *CartServiceServer) RemoveItem(ctx context.Context, req *o.RemoveCartItemRequest) (*o.Cart, error) {
+	user, err := cartServer.Orders.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cart, err := database.GetCart(cartServer.Orders.DB, user.Username)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &o.Cart{}, nil
+	}
+
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	err = database.SetCartItemQuantity(cartServer.Orders.DB, cart.Id, req.MenuItem, 0)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return cartServer.pricedCart(user)
+}
+
+func (cartServer *CartServiceServer) UpdateQuantity(ctx context.Context, req *o.UpdateCartItemQuantityRequest) (*o.Cart, error) {
+	user, err := cartServer.Orders.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cart, err := database.GetCart(cartServer.Orders.DB, user.Username)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errCartItemNotFound(req.MenuItem)
+	}
+
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	if _, inCart := cart.MenuItems()[req.MenuItem]; !inCart {
+		return nil, errCartItemNotFound(req.MenuItem)
+	}
+
+	err = database.SetCartItemQuantity(cartServer.Orders.DB, cart.Id, req.MenuItem, req.Quantity)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return cartServer.pricedCart(user)
+}
+
+func (cartServer *CartServiceServer) GetCart(ctx context.Context, _ *o.GetCartRequest) (*o.Cart, error) {
+	user, err := cartServer.Orders.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return cartServer.pricedCart(user)
+}
+
+func (cartServer *CartServiceServer) ClearCart(ctx context.Context, _ *o.ClearCartRequest) (*o.Cart, error) {
+	user, err := cartServer.Orders.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cartServer.Orders.DB.Transaction(func(tx *gorm.DB) error {
+		return database.DeleteCart(tx, user.Username)
+	})
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &o.Cart{}, nil
+}
+
+// Checkout places the cart as an order through the same path as Create. The
+// cart is deleted in the order's transaction, after checking that it wasn't
+// changed while the order was being priced.
+func (cartServer *CartServiceServer) Checkout(ctx context.Context, req *o.CheckoutRequest) (*o.CreateOrderResponse, error) {
+	user, err := cartServer.Orders.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cart, err := database.GetCart(cartServer.Orders.DB, user.Username)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errCartEmpty()
+	}
+
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	menuItems := cart.MenuItems()
+	if len(menuItems) == 0 {
+		return nil, errCartEmpty()
+	}
+
+	orderRequest := &o.CreateOrderRequest{
+		RestaurantId: cart.RestaurantId,
+		MenuItems:    menuItems,
+		Tip:          req.Tip,
+		PromoCode:    req.PromoCode,
+		QuoteToken:   req.QuoteToken,
+	}
+
+	return cartServer.Orders.placeOrder(user, orderRequest, func(tx *gorm.DB, _ *model.Order) error {
+		locked, err := database.LockCart(tx, user.Username, cart.RestaurantId)
+		if err != nil {
+			return err
+		}
+
+		if locked.RestaurantId != cart.RestaurantId || !maps.Equal(locked.MenuItems(), menuItems) {
+			return errCartChanged()
+		}
+
+		return database.DeleteCart(tx, user.Username)
+	})
+}
+
+// pricedCart reads the user's cart and prices it at the catalog's current
+// prices, without a tip or promotion. Items that can't be ordered right now
+// are flagged and left out of the prices; Checkout is what refuses them.
+func (cartServer *CartServiceServer) pricedCart(user *model.User) (*o.Cart, error) {
+	cart, err := database.GetCart(cartServer.Orders.DB, user.Username)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &o.Cart{}, nil
+	}
+
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	menuItems := cart.MenuItems()
+	if len(menuItems) == 0 {
+		return &o.Cart{}, nil
+	}
+
+	response := &o.Cart{RestaurantId: cart.RestaurantId, MenuItems: menuItems}
+
+	// The catalog reports a missing item on its own, so pricing is retried
+	// without each item it turns down.
+	available := maps.Clone(menuItems)
+	for len(available) > 0 {
+		priced, err := cartServer.Orders.priceOrder(user, &o.CreateOrderRequest{RestaurantId: cart.RestaurantId, MenuItems: available})
+		unavailable := unavailableCartItems(err, available)
+		if unavailable == nil {
+			if err != nil {
+				return nil, toStatusError(err)
+			}
+
+			response.LineItems = toLineItems(priced.items, priced.breakdown.Total.Currency)
+			response.Breakdown = toProtoBreakdown(priced.breakdown)
+			response.Total = toProtoMoney(priced.breakdown.Total)
+			break
+		}
+
+		for _, item := range unavailable {
+			delete(available, item.MenuItem)
+		}
+		response.UnavailableItems = append(response.UnavailableItems, unavailable...)
+	}
+
+	return response, nil
+}
+
+// unavailableCartItems are the items a pricing error turns down as not
+// orderable, nil if the error is about something else.
+func unavailableCartItems(err error, menuItems map[string]int32) []*o.UnavailableCartItem {
+	var domainErr *DomainError
+	if !errors.As(err, &domainErr) {
+		return nil
+	}
+
+	switch domainErr.Reason {
+	case ReasonMenuItemNotFound:
+		menuItem := domainErr.Metadata["menu_item"]
+		if _, inCart := menuItems[menuItem]; !inCart {
+			return nil
+		}
+
+		return []*o.UnavailableCartItem{{MenuItem: menuItem, Quantity: menuItems[menuItem], Reason: ReasonMenuItemNotFound, Description: "no longer on the menu"}}
+	case ReasonItemsUnavailable:
+		var unavailable []*o.UnavailableCartItem
+		for _, violation := range domainErr.Preconditions {
+			unavailable = append(unavailable, &o.UnavailableCartItem{
+				MenuItem:    violation.Subject,
+				Quantity:    menuItems[violation.Subject],
+				Reason:      violation.Type,
+				Description: violation.Description,
+			})
+		}
+		return unavailable
+	}
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"orderService.com/go-orderService-grpc/pricing"
+	o "orderService.com/go-orderService-grpc/proto/order"
+)
+
+var (
+	cartColumns     = []string{"id", "username", "restaurant_id"}
+	cartItemColumns = []string{"id", "cart_id", "menu_item_name", "quantity"}
+)
+
+func expectCartLookup(mock sqlmock.Sqlmock, restaurantId string, items map[string]int32) {
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "carts" WHERE username = $1`)).WithArgs("username", 1).
+		WillReturnRows(sqlmock.NewRows(cartColumns).AddRow(5, "username", restaurantId))
+
+	rows := sqlmock.NewRows(cartItemColumns)
+	for name, quantity := range items {
+		rows.AddRow(len(name), 5, name, quantity)
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "cart_items" WHERE "cart_items"."cart_id" = $1 ORDER BY menu_item_name`)).WithArgs(5).
+		WillReturnRows(rows)
+}
+
+func TestGetCart_RepricesAgainstCatalog(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	catalog := newCatalogServer(t, "1", map[string]menuItemFixture{"Naan": {Price: "45", Currency: "INR"}})
+
+	expectUserLookup(t, mock, "username", "password")
+	expectCartLookup(mock, "1", map[string]int32{"Naan": 2})
+
+	cartServer := &CartServiceServer{Orders: &OrderServiceServer{
+		DB:                gormDb,
+		CatalogServiceAPI: catalog.URL + "/restaurants/",
+		Pricing:           pricing.NewEngine(pricing.Config{}),
+	}}
+
+	cart, err := cartServer.GetCart(basicAuthContext("username", "password"), &o.GetCartRequest{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "1", cart.RestaurantId)
+	assert.Equal(t, map[string]int32{"Naan": 2}, cart.MenuItems)
+	assert.Equal(t, &o.Money{CurrencyCode: "INR", AmountMinor: 4500}, cart.LineItems[0].UnitPrice)
+	assert.Equal(t, &o.Money{CurrencyCode: "INR", AmountMinor: 9000}, cart.Total)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestGetCart_UnavailableItems_PricesTheRest(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	catalog := newCatalogServer(t, "1", map[string]menuItemFixture{
+		"Naan":   {Price: "45", Currency: "INR"},
+		"Paneer": {Price: "200", Currency: "INR", Extra: `,"available":false`},
+	})
+
+	expectUserLookup(t, mock, "username", "password")
+	expectCartLookup(mock, "1", map[string]int32{"Naan": 2, "Paneer": 1, "Lassi": 3})
+
+	cartServer := &CartServiceServer{Orders: &OrderServiceServer{
+		DB:                gormDb,
+		CatalogServiceAPI: catalog.URL + "/restaurants/",
+		Pricing:           pricing.NewEngine(pricing.Config{}),
+	}}
+
+	cart, err := cartServer.GetCart(basicAuthContext("username", "password"), &o.GetCartRequest{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int32{"Naan": 2, "Paneer": 1, "Lassi": 3}, cart.MenuItems)
+	assert.Len(t, cart.LineItems, 1)
+	assert.Equal(t, &o.Money{CurrencyCode: "INR", AmountMinor: 9000}, cart.Total)
+	assert.Equal(t, []*o.UnavailableCartItem{
+		{MenuItem: "Lassi", Quantity: 3, Reason: ReasonMenuItemNotFound, Description: "no longer on the menu"},
+		{MenuItem: "Paneer", Quantity: 1, Reason: violationItemUnavailable, Description: "not available right now"},
+	}, cart.UnavailableItems)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestAddItem_OtherRestaurant_ReturnsFailedPrecondition(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	catalog := newCatalogServer(t, "2", map[string]menuItemFixture{"Dosa": {Price: "80", Currency: "INR"}})
+
+	expectUserLookup(t, mock, "username", "password")
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "carts" .* ON CONFLICT DO NOTHING`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "carts" WHERE username = $1`) + `.*FOR UPDATE`).
+		WillReturnRows(sqlmock.NewRows(cartColumns).AddRow(5, "username", "1"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "cart_items"`)).
+		WillReturnRows(sqlmock.NewRows(cartItemColumns).AddRow(1, 5, "Naan", 2))
+	mock.ExpectRollback()
+
+	cartServer := &CartServiceServer{Orders: &OrderServiceServer{DB: gormDb, CatalogServiceAPI: catalog.URL + "/restaurants/"}}
+
+	cart, err := cartServer.AddItem(basicAuthContext("username", "password"), &o.AddCartItemRequest{
+		RestaurantId: "2",
+		MenuItem:     "Dosa",
+		Quantity:     1,
+	})
+
+	assert.Nil(t, cart)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.Equal(t, ReasonCartRestaurantMismatch, errorInfoOf(t, err).Reason)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestCheckout_EmptyCart_ReturnsFailedPrecondition(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+
+	expectUserLookup(t, mock, "username", "password")
+	expectCartLookup(mock, "1", nil)
+
+	cartServer := &CartServiceServer{Orders: &OrderServiceServer{DB: gormDb}}
+
+	response, err := cartServer.Checkout(basicAuthContext("username", "password"), &o.CheckoutRequest{})
+
+	assert.Nil(t, response)
+	assert.Equal(t, ReasonCartEmpty, errorInfoOf(t, err).Reason)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestCheckout_PlacesOrderAndDeletesCartInOneTransaction(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	catalog := newCatalogServer(t, "1", map[string]menuItemFixture{"Naan": {Price: "40", Currency: "INR"}})
+	fulfillment := newFulfillmentServer(t, http.StatusCreated)
+
+	expectUserLookup(t, mock, "username", "password")
+	expectCartLookup(mock, "1", map[string]int32{"Naan": 2})
+	mock.ExpectBegin()
+	expectOrderInsert(mock, 7, nil)
+	mock.ExpectQuery(`INSERT INTO "order_items"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(`INSERT INTO "carts" .* ON CONFLICT DO NOTHING`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT \* FROM "carts" .*FOR UPDATE`).
+		WillReturnRows(sqlmock.NewRows(cartColumns).AddRow(5, "username", "1"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "cart_items"`)).
+		WillReturnRows(sqlmock.NewRows(cartItemColumns).AddRow(1, 5, "Naan", 2))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "cart_items" WHERE cart_id IN (SELECT "id" FROM "carts" WHERE username = $1)`)).
+		WithArgs("username").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "carts" WHERE username = $1`)).
+		WithArgs("username").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	cartServer := &CartServiceServer{Orders: &OrderServiceServer{
+		DB:                    gormDb,
+		CatalogServiceAPI:     catalog.URL + "/restaurants/",
+		FulfillmentServiceAPI: fulfillment.URL,
+		Pricing:               pricing.NewEngine(pricing.Config{}),
+	}}
+
+	response, err := cartServer.Checkout(basicAuthContext("username", "password"), &o.CheckoutRequest{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(7), response.Id)
+	assert.Equal(t, map[string]int32{"Naan": 2}, response.MenuItems)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+	database "orderService.com/go-orderService-grpc/db"
+	"orderService.com/go-orderService-grpc/model"
+	o "orderService.com/go-orderService-grpc/proto/order"
+)
+
+// chatBatchSize is how many chat messages OrderChat reads at a time.
+const chatBatchSize = 100
+
+var chatRoles = map[model.ChatRole]o.ChatRole{
+	model.ChatCustomer: o.ChatRole_CHAT_CUSTOMER,
+	model.ChatCourier:  o.ChatRole_CHAT_COURIER,
+	model.ChatSupport:  o.ChatRole_CHAT_SUPPORT,
+}
+
+// OrderChat receives the caller's messages in a goroutine of its own, while
+// this one sends the chat's messages, as told by Chats, and closes the chat
+// once Updates tells the order became final.
+func (orderServer *OrderServiceServer) OrderChat(stream o.OrderService_OrderChatServer) error {
+	ctx := stream.Context()
+
+	user, err := orderServer.authenticate(ctx)
+	if err != nil {
+		return err
+	}
+
+	if orderServer.Updates == nil || orderServer.Chats == nil {
+		return errInternal("order updates are not configured")
+	}
+
+	join, err := stream.Recv()
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	orderId := join.OrderId
+	if orderId == 0 {
+		return errInvalidArgument("Invalid request", fieldViolation("order_id", "must be set in the first request"))
+	}
+
+	// Subscribing before the first read makes sure no change is missed
+	// between the two.
+	messages := orderServer.Chats.Subscribe(orderId)
+	defer messages.Close()
+	updates := orderServer.Updates.Subscribe(orderId)
+	defer updates.Close()
+
+	order, role, err := orderServer.chatParticipant(user, orderId)
+	if err != nil {
+		return err
+	}
+
+	if order.Final() {
+		return errOrderChatClosed(orderId)
+	}
+
+	if join.Body != "" {
+		if strings.TrimSpace(join.Body) == "" {
+			return errInvalidArgument("Invalid request", fieldViolation("body", "must not be empty"))
+		}
+
+		if err := orderServer.postChatMessage(orderId, user, role, join.Body); err != nil {
+			return err
+		}
+	}
+
+	// The receiving goroutine only gets orderId: order is replaced below.
+	received := make(chan error, 1)
+	go func() { received <- orderServer.receiveChatMessages(stream, orderId, user, role) }()
+
+	var cursor int64
+	for {
+		for {
+			batch, err := database.ListChatMessages(orderServer.DB, orderId, cursor, chatBatchSize)
+			if err != nil {
+				return toStatusError(err)
+			}
+
+			for i := range batch {
+				if err := stream.Send(&o.OrderChatEvent{Message: toProtoChatMessage(&batch[i])}); err != nil {
+					return err
+				}
+				cursor = batch[i].Id
+			}
+
+			if len(batch) < chatBatchSize {
+				break
+			}
+		}
+
+		if order.Final() {
+			return stream.Send(&o.OrderChatEvent{Closed: true})
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-received:
+			return err
+		case <-messages.C:
+		case <-updates.C:
+			order, err = database.GetOrder(orderServer.DB, orderId)
+			if err != nil {
+				return toStatusError(err)
+			}
+		}
+	}
+}
+
+// receiveChatMessages stores the messages the caller sends until the caller
+// stops sending.
+func (orderServer *OrderServiceServer) receiveChatMessages(stream o.OrderService_OrderChatServer, orderId int64, user *model.User, role model.ChatRole) error {
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case req.OrderId != 0 && req.OrderId != orderId:
+			return errInvalidArgument("Invalid request", fieldViolation("order_id", "must be the order of the chat"))
+		case strings.TrimSpace(req.Body) == "":
+			return errInvalidArgument("Invalid request", fieldViolation("body", "must not be empty"))
+		}
+
+		if err := orderServer.postChatMessage(orderId, user, role, req.Body); err != nil {
+			return err
+		}
+	}
+}
+
+// postChatMessage stores a message unless the order has become final. The
+// order stays locked while the message is stored, so that the chat can't be
+// closed in between.
+func (orderServer *OrderServiceServer) postChatMessage(orderId int64, user *model.User, role model.ChatRole, body string) error {
+	message := model.ChatMessage{
+		OrderId:        orderId,
+		SenderUsername: user.Username,
+		SenderRole:     role,
+		Body:           body,
+		SentAt:         time.Now(),
+	}
+
+	err := orderServer.DB.Transaction(func(tx *gorm.DB) error {
+		order, err := database.LockOrder(tx, orderId)
+		if err != nil {
+			return err
+		}
+
+		if order.Final() {
+			return errOrderChatClosed(orderId)
+		}
+
+		return tx.Create(&message).Error
+	})
+	if err != nil {
+		return toStatusError(err)
+	}
+
+	orderServer.Chats.Publish(orderId)
+	return nil
+}
+
+// chatParticipant tells who the user is in the order's chat. Users who may
+// not join it get errOrderNotFound, as for GetOrder.
+func (orderServer *OrderServiceServer) chatParticipant(user *model.User, orderId int64) (*model.Order, model.ChatRole, error) {
+	order, err := database.GetOrder(orderServer.DB, orderId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, "", errOrderNotFound(orderId)
+	}
+
+	if err != nil {
+		return nil, "", toStatusError(err)
+	}
+
+	switch {
+	case order.Username == user.Username:
+		return order, model.ChatCustomer, nil
+	case order.CourierUsername != "" && order.CourierUsername == user.Username:
+		return order, model.ChatCourier, nil
+	}
+
+	support, err := database.IsSupportAgent(orderServer.DB, user.Username)
+	if err != nil {
+		return nil, "", toStatusError(err)
+	}
+
+	if !support {
+		return nil, "", errOrderNotFound(orderId)
+	}
+
+	return order, model.ChatSupport, nil
+}
+
+func toProtoChatMessage(message *model.ChatMessage) *o.ChatMessage {
+	return &o.ChatMessage{
+		Id:             message.Id,
+		SenderUsername: message.SenderUsername,
+		SenderRole:     chatRoles[message.SenderRole],
+		Body:           message.Body,
+		SentAt:         timestamppb.New(message.SentAt),
+	}
+}
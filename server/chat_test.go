@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"io"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	o "orderService.com/go-orderService-grpc/proto/order"
+	"orderService.com/go-orderService-grpc/pubsub"
+)
+
+// chatStream feeds OrderChat the requests sent on requests, until it is
+// closed, and collects what it sends.
+type chatStream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	requests chan *o.OrderChatRequest
+	events   chan *o.OrderChatEvent
+}
+
+func newChatStream(ctx context.Context) *chatStream {
+	return &chatStream{ctx: ctx, requests: make(chan *o.OrderChatRequest, 10), events: make(chan *o.OrderChatEvent, 10)}
+}
+
+func (stream *chatStream) Context() context.Context {
+	return stream.ctx
+}
+
+func (stream *chatStream) Send(event *o.OrderChatEvent) error {
+	stream.events <- event
+	return nil
+}
+
+func (stream *chatStream) Recv() (*o.OrderChatRequest, error) {
+	select {
+	case req, ok := <-stream.requests:
+		if !ok {
+			return nil, io.EOF
+		}
+		return req, nil
+	case <-stream.ctx.Done():
+		return nil, stream.ctx.Err()
+	}
+}
+
+func nextChatEvent(t *testing.T, stream *chatStream) *o.OrderChatEvent {
+	select {
+	case event := <-stream.events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("Expected a chat event")
+		return nil
+	}
+}
+
+var chatMessageColumns = []string{"id", "order_id", "sender_username", "sender_role", "body", "sent_at"}
+
+func expectChatMessages(mock sqlmock.Sqlmock, after int64, rows *sqlmock.Rows) {
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "chat_messages" WHERE order_id = $1 AND id > $2 ORDER BY id LIMIT $3`)).
+		WithArgs(7, after, chatBatchSize).WillReturnRows(rows)
+}
+
+func expectChatOrderLock(mock sqlmock.Sqlmock, status string) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "orders" WHERE id = $1`)+`.*FOR UPDATE`).WithArgs(7, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "status"}).AddRow(7, "username", status))
+}
+
+func TestOrderChat_SendsHistoryAndEchoesNewMessages(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	orderServiceServer := &OrderServiceServer{DB: gormDb, Updates: pubsub.NewHub[int64](), Chats: pubsub.NewHub[int64]()}
+	stream := newChatStream(basicAuthContext("username", "password"))
+	sentAt := time.Date(2024, 3, 10, 20, 5, 0, 0, time.UTC)
+
+	expectUserLookup(t, mock, "username", "password")
+	expectOrderLookup(mock, "username", "COURIER_ASSIGNED", "Ravi")
+	expectChatMessages(mock, 0, sqlmock.NewRows(chatMessageColumns).AddRow(1, 7, "ravi", "COURIER", "At the gate", sentAt))
+	expectChatOrderLock(mock, "COURIER_ASSIGNED")
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "chat_messages" ("order_id","sender_username","sender_role","body","sent_at") VALUES ($1,$2,$3,$4,$5) RETURNING "id"`)).
+		WithArgs(7, "username", "CUSTOMER", "Code is 1234", sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectCommit()
+	expectChatMessages(mock, 1, sqlmock.NewRows(chatMessageColumns).AddRow(2, 7, "username", "CUSTOMER", "Code is 1234", sentAt))
+
+	done := make(chan error)
+	go func() { done <- orderServiceServer.OrderChat(stream) }()
+
+	stream.requests <- &o.OrderChatRequest{OrderId: 7}
+	history := nextChatEvent(t, stream)
+	assert.Equal(t, "At the gate", history.Message.Body)
+	assert.Equal(t, o.ChatRole_CHAT_COURIER, history.Message.SenderRole)
+
+	stream.requests <- &o.OrderChatRequest{Body: "Code is 1234"}
+	echo := nextChatEvent(t, stream)
+	assert.Equal(t, int64(2), echo.Message.Id)
+	assert.Equal(t, o.ChatRole_CHAT_CUSTOMER, echo.Message.SenderRole)
+
+	close(stream.requests)
+	assert.Nil(t, <-done)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestOrderChat_ClosesWhenOrderIsFinal(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	updates := pubsub.NewHub[int64]()
+	orderServiceServer := &OrderServiceServer{DB: gormDb, Updates: updates, Chats: pubsub.NewHub[int64]()}
+	stream := newChatStream(basicAuthContext("username", "password"))
+
+	expectUserLookup(t, mock, "username", "password")
+	expectOrderLookup(mock, "username", "PICKED_UP", "Ravi")
+	expectChatMessages(mock, 0, sqlmock.NewRows(chatMessageColumns))
+	expectOrderLookup(mock, "username", "DELIVERED", "Ravi")
+	expectChatMessages(mock, 0, sqlmock.NewRows(chatMessageColumns))
+
+	done := make(chan error)
+	go func() { done <- orderServiceServer.OrderChat(stream) }()
+
+	stream.requests <- &o.OrderChatRequest{OrderId: 7}
+	assert.Eventually(t, func() bool { return updates.Subscribers(7) == 1 }, time.Second, time.Millisecond)
+	updates.Publish(7)
+
+	assert.True(t, nextChatEvent(t, stream).Closed)
+	assert.Nil(t, <-done)
+	assert.Equal(t, 0, updates.Subscribers(7))
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestOrderChat_MessageAfterOrderBecameFinal_ReturnsChatClosed(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	orderServiceServer := &OrderServiceServer{DB: gormDb, Updates: pubsub.NewHub[int64](), Chats: pubsub.NewHub[int64]()}
+	stream := newChatStream(basicAuthContext("username", "password"))
+
+	expectUserLookup(t, mock, "username", "password")
+	expectOrderLookup(mock, "username", "PICKED_UP", "Ravi")
+	expectChatMessages(mock, 0, sqlmock.NewRows(chatMessageColumns))
+	// Delivered before this goroutine heard of it.
+	expectChatOrderLock(mock, "DELIVERED")
+	mock.ExpectRollback()
+	// The message may be received before the history is read.
+	mock.MatchExpectationsInOrder(false)
+
+	done := make(chan error)
+	go func() { done <- orderServiceServer.OrderChat(stream) }()
+
+	stream.requests <- &o.OrderChatRequest{OrderId: 7}
+	stream.requests <- &o.OrderChatRequest{Body: "Where are you?"}
+
+	assert.Equal(t, codes.FailedPrecondition, status.Code(<-done))
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestOrderChat_BlankFirstMessage_ReturnsInvalidArgument(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	orderServiceServer := &OrderServiceServer{DB: gormDb, Updates: pubsub.NewHub[int64](), Chats: pubsub.NewHub[int64]()}
+	stream := newChatStream(basicAuthContext("username", "password"))
+
+	expectUserLookup(t, mock, "username", "password")
+	expectOrderLookup(mock, "username", "PICKED_UP", "Ravi")
+
+	stream.requests <- &o.OrderChatRequest{OrderId: 7, Body: "  \n"}
+	err := orderServiceServer.OrderChat(stream)
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestOrderChat_Stranger_ReturnsNotFound(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	orderServiceServer := &OrderServiceServer{DB: gormDb, Updates: pubsub.NewHub[int64](), Chats: pubsub.NewHub[int64]()}
+	stream := newChatStream(basicAuthContext("username", "password"))
+
+	expectUserLookup(t, mock, "username", "password")
+	expectOrderLookup(mock, "someone else", "PICKED_UP", "Ravi")
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "support_agents" WHERE username = $1`)).WithArgs("username").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	stream.requests <- &o.OrderChatRequest{OrderId: 7}
+	err := orderServiceServer.OrderChat(stream)
+
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Empty(t, stream.events)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestOrderChat_FirstRequestWithoutOrder_ReturnsInvalidArgument(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	orderServiceServer := &OrderServiceServer{DB: gormDb, Updates: pubsub.NewHub[int64](), Chats: pubsub.NewHub[int64]()}
+	stream := newChatStream(basicAuthContext("username", "password"))
+
+	expectUserLookup(t, mock, "username", "password")
+
+	stream.requests <- &o.OrderChatRequest{Body: "Hello"}
+	err := orderServiceServer.OrderChat(stream)
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
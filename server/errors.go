@@ -0,0 +1,485 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"gorm.io/gorm"
+	"orderService.com/go-orderService-grpc/hours"
+	"orderService.com/go-orderService-grpc/limits"
+	"orderService.com/go-orderService-grpc/model"
+	"orderService.com/go-orderService-grpc/money"
+	"orderService.com/go-orderService-grpc/promotions"
+)
+
+const (
+	errorDomain   = "orderservice.com"
+	errorLocale   = "en-US"
+	defaultRetry  = 2 * time.Second
+	pgUniqueError = "23505"
+	pgForeignKey  = "23503"
+)
+
+// Reasons are stable, machine readable identifiers sent in google.rpc.ErrorInfo.
+const (
+	ReasonMissingCredentials         = "MISSING_CREDENTIALS"
+	ReasonInvalidCredentials         = "INVALID_CREDENTIALS"
+	ReasonInvalidArgument            = "INVALID_ARGUMENT"
+	ReasonUserNotFound               = "USER_NOT_FOUND"
+	ReasonUsernameTaken              = "USERNAME_TAKEN"
+	ReasonRestaurantNotFound         = "RESTAURANT_NOT_FOUND"
+	ReasonMenuItemNotFound           = "MENU_ITEM_NOT_FOUND"
+	ReasonItemsUnavailable           = "ITEMS_UNAVAILABLE"
+	ReasonRestaurantClosed           = "RESTAURANT_CLOSED"
+	ReasonMixedCurrencies            = "MIXED_CURRENCIES"
+	ReasonOutsideDeliveryZone        = "OUTSIDE_DELIVERY_ZONE"
+	ReasonBelowMinimumOrder          = "BELOW_MINIMUM_ORDER"
+	ReasonOrderLimitExceeded         = "ORDER_LIMIT_EXCEEDED"
+	ReasonInsufficientLoyaltyPoints  = "INSUFFICIENT_LOYALTY_POINTS"
+	ReasonInsufficientWalletBalance  = "INSUFFICIENT_WALLET_BALANCE"
+	ReasonIdempotencyKeyReused       = "IDEMPOTENCY_KEY_REUSED"
+	ReasonRefundExceedsOrder         = "REFUND_EXCEEDS_ORDER"
+	ReasonPromoCodeNotFound          = "PROMO_CODE_NOT_FOUND"
+	ReasonPromotionNotApplicable     = "PROMOTION_NOT_APPLICABLE"
+	ReasonPromotionLimitReached      = "PROMOTION_LIMIT_REACHED"
+	ReasonQuoteInvalid               = "QUOTE_INVALID"
+	ReasonQuoteExpired               = "QUOTE_EXPIRED"
+	ReasonQuoteMismatch              = "QUOTE_MISMATCH"
+	ReasonCartEmpty                  = "CART_EMPTY"
+	ReasonCartFull                   = "CART_FULL"
+	ReasonCartChanged                = "CART_CHANGED"
+	ReasonCartItemNotFound           = "CART_ITEM_NOT_FOUND"
+	ReasonCartRestaurantMismatch     = "CART_RESTAURANT_MISMATCH"
+	ReasonGroupOrderNotFound         = "GROUP_ORDER_NOT_FOUND"
+	ReasonGroupOrderClosed           = "GROUP_ORDER_CLOSED"
+	ReasonGroupOrderNotLocked        = "GROUP_ORDER_NOT_LOCKED"
+	ReasonGroupOrderEmpty            = "GROUP_ORDER_EMPTY"
+	ReasonNotGroupOrderHost          = "NOT_GROUP_ORDER_HOST"
+	ReasonOrderNotFound              = "ORDER_NOT_FOUND"
+	ReasonOrderNotAwaitingAcceptance = "ORDER_NOT_AWAITING_ACCEPTANCE"
+	ReasonNotRestaurantOwner         = "NOT_RESTAURANT_OWNER"
+	ReasonNotSupportAgent            = "NOT_SUPPORT_AGENT"
+	ReasonOrderChatClosed            = "ORDER_CHAT_CLOSED"
+	ReasonOrderAlreadyAssigned       = "ORDER_ALREADY_ASSIGNED"
+	ReasonNoDeliveryExecutiveNearby  = "NO_DELIVERY_EXECUTIVE_NEARBY"
+	ReasonUpstreamUnavailable        = "UPSTREAM_UNAVAILABLE"
+	ReasonUpstreamError              = "UPSTREAM_ERROR"
+	ReasonNotFound                   = "NOT_FOUND"
+	ReasonAlreadyExists              = "ALREADY_EXISTS"
+	ReasonFailedPrecondition         = "FAILED_PRECONDITION"
+	ReasonDeadlineExceeded           = "DEADLINE_EXCEEDED"
+	ReasonCanceled                   = "CANCELED"
+	ReasonInternal                   = "INTERNAL"
+)
+
+// DomainError is an error from the service's error catalogue. It knows how to
+// render itself as a gRPC status carrying ErrorInfo, LocalizedMessage and,
+// where relevant, BadRequest, PreconditionFailure and RetryInfo details.
+type DomainError struct {
+	Code       codes.Code
+	Reason     string
+	Message    string
+	Metadata   map[string]string
+	Violations []*errdetails.BadRequest_FieldViolation
+	// Preconditions are sent as PreconditionFailure, one per thing that
+	// failed the check.
+	Preconditions []*errdetails.PreconditionFailure_Violation
+	RetryAfter    time.Duration
+}
+
+func (e *DomainError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Reason, e.Message)
+}
+
+func (e *DomainError) GRPCStatus() *status.Status {
+	st := status.New(e.Code, e.Message)
+
+	details := []protoadapt.MessageV1{
+		&errdetails.ErrorInfo{Reason: e.Reason, Domain: errorDomain, Metadata: e.Metadata},
+		&errdetails.LocalizedMessage{Locale: errorLocale, Message: e.Message},
+	}
+
+	if len(e.Violations) > 0 {
+		details = append(details, &errdetails.BadRequest{FieldViolations: e.Violations})
+	}
+
+	if len(e.Preconditions) > 0 {
+		details = append(details, &errdetails.PreconditionFailure{Violations: e.Preconditions})
+	}
+
+	if e.RetryAfter > 0 {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(e.RetryAfter)})
+	}
+
+	withDetails, err := st.WithDetails(details...)
+	if err != nil {
+		return st
+	}
+
+	return withDetails
+}
+
+func newDomainError(code codes.Code, reason string, message string, metadata map[string]string) *DomainError {
+	return &DomainError{Code: code, Reason: reason, Message: message, Metadata: metadata}
+}
+
+func fieldViolation(field string, description string) *errdetails.BadRequest_FieldViolation {
+	return &errdetails.BadRequest_FieldViolation{Field: field, Description: description}
+}
+
+func errInvalidArgument(message string, violations ...*errdetails.BadRequest_FieldViolation) *DomainError {
+	err := newDomainError(codes.InvalidArgument, ReasonInvalidArgument, message, nil)
+	err.Violations = violations
+	return err
+}
+
+func errMissingCredentials() *DomainError {
+	return newDomainError(codes.Unauthenticated, ReasonMissingCredentials, "Credentials not found", nil)
+}
+
+func errInvalidCredentials() *DomainError {
+	return newDomainError(codes.Unauthenticated, ReasonInvalidCredentials, "Unauthorized: Invalid username or password", nil)
+}
+
+func errUserNotFound(username string) *DomainError {
+	return newDomainError(codes.NotFound, ReasonUserNotFound, "user not found", map[string]string{"username": username})
+}
+
+func errUsernameTaken(username string) *DomainError {
+	return newDomainError(codes.AlreadyExists, ReasonUsernameTaken, "username is already taken", map[string]string{"username": username})
+}
+
+func errRestaurantNotFound(restaurantId string, detail string) *DomainError {
+	log.Printf("%s could not find restaurant %s: %s", catalogService, restaurantId, detail)
+	return newDomainError(codes.NotFound, ReasonRestaurantNotFound, "restaurant not found", map[string]string{"restaurant_id": restaurantId})
+}
+
+func errMenuItemNotFound(restaurantId string, menuItem string) *DomainError {
+	return newDomainError(codes.NotFound, ReasonMenuItemNotFound, "menu item not found", map[string]string{"restaurant_id": restaurantId, "menu_item": menuItem})
+}
+
+// Types of the PreconditionFailure violations of errItemsUnavailable.
+const (
+	violationItemUnavailable = "ITEM_UNAVAILABLE"
+	violationOutOfStock      = "OUT_OF_STOCK"
+)
+
+func itemViolation(kind string, menuItem string, description string) *errdetails.PreconditionFailure_Violation {
+	return &errdetails.PreconditionFailure_Violation{Type: kind, Subject: menuItem, Description: description}
+}
+
+func errItemsUnavailable(restaurantId string, violations []*errdetails.PreconditionFailure_Violation) *DomainError {
+	menuItems := make([]string, len(violations))
+	for i, violation := range violations {
+		menuItems[i] = violation.Subject
+	}
+
+	err := newDomainError(codes.FailedPrecondition, ReasonItemsUnavailable, "some items are not available", map[string]string{"restaurant_id": restaurantId, "menu_items": strings.Join(menuItems, ",")})
+	err.Preconditions = violations
+	return err
+}
+
+// errRestaurantClosed tells when the restaurant opens next, if it does within
+// a week.
+func errRestaurantClosed(restaurantId string, at time.Time, openingHours *hours.Hours) *DomainError {
+	metadata := map[string]string{"restaurant_id": restaurantId, "at": at.UTC().Format(time.RFC3339)}
+	if opensAt, ok := openingHours.NextOpening(at); ok {
+		metadata["opens_at"] = opensAt.UTC().Format(time.RFC3339)
+	}
+
+	return newDomainError(codes.FailedPrecondition, ReasonRestaurantClosed, "the restaurant is closed at that time", metadata)
+}
+
+func errMixedCurrencies(restaurantId string, currencies ...string) *DomainError {
+	return newDomainError(codes.FailedPrecondition, ReasonMixedCurrencies, "all items of an order must be priced in the same currency", map[string]string{"restaurant_id": restaurantId, "currencies": strings.Join(currencies, ",")})
+}
+
+// errOutsideDeliveryZone is also returned if the drop address can't be
+// located, as zones.ErrUnknownLocation tells.
+func errOutsideDeliveryZone(restaurantId string, zipcode string, cause error) *DomainError {
+	return newDomainError(codes.FailedPrecondition, ReasonOutsideDeliveryZone, cause.Error(), map[string]string{"restaurant_id": restaurantId, "zipcode": zipcode})
+}
+
+// errOrderLimits is BELOW_MINIMUM_ORDER when the subtotal falls short of the
+// restaurant's minimum, with amount_to_add telling by how much, and
+// ORDER_LIMIT_EXCEEDED when only maximums are broken.
+func errOrderLimits(restaurantId string, currency string, broken []limits.Violation) *DomainError {
+	reason := ReasonOrderLimitExceeded
+	message := "the order exceeds the restaurant's limits"
+	metadata := map[string]string{"restaurant_id": restaurantId}
+	violations := make([]*errdetails.PreconditionFailure_Violation, len(broken))
+
+	for i, violation := range broken {
+		violations[i] = limitViolation(violation, currency)
+
+		if violation.Kind == limits.MinSubtotal {
+			reason = ReasonBelowMinimumOrder
+			message = violations[i].Description
+			metadata["min_subtotal"] = money.New(violation.Limit, currency).String()
+			metadata["amount_to_add"] = money.New(violation.Short, currency).String()
+		}
+	}
+
+	err := newDomainError(codes.FailedPrecondition, reason, message, metadata)
+	err.Preconditions = violations
+	return err
+}
+
+func errInsufficientLoyaltyPoints(requested int64, available int64) *DomainError {
+	return newDomainError(codes.FailedPrecondition, ReasonInsufficientLoyaltyPoints, "not enough loyalty points", map[string]string{
+		"requested": strconv.FormatInt(requested, 10),
+		"available": strconv.FormatInt(available, 10),
+	})
+}
+
+func errInsufficientWalletBalance(requested money.Money, available money.Money) *DomainError {
+	return newDomainError(codes.FailedPrecondition, ReasonInsufficientWalletBalance, "not enough store credit", map[string]string{
+		"requested": requested.String(),
+		"available": available.String(),
+	})
+}
+
+func errIdempotencyKeyReused(key string) *DomainError {
+	return newDomainError(codes.AlreadyExists, ReasonIdempotencyKeyReused, "idempotency key was already used for another request", map[string]string{"idempotency_key": key})
+}
+
+// errRefundExceedsOrder tells how much of the order's total is left to
+// refund.
+func errRefundExceedsOrder(orderId int64, requested money.Money, refundable money.Money) *DomainError {
+	return newDomainError(codes.FailedPrecondition, ReasonRefundExceedsOrder, "refund exceeds what is left of the order total", map[string]string{
+		"order_id":   strconv.FormatInt(orderId, 10),
+		"requested":  requested.String(),
+		"refundable": refundable.String(),
+	})
+}
+
+func errPromoCodeNotFound(code string) *DomainError {
+	return newDomainError(codes.NotFound, ReasonPromoCodeNotFound, "promo code not found", map[string]string{"promo_code": code})
+}
+
+func errPromotionNotApplicable(code string, cause error) *DomainError {
+	reason := ReasonPromotionNotApplicable
+	if errors.Is(cause, promotions.ErrUsageLimitReached) || errors.Is(cause, promotions.ErrUserLimitReached) {
+		reason = ReasonPromotionLimitReached
+	}
+
+	return newDomainError(codes.FailedPrecondition, reason, cause.Error(), map[string]string{"promo_code": code})
+}
+
+func errQuoteInvalid(detail string) *DomainError {
+	err := newDomainError(codes.InvalidArgument, ReasonQuoteInvalid, "quote token is invalid", map[string]string{"detail": detail})
+	err.Violations = append(err.Violations, fieldViolation("quote_token", detail))
+	return err
+}
+
+func errQuoteExpired() *DomainError {
+	return newDomainError(codes.FailedPrecondition, ReasonQuoteExpired, "quote has expired, request a new one", nil)
+}
+
+func errQuoteMismatch(field string, detail string) *DomainError {
+	err := newDomainError(codes.InvalidArgument, ReasonQuoteMismatch, "order does not match the quote", map[string]string{"field": field})
+	err.Violations = append(err.Violations, fieldViolation(field, detail))
+	return err
+}
+
+func errCartEmpty() *DomainError {
+	return newDomainError(codes.FailedPrecondition, ReasonCartEmpty, "cart is empty", nil)
+}
+
+func errCartFull(limit int) *DomainError {
+	return newDomainError(codes.FailedPrecondition, ReasonCartFull, fmt.Sprintf("cart can't hold more than %d different items", limit), map[string]string{"limit": strconv.Itoa(limit)})
+}
+
+func errCartChanged() *DomainError {
+	return newDomainError(codes.Aborted, ReasonCartChanged, "cart changed during checkout, review it and try again", nil)
+}
+
+func errCartItemNotFound(menuItem string) *DomainError {
+	return newDomainError(codes.NotFound, ReasonCartItemNotFound, fmt.Sprintf("%s is not in the cart", menuItem), map[string]string{"menu_item": menuItem})
+}
+
+func errCartRestaurantMismatch(cartRestaurantId string, restaurantId string) *DomainError {
+	return newDomainError(codes.FailedPrecondition, ReasonCartRestaurantMismatch, "cart holds items of another restaurant, clear it or set replace_cart",
+		map[string]string{"cart_restaurant_id": cartRestaurantId, "restaurant_id": restaurantId})
+}
+
+func errGroupOrderNotFound(inviteCode string) *DomainError {
+	return newDomainError(codes.NotFound, ReasonGroupOrderNotFound, "group order not found", map[string]string{"invite_code": inviteCode})
+}
+
+func errGroupOrderClosed(inviteCode string, status model.GroupOrderStatus) *DomainError {
+	return newDomainError(codes.FailedPrecondition, ReasonGroupOrderClosed, fmt.Sprintf("group order is %s and can't be changed", strings.ToLower(string(status))),
+		map[string]string{"invite_code": inviteCode, "status": string(status)})
+}
+
+func errGroupOrderNotLocked(inviteCode string) *DomainError {
+	return newDomainError(codes.FailedPrecondition, ReasonGroupOrderNotLocked, "group order must be locked before it is submitted", map[string]string{"invite_code": inviteCode})
+}
+
+func errGroupOrderEmpty(inviteCode string) *DomainError {
+	return newDomainError(codes.FailedPrecondition, ReasonGroupOrderEmpty, "group order has no items", map[string]string{"invite_code": inviteCode})
+}
+
+func errNotGroupOrderHost(inviteCode string) *DomainError {
+	return newDomainError(codes.PermissionDenied, ReasonNotGroupOrderHost, "only the host can do this", map[string]string{"invite_code": inviteCode})
+}
+
+// errOrderNotFound is also returned for orders of other users, so that order
+// ids can't be probed.
+func errOrderNotFound(orderId int64) *DomainError {
+	return newDomainError(codes.NotFound, ReasonOrderNotFound, fmt.Sprintf("order %d not found", orderId), map[string]string{"order_id": strconv.FormatInt(orderId, 10)})
+}
+
+func errOrderNotAwaitingAcceptance(orderId int64, status model.OrderStatus) *DomainError {
+	return newDomainError(codes.FailedPrecondition, ReasonOrderNotAwaitingAcceptance, fmt.Sprintf("order is %s and can't be accepted or rejected", strings.ToLower(string(status))),
+		map[string]string{"order_id": strconv.FormatInt(orderId, 10), "status": string(status)})
+}
+
+func errOrderChatClosed(orderId int64) *DomainError {
+	return newDomainError(codes.FailedPrecondition, ReasonOrderChatClosed, "the order is final and its chat is closed", map[string]string{"order_id": strconv.FormatInt(orderId, 10)})
+}
+
+func errNotRestaurantOwner(restaurantId string) *DomainError {
+	return newDomainError(codes.PermissionDenied, ReasonNotRestaurantOwner, "only the restaurant's owners can do this", map[string]string{"restaurant_id": restaurantId})
+}
+
+func errNotSupportAgent() *DomainError {
+	return newDomainError(codes.PermissionDenied, ReasonNotSupportAgent, "only support agents can do this", nil)
+}
+
+func errOrderAlreadyAssigned(orderId int64, detail string) *DomainError {
+	log.Printf("%s has already assigned order %d: %s", fulfillmentService, orderId, detail)
+	err := newDomainError(codes.Aborted, ReasonOrderAlreadyAssigned, "order is already assigned to a delivery executive", map[string]string{"order_id": strconv.FormatInt(orderId, 10)})
+	err.RetryAfter = defaultRetry
+	return err
+}
+
+func errNoDeliveryExecutiveNearby(orderId int64, detail string) *DomainError {
+	log.Printf("%s has no delivery executive for order %d: %s", fulfillmentService, orderId, detail)
+	err := newDomainError(codes.Unavailable, ReasonNoDeliveryExecutiveNearby, "no delivery executive is available nearby", map[string]string{"order_id": strconv.FormatInt(orderId, 10)})
+	err.RetryAfter = 30 * time.Second
+	return err
+}
+
+// errUpstreamUnavailable and errUpstreamStatus log what the service said, which
+// may name internal hosts, and tell the client only which service failed.
+func errUpstreamUnavailable(service string, cause error) *DomainError {
+	log.Printf("%s is unavailable: %v", service, cause)
+	err := newDomainError(codes.Unavailable, ReasonUpstreamUnavailable, service+" is unavailable", map[string]string{"service": service})
+	err.RetryAfter = defaultRetry
+	return err
+}
+
+// errUpstreamStatus maps a non-2xx response from a collaborating HTTP service
+// onto the closest gRPC code.
+func errUpstreamStatus(service string, statusCode int, detail string) *DomainError {
+	log.Printf("%s returned HTTP %d: %s", service, statusCode, detail)
+	metadata := map[string]string{"service": service, "http_status": strconv.Itoa(statusCode)}
+
+	switch {
+	case statusCode == http.StatusNotFound:
+		return newDomainError(codes.NotFound, ReasonNotFound, service+" could not find the requested resource", metadata)
+	case statusCode == http.StatusConflict:
+		return newDomainError(codes.Aborted, ReasonUpstreamError, service+" reported a conflict", metadata)
+	case statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError:
+		err := newDomainError(codes.Unavailable, ReasonUpstreamUnavailable, service+" is unavailable", metadata)
+		err.RetryAfter = defaultRetry
+		return err
+	default:
+		return newDomainError(codes.Internal, ReasonUpstreamError, service+" returned an unexpected response", metadata)
+	}
+}
+
+func errInternal(message string) *DomainError {
+	return newDomainError(codes.Internal, ReasonInternal, message, nil)
+}
+
+// toStatusError is the single place where errors leave the service. Catalogue
+// errors are rendered as is, database, context and transport errors are
+// mapped to the closest gRPC code, and any other error is logged and reported
+// without its text.
+func toStatusError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var domainErr *DomainError
+	if errors.As(err, &domainErr) {
+		return domainErr.GRPCStatus().Err()
+	}
+
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		metadata := map[string]string{"constraint": pgErr.ConstraintName, "table": pgErr.TableName}
+		switch pgErr.Code {
+		case pgUniqueError:
+			return newDomainError(codes.AlreadyExists, ReasonAlreadyExists, "resource already exists", metadata).GRPCStatus().Err()
+		case pgForeignKey:
+			return newDomainError(codes.FailedPrecondition, ReasonFailedPrecondition, "referenced resource does not exist", metadata).GRPCStatus().Err()
+		}
+	}
+
+	var netErr net.Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return newDomainError(codes.NotFound, ReasonNotFound, "resource not found", nil).GRPCStatus().Err()
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return newDomainError(codes.AlreadyExists, ReasonAlreadyExists, "resource already exists", nil).GRPCStatus().Err()
+	case errors.Is(err, context.DeadlineExceeded):
+		return newDomainError(codes.DeadlineExceeded, ReasonDeadlineExceeded, "deadline exceeded", nil).GRPCStatus().Err()
+	case errors.Is(err, context.Canceled):
+		return newDomainError(codes.Canceled, ReasonCanceled, "request canceled", nil).GRPCStatus().Err()
+	case errors.As(err, &netErr):
+		return errUpstreamUnavailable("upstream", err).GRPCStatus().Err()
+	}
+
+	// Anything else may carry SQL or upstream URLs, which stay in the logs.
+	log.Printf("Internal error: %v", err)
+	return errInternal("internal error").GRPCStatus().Err()
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == pgUniqueError
+	}
+
+	return errors.Is(err, gorm.ErrDuplicatedKey)
+}
+
+// errorMappingInterceptor guarantees that every unary RPC returns a status
+// built by toStatusError, even if a handler returns a raw error.
+func errorMappingInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	resp, err := handler(ctx, req)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return resp, nil
+}
+
+func errorMappingStreamInterceptor(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := handler(srv, stream); err != nil {
+		return toStatusError(err)
+	}
+
+	return nil
+}
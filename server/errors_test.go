@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+
+	u "orderService.com/go-orderService-grpc/proto/user"
+)
+
+// violatedFields lists the fields of the BadRequest detail of err.
+func violatedFields(err error) []string {
+	var fields []string
+	for _, detail := range status.Convert(err).Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			for _, violation := range badRequest.FieldViolations {
+				fields = append(fields, violation.Field)
+			}
+		}
+	}
+
+	return fields
+}
+
+func errorInfoOf(t *testing.T, err error) *errdetails.ErrorInfo {
+	statusErr, ok := status.FromError(err)
+	assert.True(t, ok, "Expected gRPC status error")
+
+	for _, detail := range statusErr.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return info
+		}
+	}
+
+	t.Fatalf("Expected ErrorInfo detail in %v", statusErr.Details())
+	return nil
+}
+
+func TestToStatusError_MapsErrorsToCodes(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedCode   codes.Code
+		expectedReason string
+	}{
+		{
+			name:           "Unique Violation",
+			err:            fmt.Errorf("error storing the user: %w", &pgconn.PgError{Code: "23505", ConstraintName: "idx_users_username"}),
+			expectedCode:   codes.AlreadyExists,
+			expectedReason: ReasonAlreadyExists,
+		},
+		{
+			name:           "Record Not Found",
+			err:            fmt.Errorf("user not found: %w", gorm.ErrRecordNotFound),
+			expectedCode:   codes.NotFound,
+			expectedReason: ReasonNotFound,
+		},
+		{
+			name:           "Deadline Exceeded",
+			err:            context.DeadlineExceeded,
+			expectedCode:   codes.DeadlineExceeded,
+			expectedReason: ReasonDeadlineExceeded,
+		},
+		{
+			name:           "Upstream Server Error",
+			err:            errUpstreamStatus(catalogService, http.StatusInternalServerError, "boom"),
+			expectedCode:   codes.Unavailable,
+			expectedReason: ReasonUpstreamUnavailable,
+		},
+		{
+			name:           "Unexpected Error",
+			err:            errors.New("some database error"),
+			expectedCode:   codes.Internal,
+			expectedReason: ReasonInternal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := toStatusError(tt.err)
+
+			assert.Equal(t, tt.expectedCode, status.Code(err))
+			info := errorInfoOf(t, err)
+			assert.Equal(t, tt.expectedReason, info.Reason)
+			assert.Equal(t, errorDomain, info.Domain)
+		})
+	}
+}
+
+func TestToStatusError_UnexpectedError_HidesItsText(t *testing.T) {
+	err := toStatusError(errors.New(`pq: relation "orders" does not exist`))
+
+	assert.Equal(t, "internal error", status.Convert(err).Message())
+	assert.Equal(t, ReasonInternal, errorInfoOf(t, err).Reason)
+	assert.Empty(t, errorInfoOf(t, err).Metadata)
+}
+
+func TestUpstreamErrors_KeepWhatTheServiceSaidOutOfMetadata(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		metadata map[string]string
+	}{
+		{
+			name:     "Unavailable",
+			err:      errUpstreamUnavailable(catalogService, errors.New("dial tcp 10.0.3.7:8080: connection refused")),
+			metadata: map[string]string{"service": catalogService},
+		},
+		{
+			name:     "Status",
+			err:      errUpstreamStatus(catalogService, http.StatusBadGateway, "upstream connect error at catalog-internal:8080"),
+			metadata: map[string]string{"service": catalogService, "http_status": "502"},
+		},
+		{
+			name:     "Restaurant Not Found",
+			err:      errRestaurantNotFound("1", "no row in restaurants_v2"),
+			metadata: map[string]string{"restaurant_id": "1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.metadata, errorInfoOf(t, toStatusError(tt.err)).Metadata)
+		})
+	}
+}
+
+func TestDomainError_RetryableErrorCarriesRetryInfo(t *testing.T) {
+	err := errNoDeliveryExecutiveNearby(7, "no one around")
+
+	statusErr := status.Convert(err)
+	assert.Equal(t, codes.Unavailable, statusErr.Code())
+
+	var retryInfo *errdetails.RetryInfo
+	var localized *errdetails.LocalizedMessage
+	for _, detail := range statusErr.Details() {
+		switch d := detail.(type) {
+		case *errdetails.RetryInfo:
+			retryInfo = d
+		case *errdetails.LocalizedMessage:
+			localized = d
+		}
+	}
+
+	assert.NotNil(t, retryInfo)
+	assert.NotNil(t, localized)
+	assert.Equal(t, "7", errorInfoOf(t, err).Metadata["order_id"])
+}
+
+func TestRegisterUser_InvalidUserData_ReturnsFieldViolations(t *testing.T) {
+	mock, userServer := setupTestDB(t)
+
+	got, err := validated(userServer.Register)(context.Background(), &u.RegisterUserRequest{
+		Username: "user",
+		Address:  &u.Address{Street: "street", City: "city"},
+	})
+
+	mock.ExpectRollback()
+	assert.Nil(t, got)
+
+	assert.Equal(t, []string{"password", "address.state", "address.zipcode"}, violatedFields(err))
+	assert.Equal(t, ReasonInvalidArgument, errorInfoOf(t, err).Reason)
+}
+
+func TestRegisterUser_DuplicateUsername_ReturnsAlreadyExists(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	userServer := &UserServiceServer{DB: gormDb}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT").WillReturnError(&pgconn.PgError{Code: "23505"})
+	mock.ExpectRollback()
+
+	got, err := userServer.Register(context.Background(), &u.RegisterUserRequest{
+		Username: "user",
+		Password: "password",
+		Address:  &u.Address{Street: "street", City: "city", State: "state", Zipcode: "zip"},
+	})
+
+	assert.Nil(t, got)
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	assert.Equal(t, ReasonUsernameTaken, errorInfoOf(t, err).Reason)
+}
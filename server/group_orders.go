@@ -0,0 +1,398 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+
+	"gorm.io/gorm"
+	database "orderService.com/go-orderService-grpc/db"
+	"orderService.com/go-orderService-grpc/model"
+	"orderService.com/go-orderService-grpc/money"
+	"orderService.com/go-orderService-grpc/pricing"
+	o "orderService.com/go-orderService-grpc/proto/order"
+)
+
+const (
+	inviteCodeLength   = 8
+	inviteCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	inviteCodeAttempts = 3
+)
+
+var groupOrderStatuses = map[model.GroupOrderStatus]o.GroupOrderStatus{
+	model.GroupOrderOpen:      o.GroupOrderStatus_GROUP_ORDER_OPEN,
+	model.GroupOrderLocked:    o.GroupOrderStatus_GROUP_ORDER_LOCKED,
+	model.GroupOrderSubmitted: o.GroupOrderStatus_GROUP_ORDER_SUBMITTED,
+}
+
+// GroupOrderServiceServer shares the OrderServiceServer's database, catalog
+// and pricing, and places submitted group orders through it.
+type GroupOrderServiceServer struct {
+	Orders *OrderServiceServer
+	o.GroupOrderServiceServer
+}
+
+func (groupServer *GroupOrderServiceServer) CreateGroupOrder(ctx context.Context, req *o.CreateGroupOrderRequest) (*o.GroupOrder, error) {
+	user, err := groupServer.Orders.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := fetchRestaurantAddress(req.RestaurantId, groupServer.Orders.CatalogServiceAPI); err != nil {
+		return nil, toStatusError(err)
+	}
+
+	groupOrder := &model.GroupOrder{
+		HostUsername: user.Username,
+		RestaurantId: req.RestaurantId,
+		Status:       model.GroupOrderOpen,
+	}
+
+	// Invite codes are random, so a clash with an existing one is unlikely
+	// but possible; a fresh code is drawn when it happens.
+	for attempt := 1; ; attempt++ {
+		groupOrder.InviteCode, err = newInviteCode()
+		if err != nil {
+			return nil, toStatusError(err)
+		}
+
+		err = groupServer.Orders.DB.Create(groupOrder).Error
+		if err == nil || !isUniqueViolation(err) || attempt == inviteCodeAttempts {
+			break
+		}
+	}
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return groupServer.groupOrderResponse(groupOrder)
+}
+
+func (groupServer *GroupOrderServiceServer) GetGroupOrder(ctx context.Context, req *o.GetGroupOrderRequest) (*o.GroupOrder, error) {
+	if _, err := groupServer.Orders.authenticate(ctx); err != nil {
+		return nil, err
+	}
+
+	groupOrder, err := getGroupOrder(groupServer.Orders.DB, req.InviteCode)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return groupServer.groupOrderResponse(groupOrder)
+}
+
+// AddGroupOrderItem adds to the caller's own quantity of a menu item.
+func (groupServer *GroupOrderServiceServer) AddGroupOrderItem(ctx context.Context, req *o.AddGroupOrderItemRequest) (*o.GroupOrder, error) {
+	user, err := groupServer.Orders.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	groupOrder, err := getGroupOrder(groupServer.Orders.DB, req.InviteCode)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	// Checks that the restaurant sells the item before it is added.
+	_, _, err = calculateOrderTotal(&o.CreateOrderRequest{
+		RestaurantId: groupOrder.RestaurantId,
+		MenuItems:    map[string]int32{req.MenuItem: req.Quantity},
+	}, groupServer.Orders.CatalogServiceAPI)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	err = groupServer.Orders.DB.Transaction(func(tx *gorm.DB) error {
+		groupOrder, err = lockOpenGroupOrder(tx, req.InviteCode)
+		if err != nil {
+			return err
+		}
+
+		var quantity int32
+		for _, item := range groupOrder.Items {
+			if item.Username == user.Username && item.MenuItemName == req.MenuItem {
+				quantity = item.Quantity
+			}
+		}
+
+		menuItems := groupOrder.MenuItems()
+		if _, ordered := menuItems[req.MenuItem]; !ordered && len(menuItems) >= maxCartItems {
+			return errCartFull(maxCartItems)
+		}
+
+		quantity += req.Quantity
+		if quantity > maxCartItemQuantity {
+			return errInvalidArgument("Invalid quantity", fieldViolation("quantity", "a participant can order at most 99 of an item"))
+		}
+
+		return database.SetGroupOrderItemQuantity(tx, groupOrder.Id, user.Username, req.MenuItem, quantity)
+	})
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return groupServer.reloadedGroupOrder(req.InviteCode)
+}
+
+// RemoveGroupOrderItem removes a menu item from the caller's own items. It is
+// idempotent, like removing an item from a cart.
+func (groupServer *GroupOrderServiceServer) RemoveGroupOrderItem(ctx context.Context, req *o.RemoveGroupOrderItemRequest) (*o.GroupOrder, error) {
+	user, err := groupServer.Orders.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = groupServer.Orders.DB.Transaction(func(tx *gorm.DB) error {
+		groupOrder, err := lockOpenGroupOrder(tx, req.InviteCode)
+		if err != nil {
+			return err
+		}
+
+		return database.SetGroupOrderItemQuantity(tx, groupOrder.Id, user.Username, req.MenuItem, 0)
+	})
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return groupServer.reloadedGroupOrder(req.InviteCode)
+}
+
+func (groupServer *GroupOrderServiceServer) LockGroupOrder(ctx context.Context, req *o.LockGroupOrderRequest) (*o.GroupOrder, error) {
+	user, err := groupServer.Orders.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = groupServer.Orders.DB.Transaction(func(tx *gorm.DB) error {
+		groupOrder, err := database.LockGroupOrder(tx, req.InviteCode)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errGroupOrderNotFound(req.InviteCode)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if groupOrder.HostUsername != user.Username {
+			return errNotGroupOrderHost(req.InviteCode)
+		}
+
+		switch groupOrder.Status {
+		case model.GroupOrderLocked:
+			return nil
+		case model.GroupOrderOpen:
+			groupOrder.Status = model.GroupOrderLocked
+			return database.UpdateGroupOrderStatus(tx, groupOrder)
+		default:
+			return errGroupOrderClosed(req.InviteCode, groupOrder.Status)
+		}
+	})
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return groupServer.reloadedGroupOrder(req.InviteCode)
+}
+
+// SubmitGroupOrder places the group order as one order for the host, through
+// the same path as Create. The group order is marked submitted in the order's
+// transaction.
+func (groupServer *GroupOrderServiceServer) SubmitGroupOrder(ctx context.Context, req *o.SubmitGroupOrderRequest) (*o.SubmitGroupOrderResponse, error) {
+	user, err := groupServer.Orders.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	groupOrder, err := getGroupOrder(groupServer.Orders.DB, req.InviteCode)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	if groupOrder.HostUsername != user.Username {
+		return nil, errNotGroupOrderHost(req.InviteCode)
+	}
+
+	switch groupOrder.Status {
+	case model.GroupOrderOpen:
+		return nil, errGroupOrderNotLocked(req.InviteCode)
+	case model.GroupOrderSubmitted:
+		return nil, errGroupOrderClosed(req.InviteCode, groupOrder.Status)
+	}
+
+	if len(groupOrder.Items) == 0 {
+		return nil, errGroupOrderEmpty(req.InviteCode)
+	}
+
+	orderRequest := &o.CreateOrderRequest{
+		RestaurantId: groupOrder.RestaurantId,
+		MenuItems:    groupOrder.MenuItems(),
+		Tip:          req.Tip,
+		PromoCode:    req.PromoCode,
+	}
+
+	order, err := groupServer.Orders.placeOrder(user, orderRequest, func(tx *gorm.DB, order *model.Order) error {
+		locked, err := database.LockGroupOrder(tx, req.InviteCode)
+		if err != nil {
+			return err
+		}
+
+		if locked.Status != model.GroupOrderLocked {
+			return errGroupOrderClosed(req.InviteCode, locked.Status)
+		}
+
+		locked.Status = model.GroupOrderSubmitted
+		locked.OrderId = &order.Id
+		return database.UpdateGroupOrderStatus(tx, locked)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := groupServer.reloadedGroupOrder(req.InviteCode)
+	if err != nil {
+		return nil, err
+	}
+
+	return &o.SubmitGroupOrderResponse{Order: order, GroupOrder: response}, nil
+}
+
+func (groupServer *GroupOrderServiceServer) reloadedGroupOrder(inviteCode string) (*o.GroupOrder, error) {
+	groupOrder, err := getGroupOrder(groupServer.Orders.DB, inviteCode)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return groupServer.groupOrderResponse(groupOrder)
+}
+
+// groupOrderResponse prices a group order and splits the price between its
+// participants. A submitted group order is described by the order it placed;
+// otherwise it is priced at current catalog prices, delivered to the host.
+func (groupServer *GroupOrderServiceServer) groupOrderResponse(groupOrder *model.GroupOrder) (*o.GroupOrder, error) {
+	response := &o.GroupOrder{
+		InviteCode:   groupOrder.InviteCode,
+		HostUsername: groupOrder.HostUsername,
+		RestaurantId: groupOrder.RestaurantId,
+		Status:       groupOrderStatuses[groupOrder.Status],
+	}
+
+	if groupOrder.OrderId != nil {
+		response.OrderId = *groupOrder.OrderId
+	}
+
+	if len(groupOrder.Items) == 0 {
+		return response, nil
+	}
+
+	var items []model.OrderItem
+	var breakdown pricing.Breakdown
+
+	if groupOrder.OrderId != nil {
+		order, err := database.GetOrder(groupServer.Orders.DB, *groupOrder.OrderId)
+		if err != nil {
+			return nil, toStatusError(err)
+		}
+
+		items, breakdown = order.Items, breakdownOf(order)
+	} else {
+		host, err := database.GetUserByUsername(groupServer.Orders.DB, groupOrder.HostUsername)
+		if err != nil {
+			return nil, toStatusError(err)
+		}
+
+		priced, err := groupServer.Orders.priceOrder(host, &o.CreateOrderRequest{RestaurantId: groupOrder.RestaurantId, MenuItems: groupOrder.MenuItems()})
+		if err != nil {
+			return nil, toStatusError(err)
+		}
+
+		items, breakdown = priced.items, priced.breakdown
+	}
+
+	unitAmounts := make(map[string]int64, len(items))
+	for _, item := range items {
+		unitAmounts[item.Key()] = item.UnitAmount
+	}
+
+	participants := groupOrder.Participants()
+	menuItems := make(map[string]map[string]int32, len(participants))
+	subtotals := make([]int64, len(participants))
+
+	for i, username := range participants {
+		menuItems[username] = make(map[string]int32)
+		for _, item := range groupOrder.Items {
+			if item.Username == username {
+				menuItems[username][item.MenuItemName] = item.Quantity
+				subtotals[i] += unitAmounts[item.MenuItemName] * int64(item.Quantity)
+			}
+		}
+	}
+
+	shares := breakdown.Split(subtotals)
+	for i, username := range participants {
+		response.Participants = append(response.Participants, &o.GroupOrderParticipant{
+			Username:  username,
+			MenuItems: menuItems[username],
+			Share:     toProtoBreakdown(shares[i]),
+		})
+	}
+
+	response.Breakdown = toProtoBreakdown(breakdown)
+	response.Total = toProtoMoney(breakdown.Total)
+
+	return response, nil
+}
+
+func getGroupOrder(db *gorm.DB, inviteCode string) (*model.GroupOrder, error) {
+	groupOrder, err := database.GetGroupOrderByInviteCode(db, inviteCode)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errGroupOrderNotFound(inviteCode)
+	}
+
+	return groupOrder, err
+}
+
+func lockOpenGroupOrder(tx *gorm.DB, inviteCode string) (*model.GroupOrder, error) {
+	groupOrder, err := database.LockGroupOrder(tx, inviteCode)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errGroupOrderNotFound(inviteCode)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if groupOrder.Status != model.GroupOrderOpen {
+		return nil, errGroupOrderClosed(inviteCode, groupOrder.Status)
+	}
+
+	return groupOrder, nil
+}
+
+// breakdownOf rebuilds the price breakdown of a stored order.
+func breakdownOf(order *model.Order) pricing.Breakdown {
+	return pricing.Breakdown{
+		Subtotal:         money.New(order.SubtotalAmount, order.Currency),
+		Discount:         money.New(order.DiscountAmount, order.Currency),
+		Tax:              money.New(order.TaxAmount, order.Currency),
+		TaxBasisPoints:   order.TaxBasisPoints,
+		DeliveryFee:      money.New(order.DeliveryFeeAmount, order.Currency),
+		SurgeBasisPoints: order.SurgeBasisPoints,
+		ServiceFee:       money.New(order.ServiceFeeAmount, order.Currency),
+		Tip:              money.New(order.TipAmount, order.Currency),
+		Total:            money.New(order.TotalAmount, order.Currency),
+	}
+}
+
+func newInviteCode() (string, error) {
+	random := make([]byte, inviteCodeLength)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+
+	code := make([]byte, inviteCodeLength)
+	for i, b := range random {
+		code[i] = inviteCodeAlphabet[int(b)%len(inviteCodeAlphabet)]
+	}
+
+	return string(code), nil
+}
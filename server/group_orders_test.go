@@ -0,0 +1,144 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"orderService.com/go-orderService-grpc/pricing"
+	o "orderService.com/go-orderService-grpc/proto/order"
+)
+
+var (
+	groupOrderColumns     = []string{"id", "invite_code", "host_username", "restaurant_id", "status", "order_id"}
+	groupOrderItemColumns = []string{"id", "group_order_id", "username", "menu_item_name", "quantity"}
+)
+
+func expectGroupOrderLookup(mock sqlmock.Sqlmock, status string, orderId any, forUpdate bool) {
+	query := regexp.QuoteMeta(`SELECT * FROM "group_orders" WHERE invite_code = $1`)
+	if forUpdate {
+		query += `.*FOR UPDATE`
+	}
+
+	mock.ExpectQuery(query).WithArgs("ABCD2345", 1).
+		WillReturnRows(sqlmock.NewRows(groupOrderColumns).AddRow(4, "ABCD2345", "host", "1", status, orderId))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "group_order_items" WHERE "group_order_items"."group_order_id" = $1`)).WithArgs(4).
+		WillReturnRows(sqlmock.NewRows(groupOrderItemColumns).
+			AddRow(1, 4, "alice", "Paneer Tikka", 1).
+			AddRow(2, 4, "host", "Naan", 2).
+			AddRow(3, 4, "host", "Paneer Tikka", 1))
+}
+
+func newGroupOrderServer(t *testing.T) (sqlmock.Sqlmock, *GroupOrderServiceServer) {
+	mock, gormDb := openMockDB(t)
+	catalog := newCatalogServer(t, "1", map[string]menuItemFixture{
+		"Paneer Tikka": {Price: "250", Currency: "INR"},
+		"Naan":         {Price: "50", Currency: "INR"},
+	})
+	fulfillment := newFulfillmentServer(t, http.StatusCreated)
+
+	return mock, &GroupOrderServiceServer{Orders: &OrderServiceServer{
+		DB:                    gormDb,
+		CatalogServiceAPI:     catalog.URL + "/restaurants/",
+		FulfillmentServiceAPI: fulfillment.URL,
+		Pricing: pricing.NewEngine(pricing.Config{
+			DeliveryFees: pricing.ZoneDeliveryFees{OtherState: 4000},
+		}),
+	}}
+}
+
+func TestGetGroupOrder_SplitsPriceByParticipant(t *testing.T) {
+	mock, groupServer := newGroupOrderServer(t)
+
+	expectUserLookup(t, mock, "alice", "password")
+	expectGroupOrderLookup(mock, "OPEN", nil, false)
+	expectUserLookup(t, mock, "host", "password")
+
+	groupOrder, err := groupServer.GetGroupOrder(basicAuthContext("alice", "password"), &o.GetGroupOrderRequest{InviteCode: "ABCD2345"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, o.GroupOrderStatus_GROUP_ORDER_OPEN, groupOrder.Status)
+	assert.Equal(t, &o.Money{CurrencyCode: "INR", AmountMinor: 64000}, groupOrder.Total)
+	assert.Equal(t, "alice", groupOrder.Participants[0].Username)
+	assert.Equal(t, &o.Money{CurrencyCode: "INR", AmountMinor: 25000}, groupOrder.Participants[0].Share.Subtotal)
+	assert.Equal(t, &o.Money{CurrencyCode: "INR", AmountMinor: 1667}, groupOrder.Participants[0].Share.DeliveryFee)
+	assert.Equal(t, &o.Money{CurrencyCode: "INR", AmountMinor: 26667}, groupOrder.Participants[0].Share.Total)
+	assert.Equal(t, "host", groupOrder.Participants[1].Username)
+	assert.Equal(t, map[string]int32{"Naan": 2, "Paneer Tikka": 1}, groupOrder.Participants[1].MenuItems)
+	assert.Equal(t, &o.Money{CurrencyCode: "INR", AmountMinor: 37333}, groupOrder.Participants[1].Share.Total)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestSubmitGroupOrder_Rejections(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		status   string
+		code     codes.Code
+		reason   string
+	}{
+		{name: "Not The Host", username: "alice", status: "LOCKED", code: codes.PermissionDenied, reason: ReasonNotGroupOrderHost},
+		{name: "Still Open", username: "host", status: "OPEN", code: codes.FailedPrecondition, reason: ReasonGroupOrderNotLocked},
+		{name: "Already Submitted", username: "host", status: "SUBMITTED", code: codes.FailedPrecondition, reason: ReasonGroupOrderClosed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock, groupServer := newGroupOrderServer(t)
+
+			expectUserLookup(t, mock, tt.username, "password")
+			expectGroupOrderLookup(mock, tt.status, nil, false)
+
+			response, err := groupServer.SubmitGroupOrder(basicAuthContext(tt.username, "password"), &o.SubmitGroupOrderRequest{InviteCode: "ABCD2345"})
+
+			assert.Nil(t, response)
+			assert.Equal(t, tt.code, status.Code(err))
+			assert.Equal(t, tt.reason, errorInfoOf(t, err).Reason)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestSubmitGroupOrder_PlacesOneOrderForTheHost(t *testing.T) {
+	mock, groupServer := newGroupOrderServer(t)
+
+	expectUserLookup(t, mock, "host", "password")
+	expectGroupOrderLookup(mock, "LOCKED", nil, false)
+	mock.ExpectBegin()
+	expectOrderInsert(mock, 7, orderColumns{
+		"username":            "host",
+		"subtotal_amount":     60000,
+		"delivery_fee_amount": 4000,
+		"total_amount":        64000,
+	})
+	mock.ExpectQuery(`INSERT INTO "order_items"`).
+		WithArgs(7, "id-Naan", "House Naan", "Naan", 2, 5000, 10000, nil, "", 7, "id-Paneer Tikka", "House Paneer Tikka", "Paneer Tikka", 2, 25000, 50000, nil, "").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	expectGroupOrderLookup(mock, "LOCKED", nil, true)
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "group_orders" SET "status"=$1,"order_id"=$2 WHERE "id" = $3`)).
+		WithArgs("SUBMITTED", 7, 4).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectGroupOrderLookup(mock, "SUBMITTED", 7, false)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "orders" WHERE id = $1`)).WithArgs(7, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "restaurant_id", "username", "currency", "subtotal_amount", "delivery_fee_amount", "total_amount"}).
+			AddRow(7, "1", "host", "INR", 60000, 4000, 64000))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "order_items" WHERE "order_items"."order_id" = $1`)).WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "menu_item_name", "menu_item_key", "quantity", "unit_amount", "line_amount"}).
+			AddRow(1, 7, "House Naan", "Naan", 2, 5000, 10000).
+			AddRow(2, 7, "House Paneer Tikka", "Paneer Tikka", 2, 25000, 50000))
+
+	response, err := groupServer.SubmitGroupOrder(basicAuthContext("host", "password"), &o.SubmitGroupOrderRequest{InviteCode: "ABCD2345"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(7), response.Order.Id)
+	assert.Equal(t, int64(7), response.GroupOrder.OrderId)
+	assert.Equal(t, o.GroupOrderStatus_GROUP_ORDER_SUBMITTED, response.GroupOrder.Status)
+	assert.Equal(t, &o.Money{CurrencyCode: "INR", AmountMinor: 26667}, response.GroupOrder.Participants[0].Share.Total)
+	assert.Equal(t, &o.Money{CurrencyCode: "INR", AmountMinor: 37333}, response.GroupOrder.Participants[1].Share.Total)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"orderService.com/go-orderService-grpc/limits"
+	"orderService.com/go-orderService-grpc/money"
+)
+
+// checkLimits fails orders that break the restaurant's minimum basket or the
+// limits that guard against abusive orders.
+func (orderServer *OrderServiceServer) checkLimits(restaurantId string, priced *pricedOrder) error {
+	breakdown := priced.breakdown
+
+	broken := orderServer.Limits.Check(restaurantId, limits.Order{Subtotal: breakdown.Subtotal, Total: breakdown.Total, Items: priced.items})
+	if len(broken) > 0 {
+		return errOrderLimits(restaurantId, breakdown.Total.Currency, broken)
+	}
+
+	return nil
+}
+
+// limitViolation describes a broken limit the way a client would put it to
+// the customer.
+func limitViolation(violation limits.Violation, currency string) *errdetails.PreconditionFailure_Violation {
+	var subject, description string
+
+	switch violation.Kind {
+	case limits.MinSubtotal:
+		subject = "subtotal"
+		description = fmt.Sprintf("add %s more to reach the minimum order of %s", money.New(violation.Short, currency), money.New(violation.Limit, currency))
+	case limits.MaxTotalQuantity:
+		subject = "quantity"
+		description = fmt.Sprintf("at most %d items per order, remove %d", violation.Limit, violation.Short)
+	case limits.MaxDistinctItems:
+		subject = "menu_items"
+		description = fmt.Sprintf("at most %d different items per order, remove %d", violation.Limit, violation.Short)
+	case limits.MaxOrderValue:
+		subject = "total"
+		description = fmt.Sprintf("orders may total at most %s, remove %s", money.New(violation.Limit, currency), money.New(violation.Short, currency))
+	}
+
+	return &errdetails.PreconditionFailure_Violation{Type: violation.Kind, Subject: subject, Description: description}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"orderService.com/go-orderService-grpc/limits"
+	"orderService.com/go-orderService-grpc/pricing"
+	o "orderService.com/go-orderService-grpc/proto/order"
+	"orderService.com/go-orderService-grpc/quotes"
+)
+
+func newLimitedServer(t *testing.T, restaurantLimits limits.Limits) (*OrderServiceServer, func() error) {
+	mock, gormDb := openMockDB(t)
+	catalog := newCatalogServer(t, "1", map[string]menuItemFixture{
+		"Naan":    {Price: "40", Currency: "INR"},
+		"Biryani": {Price: "250", Currency: "INR"},
+	})
+
+	expectUserLookup(t, mock, "username", "password")
+
+	return &OrderServiceServer{
+		DB:                    gormDb,
+		CatalogServiceAPI:     catalog.URL + "/restaurants/",
+		FulfillmentServiceAPI: httpServerThatFails(t),
+		Pricing:               pricing.NewEngine(pricing.Config{}),
+		Quotes:                quotes.NewSigner([]byte("secret"), 5*time.Minute),
+		Limits:                limits.NewChecker(limits.Config{Restaurants: map[string]limits.Limits{"1": restaurantLimits}}),
+	}, mock.ExpectationsWereMet
+}
+
+func preconditionsOf(t *testing.T, err error) []*errdetails.PreconditionFailure_Violation {
+	for _, detail := range status.Convert(err).Details() {
+		if failure, ok := detail.(*errdetails.PreconditionFailure); ok {
+			return failure.Violations
+		}
+	}
+
+	t.Fatalf("no PreconditionFailure in %v", err)
+	return nil
+}
+
+func TestCreateOrder_BelowMinimumOrder_TellsHowMuchToAdd(t *testing.T) {
+	orderServiceServer, expectationsWereMet := newLimitedServer(t, limits.Limits{MinSubtotal: 20000})
+
+	response, err := orderServiceServer.Create(basicAuthContext("username", "password"), &o.CreateOrderRequest{
+		RestaurantId: "1",
+		MenuItems:    map[string]int32{"Naan": 2},
+	})
+
+	assert.Nil(t, response)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.Equal(t, ReasonBelowMinimumOrder, errorInfoOf(t, err).Reason)
+	assert.Equal(t, "120.00 INR", errorInfoOf(t, err).Metadata["amount_to_add"])
+	assert.Equal(t, "200.00 INR", errorInfoOf(t, err).Metadata["min_subtotal"])
+	assert.Equal(t, limits.MinSubtotal, preconditionsOf(t, err)[0].Type)
+	assert.Equal(t, "add 120.00 INR more to reach the minimum order of 200.00 INR", status.Convert(err).Message())
+	assert.Nil(t, expectationsWereMet())
+}
+
+func TestQuoteOrder_OverOrderLimits_ListsEveryBrokenLimit(t *testing.T) {
+	orderServiceServer, expectationsWereMet := newLimitedServer(t, limits.Limits{MaxTotalQuantity: 5, MaxDistinctItems: 1, MaxOrderValue: 100000})
+
+	_, err := orderServiceServer.QuoteOrder(basicAuthContext("username", "password"), &o.QuoteOrderRequest{
+		RestaurantId: "1",
+		MenuItems:    map[string]int32{"Naan": 3, "Biryani": 4},
+	})
+
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.Equal(t, ReasonOrderLimitExceeded, errorInfoOf(t, err).Reason)
+
+	var broken []string
+	for _, violation := range preconditionsOf(t, err) {
+		broken = append(broken, violation.Subject+": "+violation.Description)
+	}
+	assert.Equal(t, []string{
+		"quantity: at most 5 items per order, remove 2",
+		"menu_items: at most 1 different items per order, remove 1",
+		"total: orders may total at most 1000.00 INR, remove 120.00 INR",
+	}, broken)
+	assert.Nil(t, expectationsWereMet())
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"orderService.com/go-orderService-grpc/model"
+	o "orderService.com/go-orderService-grpc/proto/order"
+	"orderService.com/go-orderService-grpc/quotes"
+)
+
+// maxOrderLines is how many lines menu_items and lines may list together.
+const maxOrderLines = 50
+
+// orderLine is a line of an order as requested, from either menu_items or
+// lines. field names it in field violations.
+type orderLine struct {
+	menuItem     string
+	quantity     int32
+	optionIds    []string
+	instructions string
+	field        string
+}
+
+// requestedLines lists the menu_items of a request, by name, followed by its
+// lines in the order they were sent.
+func requestedLines(req *o.CreateOrderRequest) ([]orderLine, error) {
+	if len(req.MenuItems) == 0 && len(req.Lines) == 0 {
+		return nil, errInvalidArgument("Invalid request", fieldViolation("menu_items", "menu_items or lines must list at least one item"))
+	}
+
+	if len(req.MenuItems)+len(req.Lines) > maxOrderLines {
+		return nil, errInvalidArgument("Invalid request", fieldViolation("lines", fmt.Sprintf("menu_items and lines must list at most %d items together", maxOrderLines)))
+	}
+
+	names := make([]string, 0, len(req.MenuItems))
+	for name := range req.MenuItems {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := make([]orderLine, 0, len(names)+len(req.Lines))
+	for _, name := range names {
+		lines = append(lines, orderLine{menuItem: name, quantity: req.MenuItems[name], field: fmt.Sprintf("menu_items[%s]", name)})
+	}
+
+	for i, line := range req.Lines {
+		lines = append(lines, orderLine{
+			menuItem:     line.MenuItemId,
+			quantity:     line.Quantity,
+			optionIds:    line.ModifierOptionIds,
+			instructions: line.Instructions,
+			field:        fmt.Sprintf("lines[%d]", i),
+		})
+	}
+
+	return lines, nil
+}
+
+// linesOf asks again for the items of a stored order, with the same options
+// and instructions.
+func linesOf(items []model.OrderItem) []*o.OrderLine {
+	lines := make([]*o.OrderLine, len(items))
+	for i, item := range items {
+		lines[i] = &o.OrderLine{MenuItemId: item.Key(), Quantity: item.Quantity, Instructions: item.Instructions}
+		for _, option := range item.Options {
+			lines[i].ModifierOptionIds = append(lines[i].ModifierOptionIds, option.OptionId)
+		}
+	}
+
+	return lines
+}
+
+// orderItem prices a line of the menu item with the options it chooses. A
+// line whose options don't fit the item's modifier groups gets a violation
+// instead.
+func (menuItem *catalogMenuItem) orderItem(line orderLine) (model.OrderItem, *errdetails.BadRequest_FieldViolation) {
+	field := line.field + ".modifier_option_ids"
+
+	chosen := make(map[string]bool, len(line.optionIds))
+	for _, optionId := range line.optionIds {
+		if chosen[optionId] {
+			return model.OrderItem{}, fieldViolation(field, fmt.Sprintf("option %q is chosen twice", optionId))
+		}
+		chosen[optionId] = true
+	}
+
+	unitPrice := menuItem.Price
+	var options []model.OrderItemOption
+
+	for _, group := range menuItem.ModifierGroups {
+		count := 0
+		for _, option := range group.Options {
+			if !chosen[option.Id] {
+				continue
+			}
+
+			delete(chosen, option.Id)
+			count++
+			unitPrice.Amount += option.Price.Amount
+			options = append(options, model.OrderItemOption{GroupId: group.Id, OptionId: option.Id, Name: option.Name, PriceAmount: option.Price.Amount})
+		}
+
+		switch {
+		case count < group.Min:
+			return model.OrderItem{}, fieldViolation(field, fmt.Sprintf("%s: choose at least %d", group.Name, group.Min))
+		case group.Max > 0 && count > group.Max:
+			return model.OrderItem{}, fieldViolation(field, fmt.Sprintf("%s: choose at most %d", group.Name, group.Max))
+		}
+	}
+
+	for _, optionId := range line.optionIds {
+		if chosen[optionId] {
+			return model.OrderItem{}, fieldViolation(field, fmt.Sprintf("%s has no option %q", line.menuItem, optionId))
+		}
+	}
+
+	name := menuItem.Name
+	if name == "" {
+		name = line.menuItem
+	}
+
+	return model.OrderItem{
+		MenuItemId:   menuItem.Id,
+		MenuItemName: name,
+		MenuItemKey:  line.menuItem,
+		Quantity:     line.quantity,
+		UnitAmount:   unitPrice.Amount,
+		LineAmount:   unitPrice.Multiply(int64(line.quantity)).Amount,
+		Options:      options,
+		Instructions: line.instructions,
+	}, nil
+}
+
+func quoteLines(lines []*o.OrderLine) []quotes.Line {
+	quoted := make([]quotes.Line, len(lines))
+	for i, line := range lines {
+		quoted[i] = quotes.Line{MenuItemId: line.MenuItemId, Quantity: line.Quantity, OptionIds: line.ModifierOptionIds, Instructions: line.Instructions}
+	}
+
+	return quoted
+}
+
+func sameLines(quoted []quotes.Line, lines []*o.OrderLine) bool {
+	return slices.EqualFunc(quoted, quoteLines(lines), func(a quotes.Line, b quotes.Line) bool {
+		return a.MenuItemId == b.MenuItemId && a.Quantity == b.Quantity && a.Instructions == b.Instructions && slices.Equal(a.OptionIds, b.OptionIds)
+	})
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"orderService.com/go-orderService-grpc/pricing"
+	o "orderService.com/go-orderService-grpc/proto/order"
+)
+
+const biryaniModifiers = `,"modifier_groups":[` +
+	`{"id":"size","name":"Size","min_selections":1,"max_selections":1,"options":[{"id":"regular","name":"Regular","price":"0"},{"id":"large","name":"Large","price":"60"}]},` +
+	`{"id":"extras","name":"Extras","max_selections":2,"options":[{"id":"raita","name":"Raita","price":"20"},{"id":"egg","name":"Egg","price":"25"}]}]`
+
+func newModifiersServer(t *testing.T) (sqlmock.Sqlmock, *OrderServiceServer) {
+	mock, gormDb := openMockDB(t)
+	catalog := newCatalogServer(t, "1", map[string]menuItemFixture{
+		"Biryani": {Price: "250", Currency: "INR", Extra: biryaniModifiers},
+		"Naan":    {Price: "40", Currency: "INR"},
+	})
+
+	return mock, &OrderServiceServer{
+		DB:                    gormDb,
+		CatalogServiceAPI:     catalog.URL + "/restaurants/",
+		FulfillmentServiceAPI: httpServerThatFails(t),
+		Pricing:               pricing.NewEngine(pricing.Config{}),
+	}
+}
+
+func TestCreateOrder_LinesWithModifiers_PricesOptionsAndKeepsInstructions(t *testing.T) {
+	mock, orderServiceServer := newModifiersServer(t)
+
+	expectUserLookup(t, mock, "username", "password")
+	mock.ExpectBegin()
+	expectOrderInsert(mock, 7, orderColumns{
+		"subtotal_amount": 70000,
+		"total_amount":    70000,
+	})
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "order_items" ("order_id","menu_item_id","menu_item_name","menu_item_key","quantity","unit_amount","line_amount","options","instructions")`)).
+		WithArgs(
+			7, "id-Biryani", "House Biryani", "Biryani", 2, 33000, 66000,
+			`[{"group_id":"size","option_id":"large","name":"Large","price_amount":6000},{"group_id":"extras","option_id":"raita","name":"Raita","price_amount":2000}]`,
+			"extra spicy, no onions",
+			7, "id-Naan", "House Naan", "Naan", 1, 4000, 4000, nil, "",
+		).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	mock.ExpectCommit()
+
+	response, err := orderServiceServer.Create(basicAuthContext("username", "password"), &o.CreateOrderRequest{
+		RestaurantId: "1",
+		MenuItems:    map[string]int32{"Naan": 1},
+		Lines:        []*o.OrderLine{{MenuItemId: "Biryani", Quantity: 2, ModifierOptionIds: []string{"raita", "large"}, Instructions: "extra spicy, no onions"}},
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, &o.OrderLineItem{
+		MenuItemId: "id-Biryani",
+		Name:       "House Biryani",
+		Quantity:   2,
+		UnitPrice:  &o.Money{CurrencyCode: "INR", AmountMinor: 33000},
+		LineTotal:  &o.Money{CurrencyCode: "INR", AmountMinor: 66000},
+		Modifiers: []*o.SelectedModifier{
+			{GroupId: "size", OptionId: "large", Name: "Large", Price: &o.Money{CurrencyCode: "INR", AmountMinor: 6000}},
+			{GroupId: "extras", OptionId: "raita", Name: "Raita", Price: &o.Money{CurrencyCode: "INR", AmountMinor: 2000}},
+		},
+		Instructions: "extra spicy, no onions",
+	}, response.LineItems[0])
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateOrder_InvalidModifiers_ReturnsViolationPerLine(t *testing.T) {
+	mock, orderServiceServer := newModifiersServer(t)
+
+	expectUserLookup(t, mock, "username", "password")
+
+	_, err := orderServiceServer.Create(basicAuthContext("username", "password"), &o.CreateOrderRequest{
+		RestaurantId: "1",
+		Lines: []*o.OrderLine{
+			{MenuItemId: "Biryani", Quantity: 1, ModifierOptionIds: []string{"raita"}},
+			{MenuItemId: "Biryani", Quantity: 1, ModifierOptionIds: []string{"large", "raita", "egg", "cheese"}},
+			{MenuItemId: "Naan", Quantity: 1, ModifierOptionIds: []string{"large"}},
+		},
+	})
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	var violations []string
+	for _, detail := range status.Convert(err).Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			for _, violation := range badRequest.FieldViolations {
+				violations = append(violations, violation.Field+": "+violation.Description)
+			}
+		}
+	}
+	assert.Equal(t, []string{
+		"lines[0].modifier_option_ids: Size: choose at least 1",
+		`lines[1].modifier_option_ids: Biryani has no option "cheese"`,
+		`lines[2].modifier_option_ids: Naan has no option "large"`,
+	}, violations)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateOrder_NothingRequested_ReturnsInvalidArgument(t *testing.T) {
+	mock, orderServiceServer := newModifiersServer(t)
+
+	expectUserLookup(t, mock, "username", "password")
+
+	_, err := orderServiceServer.Create(basicAuthContext("username", "password"), &o.CreateOrderRequest{RestaurantId: "1"})
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateOrder_TooManyItemsTogether_ReturnsInvalidArgument(t *testing.T) {
+	mock, orderServiceServer := newModifiersServer(t)
+
+	menuItems := make(map[string]int32, 30)
+	lines := make([]*o.OrderLine, 0, 30)
+	for i := range 30 {
+		menuItems[fmt.Sprintf("Item %d", i)] = 1
+		lines = append(lines, &o.OrderLine{MenuItemId: "Naan", Quantity: 1})
+	}
+
+	expectUserLookup(t, mock, "username", "password")
+
+	_, err := validated(orderServiceServer.Create)(basicAuthContext("username", "password"), &o.CreateOrderRequest{
+		RestaurantId: "1",
+		MenuItems:    menuItems,
+		Lines:        lines,
+	})
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, []string{"lines"}, violatedFields(err))
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
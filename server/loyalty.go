@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+	database "orderService.com/go-orderService-grpc/db"
+	"orderService.com/go-orderService-grpc/loyalty"
+	"orderService.com/go-orderService-grpc/model"
+	"orderService.com/go-orderService-grpc/money"
+	o "orderService.com/go-orderService-grpc/proto/order"
+)
+
+const defaultLoyaltyPageSize = 50
+
+type LoyaltyServiceServer struct {
+	Orders *OrderServiceServer
+	o.LoyaltyServiceServer
+}
+
+func (loyaltyServer *LoyaltyServiceServer) GetLoyaltyBalance(ctx context.Context, _ *o.GetLoyaltyBalanceRequest) (*o.LoyaltyBalance, error) {
+	user, err := loyaltyServer.Orders.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var lots []loyalty.Lot
+	err = loyaltyServer.Orders.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := database.LockUser(tx, user.Username); err != nil {
+			return err
+		}
+
+		lots, err = settleLoyaltyPoints(tx, user.Username, time.Now())
+		return err
+	})
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	balance := &o.LoyaltyBalance{}
+	for _, lot := range lots {
+		balance.Points += lot.Points
+	}
+
+	if len(lots) > 0 {
+		balance.ExpiringPoints = lots[0].Points
+		balance.ExpiresAt = timestamppb.New(lots[0].ExpiresAt)
+	}
+
+	return balance, nil
+}
+
+func (loyaltyServer *LoyaltyServiceServer) ListLoyaltyTransactions(ctx context.Context, req *o.ListLoyaltyTransactionsRequest) (*o.ListLoyaltyTransactionsResponse, error) {
+	user, err := loyaltyServer.Orders.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pageSize := int(req.PageSize)
+	if pageSize == 0 {
+		pageSize = defaultLoyaltyPageSize
+	}
+
+	transactions, err := database.ListLoyaltyTransactions(loyaltyServer.Orders.DB, user.Username, req.BeforeId, pageSize)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	response := &o.ListLoyaltyTransactionsResponse{}
+	for _, transaction := range transactions {
+		response.Transactions = append(response.Transactions, toProtoLoyaltyTransaction(transaction))
+	}
+
+	if len(transactions) == pageSize {
+		response.NextBeforeId = transactions[len(transactions)-1].Id
+	}
+
+	return response, nil
+}
+
+var loyaltyKinds = map[model.LoyaltyKind]o.LoyaltyTransactionKind{
+	model.LoyaltyEarn:     o.LoyaltyTransactionKind_LOYALTY_EARN,
+	model.LoyaltyRedeem:   o.LoyaltyTransactionKind_LOYALTY_REDEEM,
+	model.LoyaltyReversal: o.LoyaltyTransactionKind_LOYALTY_REVERSAL,
+	model.LoyaltyExpiry:   o.LoyaltyTransactionKind_LOYALTY_EXPIRY,
+}
+
+func toProtoLoyaltyTransaction(transaction model.LoyaltyTransaction) *o.LoyaltyTransaction {
+	response := &o.LoyaltyTransaction{
+		Id:        transaction.Id,
+		Kind:      loyaltyKinds[transaction.Kind],
+		OrderId:   transaction.OrderId,
+		CreatedAt: timestamppb.New(transaction.CreatedAt),
+	}
+
+	account := model.LoyaltyAccount(transaction.Username)
+	for _, entry := range transaction.Entries {
+		response.Entries = append(response.Entries, &o.LoyaltyEntry{Account: entry.Account, Points: entry.Points})
+		if entry.Account == account {
+			response.Points += entry.Points
+		}
+	}
+
+	if transaction.ExpiresAt != nil {
+		response.ExpiresAt = timestamppb.New(*transaction.ExpiresAt)
+	}
+
+	return response
+}
+
+// loyaltyLots returns what is left of the points a user earned, oldest
+// first, including those that expired but weren't written off yet.
+func loyaltyLots(db *gorm.DB, username string) ([]loyalty.Lot, error) {
+	balance, err := database.GetLoyaltyBalance(db, username)
+	if err != nil {
+		return nil, err
+	}
+
+	earned, err := database.ListLoyaltyLots(db, username)
+	if err != nil {
+		return nil, err
+	}
+
+	lots := make([]loyalty.Lot, 0, len(earned))
+	for _, transaction := range earned {
+		if transaction.ExpiresAt != nil {
+			lots = append(lots, loyalty.Lot{Points: transaction.Points, ExpiresAt: *transaction.ExpiresAt})
+		}
+	}
+
+	return loyalty.Remaining(lots, balance), nil
+}
+
+// availableLoyaltyPoints is the user's balance less the points that expired.
+func availableLoyaltyPoints(db *gorm.DB, username string, now time.Time) (int64, error) {
+	lots, err := loyaltyLots(db, username)
+	if err != nil {
+		return 0, err
+	}
+
+	var available int64
+	for _, lot := range lots {
+		if lot.ExpiresAt.After(now) {
+			available += lot.Points
+		}
+	}
+
+	return available, nil
+}
+
+// settleLoyaltyPoints writes off the user's points that expired by now and
+// returns the lots left. The caller holds database.LockUser.
+func settleLoyaltyPoints(tx *gorm.DB, username string, now time.Time) ([]loyalty.Lot, error) {
+	lots, err := loyaltyLots(tx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	var expired int64
+	for len(lots) > 0 && !lots[0].ExpiresAt.After(now) {
+		expired += lots[0].Points
+		lots = lots[1:]
+	}
+
+	if expired > 0 {
+		err := database.RecordLoyaltyTransaction(tx, model.LoyaltyTransfer(model.LoyaltyExpiry, username, 0, expired, model.LoyaltyAccount(username), model.LoyaltyExpiredAccount))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return lots, nil
+}
+
+// redeemLoyaltyPoints spends the points an order was priced with. It runs in
+// the transaction that stores the order, and checks the balance again under
+// the user's lock so that points can't be spent twice.
+func redeemLoyaltyPoints(tx *gorm.DB, order *model.Order, now time.Time) error {
+	if _, err := database.LockUser(tx, order.Username); err != nil {
+		return err
+	}
+
+	lots, err := settleLoyaltyPoints(tx, order.Username, now)
+	if err != nil {
+		return err
+	}
+
+	var available int64
+	for _, lot := range lots {
+		available += lot.Points
+	}
+
+	if available < order.LoyaltyPointsRedeemed {
+		return errInsufficientLoyaltyPoints(order.LoyaltyPointsRedeemed, available)
+	}
+
+	return database.RecordLoyaltyTransaction(tx, model.LoyaltyTransfer(model.LoyaltyRedeem, order.Username, order.Id, order.LoyaltyPointsRedeemed,
+		model.LoyaltyAccount(order.Username), model.LoyaltyRedeemedAccount))
+}
+
+// earnLoyaltyPoints credits the points a delivered order earns on what was
+// paid for it, tip included. The part paid with store credit earns nothing,
+// as the credit was not paid for.
+func (orderServer *OrderServiceServer) earnLoyaltyPoints(tx *gorm.DB, order *model.Order, now time.Time) error {
+	points := orderServer.Loyalty.Earned(money.New(order.TotalAmount-order.WalletAmount, order.Currency))
+	if points == 0 {
+		return nil
+	}
+
+	expiresAt := orderServer.Loyalty.ExpiresAt(now)
+	transaction := model.LoyaltyTransfer(model.LoyaltyEarn, order.Username, order.Id, points, model.LoyaltyIssuedAccount, model.LoyaltyAccount(order.Username))
+	transaction.ExpiresAt = &expiresAt
+
+	return database.RecordLoyaltyTransaction(tx, transaction)
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"orderService.com/go-orderService-grpc/loyalty"
+	"orderService.com/go-orderService-grpc/pricing"
+	o "orderService.com/go-orderService-grpc/proto/order"
+)
+
+func testLoyaltyProgram() *loyalty.Program {
+	return loyalty.NewProgram(loyalty.Config{PointsPerUnit: 1, PointValue: 25, ExpiryDays: 365})
+}
+
+type loyaltyLot struct {
+	points    int64
+	expiresAt time.Time
+}
+
+// expectLoyaltyLots answers the balance and earned points lookups of a user.
+func expectLoyaltyLots(mock sqlmock.Sqlmock, username string, balance int64, lots ...loyaltyLot) {
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COALESCE(SUM(points), 0) FROM "loyalty_entries" WHERE account = $1`)).
+		WithArgs("customer:" + username).WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(balance))
+
+	rows := sqlmock.NewRows([]string{"id", "username", "kind", "points", "expires_at"})
+	for i, lot := range lots {
+		rows.AddRow(i+1, username, "EARN", lot.points, lot.expiresAt)
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "loyalty_transactions" WHERE username = $1 AND kind = $2 ORDER BY expires_at, id`)).
+		WithArgs(username, "EARN").WillReturnRows(rows)
+}
+
+func expectUserLock(mock sqlmock.Sqlmock, username string) {
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE username = $1`)+`.*FOR UPDATE`).WithArgs(username, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(1, username))
+}
+
+// expectLoyaltyTransfer expects a transaction moving points from one account
+// to another.
+func expectLoyaltyTransfer(mock sqlmock.Sqlmock, kind string, orderId int64, points int64, expiresAt any, from string, to string) {
+	mock.ExpectQuery(`INSERT INTO "loyalty_transactions"`).
+		WithArgs("username", kind, orderId, points, expiresAt, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectQuery(`INSERT INTO "loyalty_entries"`).
+		WithArgs(3, from, -points, 3, to, points).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5).AddRow(6))
+}
+
+func TestCreateOrder_RedeemsLoyaltyPoints(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	catalog := newCatalogServer(t, "1", map[string]menuItemFixture{"Naan": {Price: "40", Currency: "INR"}})
+	expiresAt := time.Now().Add(24 * time.Hour)
+
+	orderServiceServer := &OrderServiceServer{
+		DB:                    gormDb,
+		CatalogServiceAPI:     catalog.URL + "/restaurants/",
+		FulfillmentServiceAPI: httpServerThatFails(t),
+		Pricing:               pricing.NewEngine(pricing.Config{}),
+		Loyalty:               testLoyaltyProgram(),
+	}
+
+	expectUserLookup(t, mock, "username", "password")
+	expectLoyaltyLots(mock, "username", 500, loyaltyLot{500, expiresAt})
+	mock.ExpectBegin()
+	expectOrderInsert(mock, 7, orderColumns{
+		"discount_amount":         2500,
+		"total_amount":            5500,
+		"loyalty_points_redeemed": 100,
+		"loyalty_discount_amount": 2500,
+	})
+	mock.ExpectQuery(`INSERT INTO "order_items"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectUserLock(mock, "username")
+	expectLoyaltyLots(mock, "username", 500, loyaltyLot{500, expiresAt})
+	expectLoyaltyTransfer(mock, "REDEEM", 7, 100, nil, "customer:username", "loyalty:redeemed")
+	mock.ExpectCommit()
+
+	response, err := orderServiceServer.Create(basicAuthContext("username", "password"), &o.CreateOrderRequest{
+		RestaurantId: "1",
+		MenuItems:    map[string]int32{"Naan": 2},
+		RedeemPoints: 100,
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(100), response.LoyaltyPointsRedeemed)
+	assert.Equal(t, &o.Money{CurrencyCode: "INR", AmountMinor: 2500}, response.Breakdown.Discount)
+	assert.Equal(t, &o.Money{CurrencyCode: "INR", AmountMinor: 5500}, response.Total)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateOrder_WithPromoCodeAndLoyaltyPoints_RedeemsOnlyPromotionDiscount(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	catalog := newCatalogServer(t, "1", map[string]menuItemFixture{"Naan": {Price: "40", Currency: "INR"}})
+	expiresAt := time.Now().Add(24 * time.Hour)
+
+	orderServiceServer := &OrderServiceServer{
+		DB:                    gormDb,
+		CatalogServiceAPI:     catalog.URL + "/restaurants/",
+		FulfillmentServiceAPI: httpServerThatFails(t),
+		Pricing:               pricing.NewEngine(pricing.Config{}),
+		Loyalty:               testLoyaltyProgram(),
+	}
+
+	promotionColumns := []string{"id", "code", "kind", "currency", "percent_off_basis_points"}
+
+	expectUserLookup(t, mock, "username", "password")
+	mock.ExpectQuery(`SELECT \* FROM "promotions" WHERE code`).
+		WillReturnRows(sqlmock.NewRows(promotionColumns).AddRow(3, "WELCOME10", "PERCENTAGE_OFF", "INR", 1000))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "orders"`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "promotion_redemptions"`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	expectLoyaltyLots(mock, "username", 500, loyaltyLot{500, expiresAt})
+	mock.ExpectBegin()
+	expectOrderInsert(mock, 7, orderColumns{
+		"subtotal_amount":           20000,
+		"discount_amount":           4500,
+		"promo_code":                "WELCOME10",
+		"total_amount":              15500,
+		"loyalty_points_redeemed":   100,
+		"loyalty_discount_amount":   2500,
+		"promotion_discount_amount": 2000,
+	})
+	mock.ExpectQuery(`INSERT INTO "order_items"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(`FOR UPDATE`).
+		WillReturnRows(sqlmock.NewRows(promotionColumns).AddRow(3, "WELCOME10", "PERCENTAGE_OFF", "INR", 1000))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "promotion_redemptions"`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec(`UPDATE "promotions" SET "usage_count"`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO "promotion_redemptions"`).WithArgs(3, "username", 7, 2000, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectUserLock(mock, "username")
+	expectLoyaltyLots(mock, "username", 500, loyaltyLot{500, expiresAt})
+	expectLoyaltyTransfer(mock, "REDEEM", 7, 100, nil, "customer:username", "loyalty:redeemed")
+	mock.ExpectCommit()
+
+	response, err := orderServiceServer.Create(basicAuthContext("username", "password"), &o.CreateOrderRequest{
+		RestaurantId: "1",
+		MenuItems:    map[string]int32{"Naan": 5},
+		PromoCode:    "WELCOME10",
+		RedeemPoints: 100,
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, &o.Money{CurrencyCode: "INR", AmountMinor: 4500}, response.Breakdown.Discount)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateOrder_ExpiredLoyaltyPoints_AreNotEnough(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	catalog := newCatalogServer(t, "1", map[string]menuItemFixture{"Naan": {Price: "40", Currency: "INR"}})
+
+	orderServiceServer := &OrderServiceServer{
+		DB:                gormDb,
+		CatalogServiceAPI: catalog.URL + "/restaurants/",
+		Loyalty:           testLoyaltyProgram(),
+	}
+
+	expectUserLookup(t, mock, "username", "password")
+	expectLoyaltyLots(mock, "username", 500, loyaltyLot{300, time.Now().Add(-time.Hour)}, loyaltyLot{200, time.Now().Add(time.Hour)})
+
+	_, err := orderServiceServer.Create(basicAuthContext("username", "password"), &o.CreateOrderRequest{
+		RestaurantId: "1",
+		MenuItems:    map[string]int32{"Naan": 2},
+		RedeemPoints: 300,
+	})
+
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.Equal(t, ReasonInsufficientLoyaltyPoints, errorInfoOf(t, err).Reason)
+	assert.Equal(t, "200", errorInfoOf(t, err).Metadata["available"])
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestFulfillmentWebhook_Delivered_EarnsLoyaltyPoints(t *testing.T) {
+	mock, webhook, _ := newTestWebhook(t)
+	webhook.Orders.Loyalty = testLoyaltyProgram()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "orders" WHERE id = $1`)+`.*FOR UPDATE`).WithArgs(7, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "currency", "total_amount", "status"}).AddRow(7, "username", "INR", 66005, "PICKED_UP"))
+	expectLoyaltyTransfer(mock, "EARN", 7, 660, webhookNow.AddDate(1, 0, 0), "loyalty:issued", "customer:username")
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "orders" SET "status"=$1`)).WithArgs("DELIVERED", nil, "", "", "", 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.Equal(t, http.StatusNoContent, serve(webhook, signedCallback("secret", webhookNow, `{"orderId":7,"status":"DELIVERED"}`)))
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestFulfillmentWebhook_Delivered_EarnsNothingOnStoreCredit(t *testing.T) {
+	mock, webhook, _ := newTestWebhook(t)
+	webhook.Orders.Loyalty = testLoyaltyProgram()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "orders" WHERE id = $1`)+`.*FOR UPDATE`).WithArgs(7, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "currency", "total_amount", "wallet_amount", "status"}).AddRow(7, "username", "INR", 66005, 50000, "PICKED_UP"))
+	expectLoyaltyTransfer(mock, "EARN", 7, 160, webhookNow.AddDate(1, 0, 0), "loyalty:issued", "customer:username")
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "orders" SET "status"=$1`)).WithArgs("DELIVERED", nil, "", "", "", 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.Equal(t, http.StatusNoContent, serve(webhook, signedCallback("secret", webhookNow, `{"orderId":7,"status":"DELIVERED"}`)))
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestRejectOrder_GivesBackLoyaltyPoints(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	restaurantServer := &RestaurantServiceServer{Orders: &OrderServiceServer{DB: gormDb}}
+
+	expectUserLookup(t, mock, "chef", "password")
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "orders" WHERE id = $1`)+`.*FOR UPDATE`).WithArgs(7, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "restaurant_id", "username", "status", "loyalty_points_redeemed"}).AddRow(7, "1", "username", "AWAITING_ACCEPTANCE", 100))
+	expectOwnership(mock, "chef", true)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "promotion_redemptions" WHERE order_id = $1`)).WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	expectLoyaltyTransfer(mock, "REVERSAL", 7, 100, nil, "loyalty:redeemed", "customer:username")
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "orders" SET "status"=$1,"cancellation_reason"=$2 WHERE "id" = $3`)).
+		WithArgs("REJECTED", "Out of paneer", 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectOrderLookup(mock, "username", "REJECTED", "")
+
+	_, err := restaurantServer.RejectOrder(basicAuthContext("chef", "password"), &o.RejectOrderRequest{OrderId: 7, Reason: "Out of paneer"})
+
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestGetLoyaltyBalance_WritesOffExpiredPoints(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	loyaltyServer := &LoyaltyServiceServer{Orders: &OrderServiceServer{DB: gormDb}}
+	expired := time.Now().Add(-time.Hour)
+	expiresAt := time.Now().Add(30 * 24 * time.Hour).Truncate(time.Second)
+
+	expectUserLookup(t, mock, "username", "password")
+	mock.ExpectBegin()
+	expectUserLock(mock, "username")
+	// 100 of the 200 points that expired were spent.
+	expectLoyaltyLots(mock, "username", 400, loyaltyLot{200, expired}, loyaltyLot{300, expiresAt})
+	expectLoyaltyTransfer(mock, "EXPIRY", 0, 100, nil, "customer:username", "loyalty:expired")
+	mock.ExpectCommit()
+
+	balance, err := loyaltyServer.GetLoyaltyBalance(basicAuthContext("username", "password"), &o.GetLoyaltyBalanceRequest{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, &o.LoyaltyBalance{Points: 300, ExpiringPoints: 300, ExpiresAt: timestamppb.New(expiresAt)}, balance)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestListLoyaltyTransactions_PagesThroughLedger(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	loyaltyServer := &LoyaltyServiceServer{Orders: &OrderServiceServer{DB: gormDb}}
+	createdAt := time.Date(2024, 3, 10, 20, 0, 0, 0, time.UTC)
+
+	expectUserLookup(t, mock, "username", "password")
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "loyalty_transactions" WHERE username = $1 AND id < $2 ORDER BY id DESC LIMIT $3`)).
+		WithArgs("username", 9, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "kind", "order_id", "points", "created_at"}).
+			AddRow(8, "username", "REDEEM", 7, 100, createdAt).
+			AddRow(5, "username", "REVERSAL", 4, 40, createdAt))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "loyalty_entries" WHERE "loyalty_entries"."transaction_id" IN ($1,$2) ORDER BY id`)).
+		WithArgs(8, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "transaction_id", "account", "points"}).
+			AddRow(1, 5, "loyalty:redeemed", -40).AddRow(2, 5, "customer:username", 40).
+			AddRow(3, 8, "customer:username", -100).AddRow(4, 8, "loyalty:redeemed", 100))
+
+	response, err := loyaltyServer.ListLoyaltyTransactions(basicAuthContext("username", "password"), &o.ListLoyaltyTransactionsRequest{PageSize: 2, BeforeId: 9})
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), response.NextBeforeId)
+	assert.Equal(t, &o.LoyaltyTransaction{
+		Id:        8,
+		Kind:      o.LoyaltyTransactionKind_LOYALTY_REDEEM,
+		Points:    -100,
+		OrderId:   7,
+		CreatedAt: timestamppb.New(createdAt),
+		Entries:   []*o.LoyaltyEntry{{Account: "customer:username", Points: -100}, {Account: "loyalty:redeemed", Points: 100}},
+	}, response.Transactions[0])
+	assert.Equal(t, int64(40), response.Transactions[1].Points)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
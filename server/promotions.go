@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	database "orderService.com/go-orderService-grpc/db"
+	"orderService.com/go-orderService-grpc/model"
+	"orderService.com/go-orderService-grpc/money"
+	"orderService.com/go-orderService-grpc/promotions"
+)
+
+// applyPromotion looks up a promo code and works out the discount it gives
+// the order. It returns a nil promotion when no code was given.
+func applyPromotion(db *gorm.DB, code string, username string, restaurantId string, items []model.OrderItem, subtotal money.Money) (*model.Promotion, promotions.Discount, error) {
+	code = promotions.NormalizeCode(code)
+	if code == "" {
+		return nil, promotions.Discount{}, nil
+	}
+
+	promotion, err := database.GetPromotionByCode(db, code)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, promotions.Discount{}, errPromoCodeNotFound(code)
+	}
+
+	if err != nil {
+		return nil, promotions.Discount{}, err
+	}
+
+	previousOrders, err := database.CountOrdersByUsername(db, username)
+	if err != nil {
+		return nil, promotions.Discount{}, err
+	}
+
+	userRedemptions, err := database.CountPromotionRedemptions(db, promotion.Id, username)
+	if err != nil {
+		return nil, promotions.Discount{}, err
+	}
+
+	lines := make([]promotions.Line, 0, len(items))
+	for _, item := range items {
+		lines = append(lines, promotions.Line{MenuItem: item.Key(), Quantity: item.Quantity, UnitAmount: item.UnitAmount})
+	}
+
+	discount, err := promotions.Evaluate(*promotion, promotions.Cart{
+		RestaurantId:    restaurantId,
+		Subtotal:        subtotal,
+		Lines:           lines,
+		Now:             time.Now(),
+		PreviousOrders:  previousOrders,
+		UserRedemptions: userRedemptions,
+	})
+	if err != nil {
+		return nil, promotions.Discount{}, errPromotionNotApplicable(code, err)
+	}
+
+	return promotion, discount, nil
+}
+
+// redeemPromotion runs inside the transaction that stores the order. The
+// promotion row stays locked until that transaction ends, so usage limits and
+// first-order-only promotions hold even when several orders redeem the same
+// code at once. The redemption records only the promotion's own discount.
+func redeemPromotion(tx *gorm.DB, promotionId int64, order *model.Order) error {
+	promotion, err := database.LockPromotion(tx, promotionId)
+	if err != nil {
+		return err
+	}
+
+	userRedemptions, err := database.CountPromotionRedemptions(tx, promotionId, order.Username)
+	if err != nil {
+		return err
+	}
+
+	if err := promotions.CheckUsage(*promotion, userRedemptions); err != nil {
+		return errPromotionNotApplicable(promotion.Code, err)
+	}
+
+	if promotion.FirstOrderOnly {
+		// The order itself is already stored in the transaction.
+		orders, err := database.CountOrdersByUsername(tx, order.Username)
+		if err != nil {
+			return err
+		}
+
+		if orders > 1 {
+			return errPromotionNotApplicable(promotion.Code, promotions.ErrFirstOrderOnly)
+		}
+	}
+
+	return database.RecordRedemption(tx, &model.PromotionRedemption{
+		PromotionId:    promotionId,
+		Username:       order.Username,
+		OrderId:        order.Id,
+		DiscountAmount: order.PromotionDiscountAmount,
+	})
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"maps"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"orderService.com/go-orderService-grpc/model"
+	"orderService.com/go-orderService-grpc/promotions"
+	o "orderService.com/go-orderService-grpc/proto/order"
+	"orderService.com/go-orderService-grpc/quotes"
+)
+
+// QuoteOrder prices an order exactly like Create would, without storing it or
+// requesting a delivery, and returns a signed token that Create accepts to
+// place the order at the quoted price.
+func (orderServer *OrderServiceServer) QuoteOrder(ctx context.Context, req *o.QuoteOrderRequest) (*o.QuoteOrderResponse, error) {
+	user, err := orderServer.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if orderServer.Quotes == nil {
+		return nil, errInternal("quote signing is not configured")
+	}
+
+	priced, err := orderServer.priceOrder(user, &o.CreateOrderRequest{
+		RestaurantId: req.RestaurantId,
+		MenuItems:    req.MenuItems,
+		Lines:        req.Lines,
+		Tip:          req.Tip,
+		PromoCode:    req.PromoCode,
+		RedeemPoints: req.RedeemPoints,
+	})
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	if err := orderServer.checkServiceability(req.RestaurantId, priced.restaurantAddress, user.Address); err != nil {
+		return nil, toStatusError(err)
+	}
+
+	if err := orderServer.checkLimits(req.RestaurantId, priced); err != nil {
+		return nil, toStatusError(err)
+	}
+
+	token, quote, err := orderServer.Quotes.Sign(quotes.Quote{
+		Username:     user.Username,
+		RestaurantId: req.RestaurantId,
+		MenuItems:    req.MenuItems,
+		Lines:        quoteLines(req.Lines),
+		PromoCode:    priced.promoCode,
+		PromotionId:  priced.promotionId,
+		Items:        priced.items,
+		Breakdown:    priced.breakdown,
+
+		PromotionDiscount: priced.promotionDiscount,
+
+		RedeemPoints:    req.RedeemPoints,
+		LoyaltyPoints:   priced.loyaltyPoints,
+		LoyaltyDiscount: priced.loyaltyDiscount,
+	})
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	response := &o.QuoteOrderResponse{
+		RestaurantId: req.RestaurantId,
+		MenuItems:    req.MenuItems,
+		LineItems:    toLineItems(priced.items, priced.breakdown.Total.Currency),
+		Breakdown:    toProtoBreakdown(priced.breakdown),
+		Total:        toProtoMoney(priced.breakdown.Total),
+		PromoCode:    priced.promoCode,
+		QuoteToken:   token,
+		ExpiresAt:    timestamppb.New(quote.ExpiresAt),
+		Lines:        req.Lines,
+
+		LoyaltyPointsRedeemed: priced.loyaltyPoints,
+	}
+
+	return response, nil
+}
+
+// pricedFromQuote honours the price of a quote, provided the request asks for
+// exactly what was quoted. The restaurant is fetched again since the
+// fulfillment request needs its address and the order its opening hours, and
+// the items are checked to still be available, whatever they cost now.
+func (orderServer *OrderServiceServer) pricedFromQuote(user *model.User, req *o.CreateOrderRequest) (*pricedOrder, error) {
+	if orderServer.Quotes == nil {
+		return nil, errInternal("quote signing is not configured")
+	}
+
+	quote, err := orderServer.Quotes.Verify(req.QuoteToken)
+	if errors.Is(err, quotes.ErrExpired) {
+		return nil, errQuoteExpired()
+	}
+
+	if err != nil {
+		return nil, errQuoteInvalid(err.Error())
+	}
+
+	tip := fromProtoMoney(req.Tip)
+	switch {
+	case quote.Username != user.Username:
+		return nil, errQuoteInvalid("quote belongs to another user")
+	case quote.RestaurantId != req.RestaurantId || !maps.Equal(quote.MenuItems, req.MenuItems) || !sameLines(quote.Lines, req.Lines):
+		return nil, errQuoteMismatch("menu_items", "items differ from the quote")
+	case promotions.NormalizeCode(req.PromoCode) != quote.PromoCode:
+		return nil, errQuoteMismatch("promo_code", "promo code differs from the quote")
+	case tip.Amount != quote.Breakdown.Tip.Amount || (tip.Currency != "" && tip.Currency != quote.Breakdown.Tip.Currency):
+		return nil, errQuoteMismatch("tip", "tip differs from the quote")
+	case req.RedeemPoints != quote.RedeemPoints:
+		return nil, errQuoteMismatch("redeem_points", "loyalty points differ from the quote")
+	}
+
+	if _, _, err := calculateOrderTotal(req, orderServer.CatalogServiceAPI); err != nil {
+		return nil, err
+	}
+
+	restaurant, err := fetchRestaurant(req.RestaurantId, orderServer.CatalogServiceAPI)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pricedOrder{
+		items:             quote.Items,
+		breakdown:         quote.Breakdown,
+		promotionId:       quote.PromotionId,
+		promoCode:         quote.PromoCode,
+		promotionDiscount: quote.PromotionDiscount,
+		loyaltyPoints:     quote.LoyaltyPoints,
+		loyaltyDiscount:   quote.LoyaltyDiscount,
+		restaurantAddress: restaurant.Address,
+		restaurantHours:   restaurant.Hours,
+	}, nil
+}
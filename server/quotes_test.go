@@ -0,0 +1,128 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"orderService.com/go-orderService-grpc/pricing"
+	o "orderService.com/go-orderService-grpc/proto/order"
+	"orderService.com/go-orderService-grpc/quotes"
+)
+
+func TestQuoteOrder_CreateHonoursQuotedPrice(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	catalog := newCatalogServer(t, "1", map[string]menuItemFixture{"Naan": {Price: "40", Currency: "INR"}})
+	fulfillment := newFulfillmentServer(t, http.StatusCreated)
+
+	orderServiceServer := &OrderServiceServer{
+		DB:                    gormDb,
+		CatalogServiceAPI:     catalog.URL + "/restaurants/",
+		FulfillmentServiceAPI: fulfillment.URL,
+		Pricing:               pricing.NewEngine(pricing.Config{DefaultBasisPoints: 500}),
+		Quotes:                quotes.NewSigner([]byte("secret"), 5*time.Minute),
+	}
+
+	expectUserLookup(t, mock, "username", "password")
+	quote, err := orderServiceServer.QuoteOrder(basicAuthContext("username", "password"), &o.QuoteOrderRequest{
+		RestaurantId: "1",
+		MenuItems:    map[string]int32{"Naan": 2},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, &o.Money{CurrencyCode: "INR", AmountMinor: 8400}, quote.Total)
+	assert.NotEmpty(t, quote.QuoteToken)
+	assert.Nil(t, mock.ExpectationsWereMet())
+
+	// The catalog price changes after the quote; the order keeps the quoted one.
+	orderServiceServer.CatalogServiceAPI = newCatalogServer(t, "1", map[string]menuItemFixture{"Naan": {Price: "60", Currency: "INR"}}).URL + "/restaurants/"
+
+	expectUserLookup(t, mock, "username", "password")
+	mock.ExpectBegin()
+	expectOrderInsert(mock, 7, orderColumns{
+		"tax_amount":       400,
+		"tax_basis_points": 500,
+		"total_amount":     8400,
+	})
+	mock.ExpectQuery(`INSERT INTO "order_items"`).WithArgs(7, "id-Naan", "House Naan", "Naan", 2, 4000, 8000, nil, "").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	response, err := orderServiceServer.Create(basicAuthContext("username", "password"), &o.CreateOrderRequest{
+		RestaurantId: "1",
+		MenuItems:    map[string]int32{"Naan": 2},
+		QuoteToken:   quote.QuoteToken,
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, quote.Total, response.Total)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateOrder_RejectsUnusableQuotes(t *testing.T) {
+	signer := quotes.NewSigner([]byte("secret"), 5*time.Minute)
+	token, _, err := signer.Sign(quotes.Quote{Username: "username", RestaurantId: "1", MenuItems: map[string]int32{"Naan": 2}})
+	assert.Nil(t, err)
+
+	expiredToken, _, err := quotes.NewSigner([]byte("secret"), -time.Minute).Sign(quotes.Quote{Username: "username", RestaurantId: "1", MenuItems: map[string]int32{"Naan": 2}})
+	assert.Nil(t, err)
+
+	tests := []struct {
+		name   string
+		req    *o.CreateOrderRequest
+		code   codes.Code
+		reason string
+	}{
+		{
+			name:   "Tampered Token",
+			req:    &o.CreateOrderRequest{RestaurantId: "1", MenuItems: map[string]int32{"Naan": 2}, QuoteToken: "x" + token},
+			code:   codes.InvalidArgument,
+			reason: ReasonQuoteInvalid,
+		},
+		{
+			name:   "Expired Token",
+			req:    &o.CreateOrderRequest{RestaurantId: "1", MenuItems: map[string]int32{"Naan": 2}, QuoteToken: expiredToken},
+			code:   codes.FailedPrecondition,
+			reason: ReasonQuoteExpired,
+		},
+		{
+			name:   "Different Items",
+			req:    &o.CreateOrderRequest{RestaurantId: "1", MenuItems: map[string]int32{"Naan": 3}, QuoteToken: token},
+			code:   codes.InvalidArgument,
+			reason: ReasonQuoteMismatch,
+		},
+		{
+			name: "Different Lines",
+			req: &o.CreateOrderRequest{RestaurantId: "1", MenuItems: map[string]int32{"Naan": 2}, QuoteToken: token,
+				Lines: []*o.OrderLine{{MenuItemId: "Naan", Quantity: 1, Instructions: "well done"}}},
+			code:   codes.InvalidArgument,
+			reason: ReasonQuoteMismatch,
+		},
+		{
+			name:   "Different Tip",
+			req:    &o.CreateOrderRequest{RestaurantId: "1", MenuItems: map[string]int32{"Naan": 2}, Tip: &o.Money{AmountMinor: 100}, QuoteToken: token},
+			code:   codes.InvalidArgument,
+			reason: ReasonQuoteMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock, gormDb := openMockDB(t)
+			expectUserLookup(t, mock, "username", "password")
+
+			orderServiceServer := &OrderServiceServer{DB: gormDb, Quotes: signer}
+
+			response, err := orderServiceServer.Create(basicAuthContext("username", "password"), tt.req)
+
+			assert.Nil(t, response)
+			assert.Equal(t, tt.code, status.Code(err))
+			assert.Equal(t, tt.reason, errorInfoOf(t, err).Reason)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"orderService.com/go-orderService-grpc/model"
+	"orderService.com/go-orderService-grpc/money"
+	o "orderService.com/go-orderService-grpc/proto/order"
+)
+
+func (orderServer *OrderServiceServer) Reorder(ctx context.Context, req *o.ReorderRequest) (*o.ReorderResponse, error) {
+	user, err := orderServer.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	previous, err := orderServer.ownedOrder(user, req.OrderId)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &o.ReorderResponse{}
+	var lines []*o.OrderLine
+
+	// Each line is asked for again with the same options and instructions.
+	// Lines whose item or options are gone, or whose item is out of stock for
+	// the quantity, are unavailable.
+	for i, line := range linesOf(previous.Items) {
+		item := previous.Items[i]
+		previousPrice := money.New(item.UnitAmount, previous.Currency)
+
+		menuItem, err := fetchMenuItem(orderServer.CatalogServiceAPI, previous.RestaurantId, item.Key())
+		unavailable := status.Code(err) == codes.NotFound ||
+			(err == nil && (!menuItem.Available || (menuItem.Stock != nil && *menuItem.Stock < item.Quantity)))
+		if err != nil && !unavailable {
+			return nil, toStatusError(err)
+		}
+
+		var current model.OrderItem
+		if !unavailable {
+			var violation *errdetails.BadRequest_FieldViolation
+			current, violation = menuItem.orderItem(orderLine{menuItem: line.MenuItemId, quantity: line.Quantity, optionIds: line.ModifierOptionIds, instructions: line.Instructions})
+			unavailable = violation != nil
+		}
+
+		if unavailable {
+			response.Changes = append(response.Changes, &o.ReorderItemChange{
+				Name:              item.MenuItemName,
+				Quantity:          item.Quantity,
+				Kind:              o.ReorderItemChange_UNAVAILABLE,
+				PreviousUnitPrice: toProtoMoney(previousPrice),
+			})
+			continue
+		}
+
+		price := money.New(current.UnitAmount, menuItem.Price.Currency)
+		if price != previousPrice {
+			response.Changes = append(response.Changes, &o.ReorderItemChange{
+				Name:              item.MenuItemName,
+				Quantity:          item.Quantity,
+				Kind:              o.ReorderItemChange_PRICE_CHANGED,
+				PreviousUnitPrice: toProtoMoney(previousPrice),
+				CurrentUnitPrice:  toProtoMoney(price),
+			})
+		}
+
+		lines = append(lines, line)
+	}
+
+	if len(lines) == 0 || (len(response.Changes) > 0 && !req.AcceptChanges) {
+		return response, nil
+	}
+
+	response.Order, err = orderServer.placeOrder(user, &o.CreateOrderRequest{
+		RestaurantId: previous.RestaurantId,
+		Lines:        lines,
+		Tip:          req.Tip,
+		PromoCode:    req.PromoCode,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"orderService.com/go-orderService-grpc/pricing"
+	o "orderService.com/go-orderService-grpc/proto/order"
+)
+
+func expectPreviousOrder(mock sqlmock.Sqlmock, username string) {
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "orders" WHERE id = $1`)).WithArgs(7, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "restaurant_id", "username", "currency"}).AddRow(7, "1", username, "INR"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "order_items" WHERE "order_items"."order_id" = $1`)).WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "menu_item_name", "menu_item_key", "quantity", "unit_amount", "line_amount"}).
+			AddRow(1, 7, "House Naan", "Naan", 2, 4000, 8000).
+			AddRow(2, 7, "House Paneer Tikka", "Paneer Tikka", 1, 25000, 25000))
+}
+
+func newReorderServer(t *testing.T, menuItems map[string]menuItemFixture) (sqlmock.Sqlmock, *OrderServiceServer) {
+	mock, gormDb := openMockDB(t)
+	catalog := newCatalogServer(t, "1", menuItems)
+	fulfillment := newFulfillmentServer(t, http.StatusCreated)
+
+	return mock, &OrderServiceServer{
+		DB:                    gormDb,
+		CatalogServiceAPI:     catalog.URL + "/restaurants/",
+		FulfillmentServiceAPI: fulfillment.URL,
+		Pricing:               pricing.NewEngine(pricing.Config{}),
+	}
+}
+
+func TestReorder_OrderOfAnotherUser_ReturnsNotFound(t *testing.T) {
+	mock, orderServiceServer := newReorderServer(t, nil)
+
+	expectUserLookup(t, mock, "username", "password")
+	expectPreviousOrder(mock, "someone else")
+
+	response, err := orderServiceServer.Reorder(basicAuthContext("username", "password"), &o.ReorderRequest{OrderId: 7})
+
+	assert.Nil(t, response)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Equal(t, ReasonOrderNotFound, errorInfoOf(t, err).Reason)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestReorder_ChangedItems_ReturnsDiffWithoutOrdering(t *testing.T) {
+	mock, orderServiceServer := newReorderServer(t, map[string]menuItemFixture{"Naan": {Price: "45", Currency: "INR"}})
+
+	expectUserLookup(t, mock, "username", "password")
+	expectPreviousOrder(mock, "username")
+
+	response, err := orderServiceServer.Reorder(basicAuthContext("username", "password"), &o.ReorderRequest{OrderId: 7})
+
+	assert.Nil(t, err)
+	assert.Nil(t, response.Order)
+	assert.Equal(t, []*o.ReorderItemChange{
+		{
+			Name:              "House Naan",
+			Quantity:          2,
+			Kind:              o.ReorderItemChange_PRICE_CHANGED,
+			PreviousUnitPrice: &o.Money{CurrencyCode: "INR", AmountMinor: 4000},
+			CurrentUnitPrice:  &o.Money{CurrencyCode: "INR", AmountMinor: 4500},
+		},
+		{
+			Name:              "House Paneer Tikka",
+			Quantity:          1,
+			Kind:              o.ReorderItemChange_UNAVAILABLE,
+			PreviousUnitPrice: &o.Money{CurrencyCode: "INR", AmountMinor: 25000},
+		},
+	}, response.Changes)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestReorder_AcceptChanges_PlacesAvailableItems(t *testing.T) {
+	mock, orderServiceServer := newReorderServer(t, map[string]menuItemFixture{"Naan": {Price: "45", Currency: "INR"}})
+
+	expectUserLookup(t, mock, "username", "password")
+	expectPreviousOrder(mock, "username")
+	mock.ExpectBegin()
+	expectOrderInsert(mock, 8, orderColumns{
+		"subtotal_amount": 9000,
+		"total_amount":    9000,
+	})
+	mock.ExpectQuery(`INSERT INTO "order_items"`).WithArgs(8, "id-Naan", "House Naan", "Naan", 2, 4500, 9000, nil, "").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectCommit()
+
+	response, err := orderServiceServer.Reorder(basicAuthContext("username", "password"), &o.ReorderRequest{OrderId: 7, AcceptChanges: true})
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(8), response.Order.Id)
+	assert.Len(t, response.Changes, 2)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestReorder_ItemFlaggedUnavailable_IsReportedAsUnavailable(t *testing.T) {
+	mock, orderServiceServer := newReorderServer(t, map[string]menuItemFixture{
+		"Naan":         {Price: "40", Currency: "INR"},
+		"Paneer Tikka": {Price: "250", Currency: "INR", Extra: `,"available":false`},
+	})
+
+	expectUserLookup(t, mock, "username", "password")
+	expectPreviousOrder(mock, "username")
+
+	response, err := orderServiceServer.Reorder(basicAuthContext("username", "password"), &o.ReorderRequest{OrderId: 7})
+
+	assert.Nil(t, err)
+	assert.Nil(t, response.Order)
+	assert.Equal(t, []*o.ReorderItemChange{{
+		Name:              "House Paneer Tikka",
+		Quantity:          1,
+		Kind:              o.ReorderItemChange_UNAVAILABLE,
+		PreviousUnitPrice: &o.Money{CurrencyCode: "INR", AmountMinor: 25000},
+	}}, response.Changes)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestReorder_ItemOutOfStockForQuantity_IsReportedAsUnavailable(t *testing.T) {
+	mock, orderServiceServer := newReorderServer(t, map[string]menuItemFixture{
+		"Naan":         {Price: "40", Currency: "INR", Extra: `,"stock":1`},
+		"Paneer Tikka": {Price: "250", Currency: "INR", Extra: `,"stock":1`},
+	})
+
+	expectUserLookup(t, mock, "username", "password")
+	expectPreviousOrder(mock, "username")
+
+	response, err := orderServiceServer.Reorder(basicAuthContext("username", "password"), &o.ReorderRequest{OrderId: 7})
+
+	assert.Nil(t, err)
+	assert.Nil(t, response.Order)
+	assert.Equal(t, []*o.ReorderItemChange{{
+		Name:              "House Naan",
+		Quantity:          2,
+		Kind:              o.ReorderItemChange_UNAVAILABLE,
+		PreviousUnitPrice: &o.Money{CurrencyCode: "INR", AmountMinor: 4000},
+	}}, response.Changes)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
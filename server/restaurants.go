@@ -0,0 +1,314 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+	database "orderService.com/go-orderService-grpc/db"
+	"orderService.com/go-orderService-grpc/model"
+	o "orderService.com/go-orderService-grpc/proto/order"
+)
+
+// restaurantFeedBatchSize is how many changed orders StreamRestaurantOrders
+// reads at a time.
+const restaurantFeedBatchSize = 100
+
+// RestaurantServiceServer serves the owners of restaurants, as recorded in
+// model.RestaurantOwner.
+type RestaurantServiceServer struct {
+	Orders *OrderServiceServer
+	o.RestaurantServiceServer
+}
+
+func (restaurantServer *RestaurantServiceServer) ListIncomingOrders(ctx context.Context, req *o.ListIncomingOrdersRequest) (*o.ListIncomingOrdersResponse, error) {
+	user, err := restaurantServer.Orders.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := restaurantServer.requireOwner(user, req.RestaurantId); err != nil {
+		return nil, err
+	}
+
+	orders, err := database.ListIncomingOrders(restaurantServer.Orders.DB, req.RestaurantId)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	response := &o.ListIncomingOrdersResponse{Orders: make([]*o.Order, len(orders))}
+	for i := range orders {
+		response.Orders[i] = toProtoOrder(&orders[i])
+	}
+
+	return response, nil
+}
+
+// StreamRestaurantOrders follows the orders of a restaurant by their
+// change_seq, which is also the cursor. RestaurantUpdates only says that
+// something changed; what changed is read from the database.
+func (restaurantServer *RestaurantServiceServer) StreamRestaurantOrders(req *o.StreamRestaurantOrdersRequest, stream o.RestaurantService_StreamRestaurantOrdersServer) error {
+	ctx := stream.Context()
+	db := restaurantServer.Orders.DB
+
+	user, err := restaurantServer.Orders.authenticate(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := restaurantServer.requireOwner(user, req.RestaurantId); err != nil {
+		return err
+	}
+
+	if restaurantServer.Orders.RestaurantUpdates == nil {
+		return errInternal("order updates are not configured")
+	}
+
+	// Subscribing before the first read makes sure no change is missed
+	// between the two.
+	subscription := restaurantServer.Orders.RestaurantUpdates.Subscribe(req.RestaurantId)
+	defer subscription.Close()
+
+	heartbeat := time.NewTicker(watchHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	send := func(order *model.Order) error {
+		heartbeat.Reset(watchHeartbeatInterval)
+		return stream.Send(&o.RestaurantOrderEvent{Order: toProtoOrder(order), Cursor: order.ChangeSeq, SentAt: timestamppb.Now()})
+	}
+
+	cursor := req.Cursor
+	if cursor == 0 {
+		cursor, err = database.LatestOrderChange(db, req.RestaurantId)
+		if err != nil {
+			return toStatusError(err)
+		}
+
+		orders, err := database.ListOpenRestaurantOrders(db, req.RestaurantId, cursor)
+		if err != nil {
+			return toStatusError(err)
+		}
+
+		for i := range orders {
+			if err := send(&orders[i]); err != nil {
+				return err
+			}
+		}
+	}
+
+	for {
+		orders, err := database.ListOrderChanges(db, req.RestaurantId, cursor, restaurantFeedBatchSize)
+		if err != nil {
+			return toStatusError(err)
+		}
+
+		for i := range orders {
+			if err := send(&orders[i]); err != nil {
+				return err
+			}
+			cursor = orders[i].ChangeSeq
+		}
+
+		if len(orders) == restaurantFeedBatchSize {
+			continue
+		}
+
+	wait:
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-heartbeat.C:
+				if err := stream.Send(&o.RestaurantOrderEvent{Cursor: cursor, Heartbeat: true, SentAt: timestamppb.Now()}); err != nil {
+					return err
+				}
+			case <-subscription.C:
+				break wait
+			}
+		}
+	}
+}
+
+// AcceptOrder records the restaurant's preparation time and sends the order
+// to the fulfillment service. If that fails, the order stays accepted and the
+// scheduler retries the dispatch.
+func (restaurantServer *RestaurantServiceServer) AcceptOrder(ctx context.Context, req *o.AcceptOrderRequest) (*o.Order, error) {
+	user, err := restaurantServer.Orders.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	var order *model.Order
+	err = restaurantServer.Orders.DB.Transaction(func(tx *gorm.DB) error {
+		order, err = restaurantServer.lockIncomingOrder(tx, user, req.OrderId)
+		if err != nil {
+			return err
+		}
+
+		order.Status = model.OrderDispatching
+		order.DispatchClaimedAt = &now
+		order.AcceptedAt = &now
+		order.PrepTimeMinutes = req.PrepTimeMinutes
+
+		if order.DeliveryDistanceMeters != nil && restaurantServer.Orders.ETA != nil {
+			estimate := restaurantServer.Orders.ETA.Estimate(now, time.Duration(req.PrepTimeMinutes)*time.Minute, *order.DeliveryDistanceMeters)
+			order.EstimatedDeliveryAt = &estimate
+		}
+
+		return database.UpdateOrder(tx, order, "status", "dispatch_claimed_at", "accepted_at", "prep_time_minutes", "estimated_delivery_at")
+	})
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	restaurantServer.Orders.publishUpdate(order)
+
+	if err := restaurantServer.Orders.dispatchAccepted(order, time.Now()); err != nil {
+		log.Printf("Error dispatching accepted order %d, leaving it to the scheduler: %v", order.Id, err)
+	}
+
+	return restaurantServer.orderResponse(order.Id)
+}
+
+// RejectOrder rejects an order the restaurant won't cook, giving back its
+// promotion use and loyalty points and reversing its wallet payment.
+func (restaurantServer *RestaurantServiceServer) RejectOrder(ctx context.Context, req *o.RejectOrderRequest) (*o.Order, error) {
+	user, err := restaurantServer.Orders.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var order *model.Order
+	err = restaurantServer.Orders.DB.Transaction(func(tx *gorm.DB) error {
+		order, err = restaurantServer.lockIncomingOrder(tx, user, req.OrderId)
+		if err != nil {
+			return err
+		}
+
+		if err := database.ReleaseRedemption(tx, order.Id); err != nil {
+			return err
+		}
+
+		if err := database.ReverseLoyaltyRedemption(tx, order); err != nil {
+			return err
+		}
+
+		if err := database.ReverseWalletPayment(tx, order); err != nil {
+			return err
+		}
+
+		order.Status = model.OrderRejected
+		order.CancellationReason = req.Reason
+
+		return database.UpdateOrder(tx, order, "status", "cancellation_reason")
+	})
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	restaurantServer.Orders.publishUpdate(order)
+	return restaurantServer.orderResponse(order.Id)
+}
+
+func (restaurantServer *RestaurantServiceServer) requireOwner(user *model.User, restaurantId string) error {
+	owner, err := database.IsRestaurantOwner(restaurantServer.Orders.DB, user.Username, restaurantId)
+	if err != nil {
+		return toStatusError(err)
+	}
+
+	if !owner {
+		return toStatusError(errNotRestaurantOwner(restaurantId))
+	}
+
+	return nil
+}
+
+// lockIncomingOrder reads an order waiting for acceptance with a row lock.
+// Orders of restaurants the user doesn't own are reported as not found.
+func (restaurantServer *RestaurantServiceServer) lockIncomingOrder(tx *gorm.DB, user *model.User, orderId int64) (*model.Order, error) {
+	order, err := database.LockOrder(tx, orderId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errOrderNotFound(orderId)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	owner, err := database.IsRestaurantOwner(tx, user.Username, order.RestaurantId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !owner {
+		return nil, errOrderNotFound(orderId)
+	}
+
+	if order.Status != model.OrderAwaitingAcceptance {
+		return nil, errOrderNotAwaitingAcceptance(orderId, order.Status)
+	}
+
+	return order, nil
+}
+
+func (restaurantServer *RestaurantServiceServer) orderResponse(orderId int64) (*o.Order, error) {
+	order, err := database.GetOrder(restaurantServer.Orders.DB, orderId)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return toProtoOrder(order), nil
+}
+
+// dispatchAccepted sends an order the restaurant accepted to the fulfillment
+// service and records it as dispatched at now. A callback of the fulfillment
+// service may have moved the order on already, in which case it is left as
+// the callback set it.
+func (orderServer *OrderServiceServer) dispatchAccepted(order *model.Order, now time.Time) error {
+	user, err := database.GetUserByUsername(orderServer.DB, order.Username)
+	if err != nil {
+		return err
+	}
+
+	restaurantAddress, err := fetchRestaurantAddress(order.RestaurantId, orderServer.CatalogServiceAPI)
+	if err != nil {
+		return err
+	}
+
+	err = orderServer.dispatch(order, user.Address, restaurantAddress)
+
+	var domainErr *DomainError
+	if errors.As(err, &domainErr) && domainErr.Reason == ReasonOrderAlreadyAssigned {
+		// A previous attempt dispatched it, but stopped before recording that.
+		err = nil
+	}
+
+	// Couriers not found nearby surge the delivery fee of the next orders
+	// from the area.
+	if err == nil || errors.As(err, &domainErr) && domainErr.Reason == ReasonNoDeliveryExecutiveNearby {
+		orderServer.Surge.RecordDispatch(restaurantAddress, err == nil)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	order.Status = model.OrderDispatched
+	order.DispatchedAt = &now
+
+	updated, err := database.MarkOrderDispatched(orderServer.DB, order)
+	if err != nil {
+		return err
+	}
+
+	if updated {
+		orderServer.publishUpdate(order)
+	}
+
+	return nil
+}
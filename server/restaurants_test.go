@@ -0,0 +1,356 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	o "orderService.com/go-orderService-grpc/proto/order"
+	"orderService.com/go-orderService-grpc/pubsub"
+)
+
+func expectOwnership(mock sqlmock.Sqlmock, username string, owner bool) {
+	count := 0
+	if owner {
+		count = 1
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "restaurant_owners" WHERE username = $1 AND restaurant_id = $2`)).
+		WithArgs(username, "1").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(count))
+}
+
+func expectIncomingOrder(mock sqlmock.Sqlmock, status string) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "orders" WHERE id = $1`)+`.*FOR UPDATE`).WithArgs(7, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "restaurant_id", "username", "status"}).AddRow(7, "1", "username", status))
+}
+
+func TestListIncomingOrders(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	restaurantServer := &RestaurantServiceServer{Orders: &OrderServiceServer{DB: gormDb}}
+	acceptBy := time.Date(2024, 3, 10, 19, 40, 0, 0, time.UTC)
+
+	expectUserLookup(t, mock, "chef", "password")
+	expectOwnership(mock, "chef", true)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "orders" WHERE restaurant_id = $1 AND status = $2 ORDER BY accept_by, id`)).
+		WithArgs("1", "AWAITING_ACCEPTANCE").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "restaurant_id", "username", "currency", "total_amount", "status", "accept_by"}).
+			AddRow(7, "1", "username", "INR", 8000, "AWAITING_ACCEPTANCE", acceptBy))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "order_items" WHERE "order_items"."order_id" = $1`)).WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "menu_item_name", "quantity", "unit_amount", "line_amount"}).AddRow(1, 7, "Naan", 2, 4000, 8000))
+
+	response, err := restaurantServer.ListIncomingOrders(basicAuthContext("chef", "password"), &o.ListIncomingOrdersRequest{RestaurantId: "1"})
+
+	assert.Nil(t, err)
+	assert.Len(t, response.Orders, 1)
+	assert.Equal(t, o.OrderStatus_ORDER_AWAITING_ACCEPTANCE, response.Orders[0].Status)
+	assert.Equal(t, acceptBy, response.Orders[0].AcceptBy.AsTime())
+	assert.Equal(t, "Naan", response.Orders[0].LineItems[0].Name)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestListIncomingOrders_NotOwner_ReturnsPermissionDenied(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	restaurantServer := &RestaurantServiceServer{Orders: &OrderServiceServer{DB: gormDb}}
+
+	expectUserLookup(t, mock, "username", "password")
+	expectOwnership(mock, "username", false)
+
+	response, err := restaurantServer.ListIncomingOrders(basicAuthContext("username", "password"), &o.ListIncomingOrdersRequest{RestaurantId: "1"})
+
+	assert.Nil(t, response)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.Equal(t, ReasonNotRestaurantOwner, errorInfoOf(t, err).Reason)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestAcceptOrder_DispatchesOrder(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	catalog := newCatalogServer(t, "1", map[string]menuItemFixture{"Naan": {Price: "40", Currency: "INR"}})
+	fulfillment := newFulfillmentServer(t, http.StatusCreated)
+	hub := pubsub.NewHub[int64]()
+	restaurantServer := &RestaurantServiceServer{Orders: &OrderServiceServer{
+		DB:                    gormDb,
+		CatalogServiceAPI:     catalog.URL + "/restaurants/",
+		FulfillmentServiceAPI: fulfillment.URL,
+		Updates:               hub,
+	}}
+	subscription := hub.Subscribe(7)
+
+	expectUserLookup(t, mock, "chef", "password")
+	expectIncomingOrder(mock, "AWAITING_ACCEPTANCE")
+	expectOwnership(mock, "chef", true)
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "orders" SET "status"=$1,"dispatch_claimed_at"=$2,"estimated_delivery_at"=$3,"accepted_at"=$4,"prep_time_minutes"=$5 WHERE "id" = $6`)).
+		WithArgs("DISPATCHING", sqlmock.AnyArg(), nil, sqlmock.AnyArg(), 20, 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectUserLookup(t, mock, "username", "password")
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "orders" SET "status"=$1,"dispatched_at"=$2 WHERE status = $3 AND "id" = $4`)).
+		WithArgs("DISPATCHED", sqlmock.AnyArg(), "DISPATCHING", 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectOrderLookup(mock, "username", "DISPATCHED", "")
+
+	response, err := restaurantServer.AcceptOrder(basicAuthContext("chef", "password"), &o.AcceptOrderRequest{OrderId: 7, PrepTimeMinutes: 20})
+
+	assert.Nil(t, err)
+	assert.Equal(t, o.OrderStatus_ORDER_DISPATCHED, response.Status)
+	assert.Len(t, subscription.C, 1)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestAcceptOrder_CallbackArrivedFirst_KeepsDeliveryProgress(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	catalog := newCatalogServer(t, "1", map[string]menuItemFixture{"Naan": {Price: "40", Currency: "INR"}})
+	fulfillment := newFulfillmentServer(t, http.StatusCreated)
+	restaurantServer := &RestaurantServiceServer{Orders: &OrderServiceServer{
+		DB:                    gormDb,
+		CatalogServiceAPI:     catalog.URL + "/restaurants/",
+		FulfillmentServiceAPI: fulfillment.URL,
+	}}
+
+	expectUserLookup(t, mock, "chef", "password")
+	expectIncomingOrder(mock, "AWAITING_ACCEPTANCE")
+	expectOwnership(mock, "chef", true)
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "orders" SET "status"=$1,"dispatch_claimed_at"=$2,"estimated_delivery_at"=$3,"accepted_at"=$4,"prep_time_minutes"=$5 WHERE "id" = $6`)).
+		WithArgs("DISPATCHING", sqlmock.AnyArg(), nil, sqlmock.AnyArg(), 20, 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectUserLookup(t, mock, "username", "password")
+	// The courier was assigned before the fulfillment service answered.
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "orders" SET "status"=$1,"dispatched_at"=$2 WHERE status = $3 AND "id" = $4`)).
+		WithArgs("DISPATCHED", sqlmock.AnyArg(), "DISPATCHING", 7).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	expectOrderLookup(mock, "username", "COURIER_ASSIGNED", "")
+
+	response, err := restaurantServer.AcceptOrder(basicAuthContext("chef", "password"), &o.AcceptOrderRequest{OrderId: 7, PrepTimeMinutes: 20})
+
+	assert.Nil(t, err)
+	assert.Equal(t, o.OrderStatus_ORDER_COURIER_ASSIGNED, response.Status)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestAcceptOrder_FulfillmentDown_StaysAccepted(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	catalog := newCatalogServer(t, "1", map[string]menuItemFixture{"Naan": {Price: "40", Currency: "INR"}})
+	fulfillment := newFulfillmentServer(t, http.StatusInternalServerError)
+	restaurantServer := &RestaurantServiceServer{Orders: &OrderServiceServer{
+		DB:                    gormDb,
+		CatalogServiceAPI:     catalog.URL + "/restaurants/",
+		FulfillmentServiceAPI: fulfillment.URL,
+	}}
+
+	expectUserLookup(t, mock, "chef", "password")
+	expectIncomingOrder(mock, "AWAITING_ACCEPTANCE")
+	expectOwnership(mock, "chef", true)
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "orders" SET "status"=$1,"dispatch_claimed_at"=$2,"estimated_delivery_at"=$3,"accepted_at"=$4,"prep_time_minutes"=$5 WHERE "id" = $6`)).
+		WithArgs("DISPATCHING", sqlmock.AnyArg(), nil, sqlmock.AnyArg(), 20, 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectUserLookup(t, mock, "username", "password")
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "orders" WHERE id = $1`)).WithArgs(7, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "restaurant_id", "username", "currency", "status", "accepted_at", "prep_time_minutes"}).
+			AddRow(7, "1", "username", "INR", "DISPATCHING", time.Now(), 20))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "order_items" WHERE "order_items"."order_id" = $1`)).WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "menu_item_name", "quantity", "unit_amount", "line_amount"}))
+
+	response, err := restaurantServer.AcceptOrder(basicAuthContext("chef", "password"), &o.AcceptOrderRequest{OrderId: 7, PrepTimeMinutes: 20})
+
+	assert.Nil(t, err)
+	assert.Equal(t, o.OrderStatus_ORDER_ACCEPTED, response.Status)
+	assert.Equal(t, int32(20), response.PrepTimeMinutes)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestAcceptOrder_OtherRestaurant_ReturnsNotFound(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	restaurantServer := &RestaurantServiceServer{Orders: &OrderServiceServer{DB: gormDb}}
+
+	expectUserLookup(t, mock, "chef", "password")
+	expectIncomingOrder(mock, "AWAITING_ACCEPTANCE")
+	expectOwnership(mock, "chef", false)
+	mock.ExpectRollback()
+
+	response, err := restaurantServer.AcceptOrder(basicAuthContext("chef", "password"), &o.AcceptOrderRequest{OrderId: 7, PrepTimeMinutes: 20})
+
+	assert.Nil(t, response)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Equal(t, ReasonOrderNotFound, errorInfoOf(t, err).Reason)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestRejectOrder_ReleasesPromotion(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	restaurantServer := &RestaurantServiceServer{Orders: &OrderServiceServer{DB: gormDb}}
+
+	expectUserLookup(t, mock, "chef", "password")
+	expectIncomingOrder(mock, "AWAITING_ACCEPTANCE")
+	expectOwnership(mock, "chef", true)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "promotion_redemptions" WHERE order_id = $1`)).WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "promotion_id", "username", "order_id"}).AddRow(9, 3, "username", 7))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "promotions" SET "usage_count"=usage_count - 1 WHERE id = $1 AND usage_count > 0`)).WithArgs(3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "promotion_redemptions" WHERE "promotion_redemptions"."id" = $1`)).WithArgs(9).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "orders" SET "status"=$1,"cancellation_reason"=$2 WHERE "id" = $3`)).
+		WithArgs("REJECTED", "Out of paneer", 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectOrderLookup(mock, "username", "REJECTED", "")
+
+	response, err := restaurantServer.RejectOrder(basicAuthContext("chef", "password"), &o.RejectOrderRequest{OrderId: 7, Reason: "Out of paneer"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, o.OrderStatus_ORDER_REJECTED, response.Status)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestRejectOrder_AlreadyAccepted_ReturnsFailedPrecondition(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	restaurantServer := &RestaurantServiceServer{Orders: &OrderServiceServer{DB: gormDb}}
+
+	expectUserLookup(t, mock, "chef", "password")
+	expectIncomingOrder(mock, "DISPATCHED")
+	expectOwnership(mock, "chef", true)
+	mock.ExpectRollback()
+
+	response, err := restaurantServer.RejectOrder(basicAuthContext("chef", "password"), &o.RejectOrderRequest{OrderId: 7, Reason: "Closing early"})
+
+	assert.Nil(t, response)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.Equal(t, ReasonOrderNotAwaitingAcceptance, errorInfoOf(t, err).Reason)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+// restaurantStream collects what StreamRestaurantOrders sends.
+type restaurantStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	events chan *o.RestaurantOrderEvent
+}
+
+func (stream *restaurantStream) Context() context.Context {
+	return stream.ctx
+}
+
+func (stream *restaurantStream) Send(event *o.RestaurantOrderEvent) error {
+	stream.events <- event
+	return nil
+}
+
+func nextEvent(t *testing.T, stream *restaurantStream) *o.RestaurantOrderEvent {
+	select {
+	case event := <-stream.events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("Expected a restaurant order event")
+		return nil
+	}
+}
+
+func restaurantOrderRows(changes ...[]driver.Value) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"id", "restaurant_id", "username", "currency", "total_amount", "status", "change_seq"})
+	for _, change := range changes {
+		rows.AddRow(change...)
+	}
+
+	return rows
+}
+
+func expectOrderChanges(mock sqlmock.Sqlmock, cursor int64, rows *sqlmock.Rows, orderId int64) {
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "orders" WHERE restaurant_id = $1 AND change_seq > $2 ORDER BY change_seq LIMIT $3`)).
+		WithArgs("1", cursor, restaurantFeedBatchSize).WillReturnRows(rows)
+	expectItemsOf(mock, orderId)
+}
+
+func expectItemsOf(mock sqlmock.Sqlmock, orderId int64) {
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "order_items" WHERE "order_items"."order_id" = $1`)).WithArgs(orderId).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "menu_item_name", "quantity", "unit_amount", "line_amount"}))
+}
+
+func TestStreamRestaurantOrders_SendsOpenOrdersThenChanges(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	hub := pubsub.NewHub[string]()
+	restaurantServer := &RestaurantServiceServer{Orders: &OrderServiceServer{DB: gormDb, RestaurantUpdates: hub}}
+	ctx, cancel := context.WithCancel(basicAuthContext("chef", "password"))
+	stream := &restaurantStream{ctx: ctx, events: make(chan *o.RestaurantOrderEvent, 10)}
+
+	expectUserLookup(t, mock, "chef", "password")
+	expectOwnership(mock, "chef", true)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COALESCE(MAX(change_seq), 0) FROM "orders" WHERE restaurant_id = $1`)).WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(6))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "orders" WHERE restaurant_id = $1 AND change_seq <= $2 AND status NOT IN ($3,$4,$5) ORDER BY change_seq`)).
+		WithArgs("1", 6, "CANCELLED", "REJECTED", "DELIVERED").
+		WillReturnRows(restaurantOrderRows([]driver.Value{7, "1", "username", "INR", 8000, "AWAITING_ACCEPTANCE", 5}))
+	expectItemsOf(mock, 7)
+	// Order 8 was placed between the two reads.
+	expectOrderChanges(mock, 6, restaurantOrderRows([]driver.Value{8, "1", "username", "INR", 9000, "AWAITING_ACCEPTANCE", 7}), 8)
+
+	done := make(chan error)
+	go func() {
+		done <- restaurantServer.StreamRestaurantOrders(&o.StreamRestaurantOrdersRequest{RestaurantId: "1"}, stream)
+	}()
+
+	open := nextEvent(t, stream)
+	assert.Equal(t, int64(7), open.Order.Id)
+	assert.Equal(t, int64(5), open.Cursor)
+	placed := nextEvent(t, stream)
+	assert.Equal(t, int64(8), placed.Order.Id)
+	assert.Equal(t, int64(7), placed.Cursor)
+
+	cancel()
+	assert.Nil(t, <-done)
+	assert.Equal(t, 0, hub.Subscribers("1"))
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestStreamRestaurantOrders_ResumesFromCursor(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	hub := pubsub.NewHub[string]()
+	restaurantServer := &RestaurantServiceServer{Orders: &OrderServiceServer{DB: gormDb, RestaurantUpdates: hub}}
+	ctx, cancel := context.WithCancel(basicAuthContext("chef", "password"))
+	stream := &restaurantStream{ctx: ctx, events: make(chan *o.RestaurantOrderEvent, 10)}
+
+	expectUserLookup(t, mock, "chef", "password")
+	expectOwnership(mock, "chef", true)
+	expectOrderChanges(mock, 4, restaurantOrderRows([]driver.Value{7, "1", "username", "INR", 8000, "DELIVERED", 5}), 7)
+	expectOrderChanges(mock, 5, restaurantOrderRows([]driver.Value{8, "1", "username", "INR", 9000, "AWAITING_ACCEPTANCE", 6}), 8)
+
+	done := make(chan error)
+	go func() {
+		done <- restaurantServer.StreamRestaurantOrders(&o.StreamRestaurantOrdersRequest{RestaurantId: "1", Cursor: 4}, stream)
+	}()
+
+	missed := nextEvent(t, stream)
+	assert.Equal(t, o.OrderStatus_ORDER_DELIVERED, missed.Order.Status)
+	assert.Equal(t, int64(5), missed.Cursor)
+
+	hub.Publish("1")
+	placed := nextEvent(t, stream)
+	assert.Equal(t, int64(8), placed.Order.Id)
+	assert.Equal(t, int64(6), placed.Cursor)
+
+	cancel()
+	assert.Nil(t, <-done)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestStreamRestaurantOrders_NotOwner_ReturnsPermissionDenied(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	restaurantServer := &RestaurantServiceServer{Orders: &OrderServiceServer{DB: gormDb, RestaurantUpdates: pubsub.NewHub[string]()}}
+	stream := &restaurantStream{ctx: basicAuthContext("username", "password"), events: make(chan *o.RestaurantOrderEvent, 10)}
+
+	expectUserLookup(t, mock, "username", "password")
+	expectOwnership(mock, "username", false)
+
+	err := restaurantServer.StreamRestaurantOrders(&o.StreamRestaurantOrdersRequest{RestaurantId: "1"}, stream)
+
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.Empty(t, stream.events)
+}
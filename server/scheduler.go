@@ -0,0 +1,403 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+	database "orderService.com/go-orderService-grpc/db"
+	"orderService.com/go-orderService-grpc/model"
+	"orderService.com/go-orderService-grpc/money"
+	"orderService.com/go-orderService-grpc/pricing"
+	o "orderService.com/go-orderService-grpc/proto/order"
+)
+
+const (
+	// dispatchLeadTime is how long before its scheduled time an order is sent
+	// to the fulfillment service, leaving time to cook and deliver it. Orders
+	// can't be scheduled sooner than that.
+	dispatchLeadTime   = 45 * time.Minute
+	maxScheduleAhead   = 7 * 24 * time.Hour
+	schedulerInterval  = 30 * time.Second
+	schedulerBatchSize = 20
+	// dispatchClaimTimeout is how long an order may stay claimed before
+	// another scheduler assumes the claim was abandoned and takes it over.
+	dispatchClaimTimeout = 5 * time.Minute
+	// dispatchGiveUpAfter is how late past its scheduled time, or past its
+	// acceptance, an order that can't be dispatched is cancelled instead of
+	// retried.
+	dispatchGiveUpAfter = 30 * time.Minute
+	acceptTimeoutReason = "the restaurant did not accept the order in time"
+)
+
+var orderStatuses = map[model.OrderStatus]o.OrderStatus{
+	model.OrderPlaced:             o.OrderStatus_ORDER_PLACED,
+	model.OrderAwaitingAcceptance: o.OrderStatus_ORDER_AWAITING_ACCEPTANCE,
+	model.OrderRejected:           o.OrderStatus_ORDER_REJECTED,
+	model.OrderScheduled:          o.OrderStatus_ORDER_SCHEDULED,
+	model.OrderDispatching:        o.OrderStatus_ORDER_SCHEDULED,
+	model.OrderDispatched:         o.OrderStatus_ORDER_DISPATCHED,
+	model.OrderCancelled:          o.OrderStatus_ORDER_CANCELLED,
+
+	model.OrderCourierAssigned: o.OrderStatus_ORDER_COURIER_ASSIGNED,
+	model.OrderPickedUp:        o.OrderStatus_ORDER_PICKED_UP,
+	model.OrderDelivered:       o.OrderStatus_ORDER_DELIVERED,
+}
+
+// orderStatus tells accepted orders being dispatched from scheduled ones
+// being re-priced, which share model.OrderDispatching.
+func orderStatus(order *model.Order) o.OrderStatus {
+	if order.Status == model.OrderDispatching && order.AcceptedAt != nil {
+		return o.OrderStatus_ORDER_ACCEPTED
+	}
+
+	return orderStatuses[order.Status]
+}
+
+var priceChangePolicies = map[o.PriceChangePolicy]model.PriceChangePolicy{
+	o.PriceChangePolicy_PRICE_CHANGE_POLICY_UNSPECIFIED: model.KeepOrderPrice,
+	o.PriceChangePolicy_KEEP_ORDER_PRICE:                model.KeepOrderPrice,
+	o.PriceChangePolicy_ACCEPT_NEW_PRICE:                model.AcceptNewPrice,
+	o.PriceChangePolicy_CANCEL_IF_PRICE_INCREASES:       model.CancelIfPriceIncreases,
+}
+
+// scheduledTime checks the requested delivery time of an order. It returns
+// nil for orders to be delivered now.
+func scheduledTime(scheduledFor *timestamppb.Timestamp, now time.Time) (*time.Time, error) {
+	if scheduledFor == nil {
+		return nil, nil
+	}
+
+	if err := scheduledFor.CheckValid(); err != nil {
+		return nil, errInvalidArgument("Invalid scheduled_for", fieldViolation("scheduled_for", err.Error()))
+	}
+
+	at := scheduledFor.AsTime()
+	switch {
+	case at.Before(now.Add(dispatchLeadTime)):
+		return nil, errInvalidArgument("Invalid scheduled_for", fieldViolation("scheduled_for", fmt.Sprintf("must be at least %v from now", dispatchLeadTime)))
+	case at.After(now.Add(maxScheduleAhead)):
+		return nil, errInvalidArgument("Invalid scheduled_for", fieldViolation("scheduled_for", fmt.Sprintf("must be within %v from now", maxScheduleAhead)))
+	}
+
+	return &at, nil
+}
+
+// OrderScheduler hands scheduled orders to their restaurant when they are
+// due, retries dispatching accepted orders whose dispatch failed, and rejects
+// orders the restaurant didn't accept in time. Several schedulers may run
+// against the same database: ClaimDueOrders hands each order to one of them,
+// and a claim abandoned by a crash is taken over once it is older than
+// dispatchClaimTimeout. Retrying a dispatch is safe because the fulfillment
+// service rejects an order it already has.
+type OrderScheduler struct {
+	Orders *OrderServiceServer
+	now    func() time.Time
+}
+
+func NewOrderScheduler(orders *OrderServiceServer) *OrderScheduler {
+	return &OrderScheduler{Orders: orders, now: time.Now}
+}
+
+func (scheduler *OrderScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(schedulerInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := scheduler.DispatchDue(); err != nil {
+			log.Printf("Error dispatching scheduled orders: %v", err)
+		}
+
+		if err := scheduler.RejectExpired(); err != nil {
+			log.Printf("Error rejecting orders not accepted in time: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchDue processes the claimed orders, in batches, until none are left.
+func (scheduler *OrderScheduler) DispatchDue() error {
+	for {
+		now := scheduler.now()
+
+		orders, err := database.ClaimDueOrders(scheduler.Orders.DB, now.Add(dispatchLeadTime), now.Add(-dispatchClaimTimeout), now, schedulerBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for i := range orders {
+			if err := scheduler.process(&orders[i]); err != nil {
+				log.Printf("Error dispatching scheduled order %d: %v", orders[i].Id, err)
+			}
+		}
+
+		if len(orders) < schedulerBatchSize {
+			return nil
+		}
+	}
+}
+
+// RejectExpired rejects the orders that their restaurant didn't accept in
+// time, in batches, until none are left.
+func (scheduler *OrderScheduler) RejectExpired() error {
+	for {
+		orders, err := database.RejectExpiredOrders(scheduler.Orders.DB, scheduler.now(), acceptTimeoutReason, schedulerBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for i := range orders {
+			scheduler.Orders.publishUpdate(&orders[i])
+		}
+
+		if len(orders) < schedulerBatchSize {
+			return nil
+		}
+	}
+}
+
+// process sends a claimed order that the restaurant accepted to the
+// fulfillment service, and hands a scheduled one to its restaurant. Failures
+// that may pass are retried on a later run until dispatchGiveUpAfter, when
+// the order is cancelled.
+func (scheduler *OrderScheduler) process(order *model.Order) error {
+	if order.AcceptedAt != nil {
+		if err := scheduler.Orders.dispatchAccepted(order, scheduler.now()); err != nil {
+			return scheduler.retryOrCancel(order, err)
+		}
+
+		return nil
+	}
+
+	return scheduler.release(order)
+}
+
+// release re-prices a scheduled order, applies its price change policy and
+// leaves it for the restaurant to accept. The order is cancelled if the
+// restaurant no longer opens at its scheduled time.
+func (scheduler *OrderScheduler) release(order *model.Order) error {
+	db := scheduler.Orders.DB
+
+	user, err := database.GetUserByUsername(db, order.Username)
+	if err != nil {
+		return scheduler.retryOrCancel(order, err)
+	}
+
+	if err := db.Where("order_id = ?", order.Id).Order("menu_item_name").Find(&order.Items).Error; err != nil {
+		return scheduler.retryOrCancel(order, err)
+	}
+
+	items, subtotal, err := calculateOrderTotal(&o.CreateOrderRequest{RestaurantId: order.RestaurantId, Lines: linesOf(order.Items)}, scheduler.Orders.CatalogServiceAPI)
+	if isPermanent(err) {
+		return scheduler.cancel(order, "items are no longer available: "+status.Convert(err).Message())
+	}
+
+	if err != nil {
+		return scheduler.retryOrCancel(order, err)
+	}
+
+	restaurant, err := fetchRestaurant(order.RestaurantId, scheduler.Orders.CatalogServiceAPI)
+	if isPermanent(err) {
+		return scheduler.cancel(order, "restaurant is no longer available: "+status.Convert(err).Message())
+	}
+
+	if err != nil {
+		return scheduler.retryOrCancel(order, err)
+	}
+
+	restaurantAddress := restaurant.Address
+
+	// The delivery zone or the user's address may have changed since the
+	// order was placed.
+	if err := scheduler.Orders.checkServiceability(order.RestaurantId, restaurantAddress, user.Address); err != nil {
+		return scheduler.cancel(order, "address is no longer served: "+status.Convert(err).Message())
+	}
+
+	// So may the opening hours, e.g. for a holiday.
+	cookAt := scheduler.now()
+	if order.ScheduledFor != nil {
+		cookAt = *order.ScheduledFor
+	}
+
+	if !restaurant.Hours.OpenAt(cookAt) {
+		return scheduler.cancel(order, "restaurant is closed at the scheduled time")
+	}
+
+	repriced := subtotal.Currency != order.Currency || subtotal.Amount != order.SubtotalAmount
+	switch {
+	case repriced && order.PriceChangePolicy == model.CancelIfPriceIncreases && (subtotal.Currency != order.Currency || subtotal.Amount > order.SubtotalAmount):
+		return scheduler.cancel(order, "menu prices increased since the order was placed")
+	case repriced && order.PriceChangePolicy == model.AcceptNewPrice:
+		if err := scheduler.reprice(order, user, restaurantAddress, items, subtotal); err != nil {
+			return scheduler.retryOrCancel(order, err)
+		}
+	}
+
+	acceptBy := scheduler.now().Add(scheduler.Orders.acceptTimeout())
+	order.Status = model.OrderAwaitingAcceptance
+	order.AcceptBy = &acceptBy
+	order.DispatchClaimedAt = nil
+
+	if err := database.UpdateOrder(db, order, "status", "dispatch_claimed_at", "accept_by"); err != nil {
+		return err
+	}
+
+	scheduler.Orders.Surge.Opened(order.Id, restaurantAddress)
+	scheduler.Orders.publishUpdate(order)
+	return nil
+}
+
+// reprice prices the order's items at current prices. The promotion discount
+// keeps the amount it had when the order was placed, since the promotion was
+// already redeemed and may have ended since.
+func (scheduler *OrderScheduler) reprice(order *model.Order, user *model.User, restaurantAddress *model.Address, items []model.OrderItem, subtotal money.Money) error {
+	// The promotion keeps its discount and the redeemed points are worth what
+	// is left of the new subtotal after it, as in priceOrder.
+	promotionDiscount := min(order.PromotionDiscountAmount, subtotal.Amount)
+	_, loyaltyDiscount := scheduler.Orders.Loyalty.Redeem(order.LoyaltyPointsRedeemed, money.New(subtotal.Amount-promotionDiscount, subtotal.Currency))
+
+	input := pricing.Input{
+		Subtotal:      subtotal,
+		DropAddress:   user.Address,
+		PickupAddress: restaurantAddress,
+		Tip:           money.New(order.TipAmount, subtotal.Currency),
+		ItemDiscount:  money.New(promotionDiscount+loyaltyDiscount.Amount, subtotal.Currency),
+		// The surge stays what the customer agreed to.
+		SurgeBasisPoints: order.SurgeBasisPoints,
+	}
+
+	if order.PromoCode != "" {
+		promotion, err := database.GetPromotionByCode(scheduler.Orders.DB, order.PromoCode)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if promotion != nil && promotion.Kind == model.FreeDelivery {
+			input.FreeDelivery = true
+		}
+	}
+
+	breakdown, err := scheduler.Orders.pricingEngine().Price(input)
+	if err != nil {
+		return err
+	}
+
+	// Store credit paid beyond the new total, or in a currency the order is
+	// no longer in, is given back.
+	refund := order.WalletAmount
+	if breakdown.Total.Currency == order.Currency {
+		refund = max(order.WalletAmount-breakdown.Total.Amount, 0)
+	}
+	walletCurrency := order.Currency
+
+	order.Items = items
+	order.Currency = breakdown.Total.Currency
+	order.SubtotalAmount = breakdown.Subtotal.Amount
+	order.DiscountAmount = breakdown.Discount.Amount
+	order.TaxAmount = breakdown.Tax.Amount
+	order.TaxBasisPoints = breakdown.TaxBasisPoints
+	order.DeliveryFeeAmount = breakdown.DeliveryFee.Amount
+	order.ServiceFeeAmount = breakdown.ServiceFee.Amount
+	order.TotalAmount = breakdown.Total.Amount
+	order.PromotionDiscountAmount = promotionDiscount
+	order.LoyaltyDiscountAmount = loyaltyDiscount.Amount
+
+	err = scheduler.Orders.DB.Transaction(func(tx *gorm.DB) error {
+		if err := database.ReplaceOrderItems(tx, order); err != nil {
+			return err
+		}
+
+		columns := []string{"currency", "subtotal_amount", "discount_amount", "tax_amount", "tax_basis_points",
+			"delivery_fee_amount", "service_fee_amount", "total_amount", "loyalty_discount_amount", "promotion_discount_amount"}
+
+		if refund > 0 {
+			err := database.RecordWalletTransaction(tx, model.WalletTransfer(model.WalletReversal, order.Username, order.Id, walletCurrency, refund,
+				model.WalletPaymentsAccount, model.WalletAccount(order.Username)))
+			if err != nil {
+				return err
+			}
+
+			order.WalletAmount -= refund
+			columns = append(columns, "wallet_amount")
+		}
+
+		return database.UpdateOrder(tx, order, columns...)
+	})
+	if err != nil {
+		return err
+	}
+
+	scheduler.Orders.publishUpdate(order)
+	return nil
+}
+
+// retryOrCancel hands a claimed order back to the next run, or cancels it if
+// it is already too late to deliver. Accepted orders keep their claim, which
+// is taken over again once stale.
+func (scheduler *OrderScheduler) retryOrCancel(order *model.Order, cause error) error {
+	deadline := order.ScheduledFor
+	if order.AcceptedAt != nil {
+		deadline = order.AcceptedAt
+	}
+
+	if deadline == nil || scheduler.now().After(deadline.Add(dispatchGiveUpAfter)) {
+		return errors.Join(cause, scheduler.cancel(order, "could not be dispatched in time: "+cause.Error()))
+	}
+
+	if order.AcceptedAt != nil {
+		return cause
+	}
+
+	order.Status = model.OrderScheduled
+	order.DispatchClaimedAt = nil
+	return errors.Join(cause, database.UpdateOrder(scheduler.Orders.DB, order, "status", "dispatch_claimed_at"))
+}
+
+func (scheduler *OrderScheduler) cancel(order *model.Order, reason string) error {
+	order.Status = model.OrderCancelled
+	order.CancellationReason = reason
+
+	err := scheduler.Orders.DB.Transaction(func(tx *gorm.DB) error {
+		if err := database.ReleaseRedemption(tx, order.Id); err != nil {
+			return err
+		}
+
+		if err := database.ReverseLoyaltyRedemption(tx, order); err != nil {
+			return err
+		}
+
+		if err := database.ReverseWalletPayment(tx, order); err != nil {
+			return err
+		}
+
+		return database.UpdateOrder(tx, order, "status", "cancellation_reason")
+	})
+	if err != nil {
+		return err
+	}
+
+	scheduler.Orders.publishUpdate(order)
+	return nil
+}
+
+// isPermanent tells errors that retrying won't fix, such as a menu item that
+// was removed from the catalog, from upstream outages.
+func isPermanent(err error) bool {
+	switch status.Code(err) {
+	case codes.OK, codes.Unavailable, codes.Internal, codes.Unknown, codes.DeadlineExceeded:
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"orderService.com/go-orderService-grpc/pricing"
+	o "orderService.com/go-orderService-grpc/proto/order"
+	"orderService.com/go-orderService-grpc/zones"
+)
+
+func TestScheduledTime(t *testing.T) {
+	now := time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+
+	at, err := scheduledTime(nil, now)
+	assert.Nil(t, err)
+	assert.Nil(t, at)
+
+	at, err = scheduledTime(timestamppb.New(now.Add(10*time.Hour)), now)
+	assert.Nil(t, err)
+	assert.Equal(t, now.Add(10*time.Hour), *at)
+
+	_, err = scheduledTime(timestamppb.New(now.Add(10*time.Minute)), now)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = scheduledTime(timestamppb.New(now.Add(8*24*time.Hour)), now)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestCreateOrder_Scheduled_StoresWithoutDispatching(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	catalog := newCatalogServer(t, "1", map[string]menuItemFixture{"Naan": {Price: "40", Currency: "INR"}})
+	fulfillment := httpServerThatFails(t)
+	scheduledFor := time.Now().Add(10 * time.Hour).UTC().Truncate(time.Second)
+
+	expectUserLookup(t, mock, "username", "password")
+	mock.ExpectBegin()
+	expectOrderInsert(mock, 7, orderColumns{
+		"status":              "SCHEDULED",
+		"scheduled_for":       scheduledFor,
+		"price_change_policy": "CANCEL_IF_PRICE_INCREASES",
+		"accept_by":           nil,
+	})
+	mock.ExpectQuery(`INSERT INTO "order_items"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	orderServiceServer := &OrderServiceServer{
+		DB:                    gormDb,
+		CatalogServiceAPI:     catalog.URL + "/restaurants/",
+		FulfillmentServiceAPI: fulfillment,
+		Pricing:               pricing.NewEngine(pricing.Config{}),
+	}
+
+	response, err := orderServiceServer.Create(basicAuthContext("username", "password"), &o.CreateOrderRequest{
+		RestaurantId:      "1",
+		MenuItems:         map[string]int32{"Naan": 2},
+		ScheduledFor:      timestamppb.New(scheduledFor),
+		PriceChangePolicy: o.PriceChangePolicy_CANCEL_IF_PRICE_INCREASES,
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, o.OrderStatus_ORDER_SCHEDULED, response.Status)
+	assert.Equal(t, timestamppb.New(scheduledFor), response.ScheduledFor)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+// httpServerThatFails fails the test if anything is sent to it.
+func httpServerThatFails(t *testing.T) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Unexpected request to %s", r.URL.Path)
+	}))
+	t.Cleanup(server.Close)
+
+	return server.URL
+}
+
+var scheduledOrderColumns = []string{"id", "restaurant_id", "username", "currency", "subtotal_amount", "total_amount", "promo_code", "status", "scheduled_for", "price_change_policy", "accepted_at", "wallet_amount"}
+
+func expectClaimedRow(mock sqlmock.Sqlmock, row []driver.Value) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "orders" WHERE (status = $1 AND scheduled_for <= $2) OR (status = $3 AND dispatch_claimed_at <= $4) ORDER BY scheduled_for LIMIT $5 FOR UPDATE SKIP LOCKED`)).
+		WithArgs("SCHEDULED", sqlmock.AnyArg(), "DISPATCHING", sqlmock.AnyArg(), schedulerBatchSize).
+		WillReturnRows(sqlmock.NewRows(scheduledOrderColumns).AddRow(row...))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "orders" SET "dispatch_claimed_at"=$1,"status"=$2 WHERE id IN ($3)`)).
+		WithArgs(sqlmock.AnyArg(), "DISPATCHING", 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+func expectClaim(t *testing.T, mock sqlmock.Sqlmock, policy string, scheduledFor time.Time) {
+	expectClaimedRow(mock, []driver.Value{7, "1", "username", "INR", 8000, 8000, "", "SCHEDULED", scheduledFor, policy, nil, 0})
+	expectUserLookup(t, mock, "username", "password")
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "order_items" WHERE order_id = $1 ORDER BY menu_item_name`)).WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "menu_item_name", "quantity", "unit_amount", "line_amount"}).AddRow(1, 7, "Naan", 2, 4000, 8000))
+}
+
+// expectAcceptedClaim expects the claim of an order accepted at acceptedAt
+// whose dispatch was interrupted.
+func expectAcceptedClaim(t *testing.T, mock sqlmock.Sqlmock, acceptedAt time.Time) {
+	expectClaimedRow(mock, []driver.Value{7, "1", "username", "INR", 8000, 8000, "", "DISPATCHING", nil, "", acceptedAt, 0})
+	expectUserLookup(t, mock, "username", "password")
+}
+
+func newTestScheduler(t *testing.T, naanPrice string, fulfillmentStatus int) (sqlmock.Sqlmock, *OrderScheduler, time.Time) {
+	mock, gormDb := openMockDB(t)
+	catalog := newCatalogServer(t, "1", map[string]menuItemFixture{"Naan": {Price: naanPrice, Currency: "INR"}})
+	fulfillment := newFulfillmentServer(t, fulfillmentStatus)
+
+	now := time.Date(2024, 3, 10, 19, 30, 0, 0, time.UTC)
+	scheduler := NewOrderScheduler(&OrderServiceServer{
+		DB:                    gormDb,
+		CatalogServiceAPI:     catalog.URL + "/restaurants/",
+		FulfillmentServiceAPI: fulfillment.URL,
+	})
+	scheduler.now = func() time.Time { return now }
+
+	return mock, scheduler, now
+}
+
+func TestDispatchDue_HandsScheduledOrderToRestaurant(t *testing.T) {
+	mock, scheduler, now := newTestScheduler(t, "40", http.StatusCreated)
+
+	expectClaim(t, mock, "KEEP_ORDER_PRICE", now.Add(30*time.Minute))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "orders" SET "status"=$1,"dispatch_claimed_at"=$2,"accept_by"=$3 WHERE "id" = $4`)).
+		WithArgs("AWAITING_ACCEPTANCE", nil, now.Add(defaultAcceptTimeout), 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.Nil(t, scheduler.DispatchDue())
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestDispatchDue_AcceptedOrder_AlreadyAssignedCountsAsDispatched(t *testing.T) {
+	mock, scheduler, now := newTestScheduler(t, "40", http.StatusConflict)
+
+	expectAcceptedClaim(t, mock, now.Add(-10*time.Minute))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "orders" SET "status"=$1,"dispatched_at"=$2 WHERE status = $3 AND "id" = $4`)).
+		WithArgs("DISPATCHED", now, "DISPATCHING", 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.Nil(t, scheduler.DispatchDue())
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestDispatchDue_PriceIncreased_CancelsAndReleasesPromotion(t *testing.T) {
+	mock, scheduler, now := newTestScheduler(t, "45", http.StatusCreated)
+
+	expectClaim(t, mock, "CANCEL_IF_PRICE_INCREASES", now.Add(30*time.Minute))
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "promotion_redemptions" WHERE order_id = $1`)).WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "promotion_id", "username", "order_id"}).AddRow(9, 3, "username", 7))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "promotions" SET "usage_count"=usage_count - 1 WHERE id = $1 AND usage_count > 0`)).WithArgs(3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "promotion_redemptions" WHERE "promotion_redemptions"."id" = $1`)).WithArgs(9).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "orders" SET "status"=$1,"cancellation_reason"=$2 WHERE "id" = $3`)).
+		WithArgs("CANCELLED", "menu prices increased since the order was placed", 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.Nil(t, scheduler.DispatchDue())
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestDispatchDue_PriceDropped_KeepsPromotionAndCapsLoyaltyDiscount(t *testing.T) {
+	mock, scheduler, now := newTestScheduler(t, "15", http.StatusCreated)
+	scheduler.Orders.Loyalty = testLoyaltyProgram()
+
+	columns := append(scheduledOrderColumns, "promotion_discount_amount", "loyalty_points_redeemed", "loyalty_discount_amount")
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "orders"`).WillReturnRows(sqlmock.NewRows(columns).
+		AddRow(7, "1", "username", "INR", 8000, 4700, "WELCOME10", "SCHEDULED", now.Add(30*time.Minute), "ACCEPT_NEW_PRICE", nil, 0, 800, 100, 2500))
+	mock.ExpectExec(`UPDATE "orders" SET "dispatch_claimed_at"`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectUserLookup(t, mock, "username", "password")
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "order_items" WHERE order_id = $1 ORDER BY menu_item_name`)).WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "menu_item_name", "quantity", "unit_amount", "line_amount"}).AddRow(1, 7, "Naan", 2, 4000, 8000))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "promotions" WHERE code = $1`)).WithArgs("WELCOME10", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "code", "kind", "currency", "percent_off_basis_points"}).AddRow(3, "WELCOME10", "PERCENTAGE_OFF", "INR", 1000))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "order_items" WHERE order_id = $1`)).WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO "order_items"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	// The 100 points are worth only what is left of the 3000 subtotal after
+	// the promotion's 800.
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "orders" SET "currency"=$1,"subtotal_amount"=$2,"tax_amount"=$3,"tax_basis_points"=$4,"delivery_fee_amount"=$5,"service_fee_amount"=$6,"discount_amount"=$7,"total_amount"=$8,"loyalty_discount_amount"=$9,"promotion_discount_amount"=$10 WHERE "id" = $11`)).
+		WithArgs("INR", 3000, 0, 0, 0, 0, 3000, 0, 2200, 800, 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "orders" SET "status"=$1,"dispatch_claimed_at"=$2,"accept_by"=$3 WHERE "id" = $4`)).
+		WithArgs("AWAITING_ACCEPTANCE", nil, now.Add(defaultAcceptTimeout), 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.Nil(t, scheduler.DispatchDue())
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestDispatchDue_AddressNoLongerServed_Cancels(t *testing.T) {
+	mock, scheduler, now := newTestScheduler(t, "40", http.StatusCreated)
+	scheduler.Orders.Zones = zipcodeZone("560001")
+
+	expectClaim(t, mock, "KEEP_ORDER_PRICE", now.Add(30*time.Minute))
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "promotion_redemptions" WHERE order_id = $1`)).WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "promotion_id", "username", "order_id"}))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "orders" SET "status"=$1,"cancellation_reason"=$2 WHERE "id" = $3`)).
+		WithArgs("CANCELLED", "address is no longer served: "+zones.ErrOutsideZone.Error(), 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.Nil(t, scheduler.DispatchDue())
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestDispatchDue_RestaurantClosedAtScheduledTime_Cancels(t *testing.T) {
+	mock, scheduler, now := newTestScheduler(t, "40", http.StatusCreated)
+	catalog := newCatalogServerWithHours(t, "1", `"time_zone":"UTC","opening_hours":{`+everyDay(`[{"open":"10:00","close":"15:00"}]`)+`}`,
+		map[string]menuItemFixture{"Naan": {Price: "40", Currency: "INR"}})
+	scheduler.Orders.CatalogServiceAPI = catalog.URL + "/restaurants/"
+
+	expectClaim(t, mock, "KEEP_ORDER_PRICE", now.Add(30*time.Minute))
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "promotion_redemptions" WHERE order_id = $1`)).WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "promotion_id", "username", "order_id"}))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "orders" SET "status"=$1,"cancellation_reason"=$2 WHERE "id" = $3`)).
+		WithArgs("CANCELLED", "restaurant is closed at the scheduled time", 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.Nil(t, scheduler.DispatchDue())
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestDispatchDue_CatalogDown_ReleasesClaimForRetry(t *testing.T) {
+	mock, scheduler, now := newTestScheduler(t, "40", http.StatusCreated)
+	scheduler.Orders.CatalogServiceAPI = "http://127.0.0.1:1/restaurants/"
+
+	expectClaim(t, mock, "KEEP_ORDER_PRICE", now.Add(30*time.Minute))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "orders" SET "status"=$1,"dispatch_claimed_at"=$2 WHERE "id" = $3`)).
+		WithArgs("SCHEDULED", nil, 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.Nil(t, scheduler.DispatchDue())
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestDispatchDue_AcceptedOrder_FulfillmentDown_KeepsClaimForRetry(t *testing.T) {
+	mock, scheduler, now := newTestScheduler(t, "40", http.StatusInternalServerError)
+
+	expectAcceptedClaim(t, mock, now.Add(-10*time.Minute))
+
+	assert.Nil(t, scheduler.DispatchDue())
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestDispatchDue_AcceptedOrder_FulfillmentDownTooLong_Cancels(t *testing.T) {
+	mock, scheduler, now := newTestScheduler(t, "40", http.StatusInternalServerError)
+
+	expectAcceptedClaim(t, mock, now.Add(-dispatchGiveUpAfter-time.Minute))
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "promotion_redemptions" WHERE order_id = $1`)).WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "promotion_id", "username", "order_id"}))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "orders" SET "status"=$1,"cancellation_reason"=$2 WHERE "id" = $3`)).
+		WithArgs("CANCELLED", sqlmock.AnyArg(), 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.Nil(t, scheduler.DispatchDue())
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestRejectExpired_RejectsAndReleasesPromotion(t *testing.T) {
+	mock, scheduler, now := newTestScheduler(t, "40", http.StatusCreated)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "orders" WHERE status = $1 AND accept_by <= $2 ORDER BY accept_by LIMIT $3 FOR UPDATE SKIP LOCKED`)).
+		WithArgs("AWAITING_ACCEPTANCE", now, schedulerBatchSize).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "restaurant_id", "username", "status", "accept_by"}).AddRow(7, "1", "username", "AWAITING_ACCEPTANCE", now.Add(-time.Minute)))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "promotion_redemptions" WHERE order_id = $1`)).WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "promotion_id", "username", "order_id"}).AddRow(9, 3, "username", 7))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "promotions" SET "usage_count"=usage_count - 1 WHERE id = $1 AND usage_count > 0`)).WithArgs(3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "promotion_redemptions" WHERE "promotion_redemptions"."id" = $1`)).WithArgs(9).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "orders" SET "status"=$1,"cancellation_reason"=$2 WHERE "id" = $3`)).
+		WithArgs("REJECTED", acceptTimeoutReason, 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.Nil(t, scheduler.RejectExpired())
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -12,20 +13,52 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"os"
+	"regexp"
+	"sort"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
-	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	"gorm.io/gorm"
 	database "orderService.com/go-orderService-grpc/db"
+	"orderService.com/go-orderService-grpc/eta"
+	"orderService.com/go-orderService-grpc/geo"
+	"orderService.com/go-orderService-grpc/hours"
+	"orderService.com/go-orderService-grpc/limits"
+	"orderService.com/go-orderService-grpc/loyalty"
 	"orderService.com/go-orderService-grpc/model"
+	"orderService.com/go-orderService-grpc/money"
+	"orderService.com/go-orderService-grpc/pricing"
 	o "orderService.com/go-orderService-grpc/proto/order"
 	u "orderService.com/go-orderService-grpc/proto/user"
+	"orderService.com/go-orderService-grpc/pubsub"
+	"orderService.com/go-orderService-grpc/quotes"
+	"orderService.com/go-orderService-grpc/surge"
+	"orderService.com/go-orderService-grpc/validation"
+	"orderService.com/go-orderService-grpc/zones"
 )
 
+const (
+	catalogService     = "catalog service"
+	fulfillmentService = "fulfillment service"
+	defaultCurrency    = "INR"
+	quoteTTL           = 5 * time.Minute
+	// defaultAcceptTimeout is how long a restaurant has to accept an order
+	// before it is rejected, unless ORDER_ACCEPT_TIMEOUT says otherwise.
+	defaultAcceptTimeout = 10 * time.Minute
+	minUsernameLength    = 3
+	maxUsernameLength    = 30
+)
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
 type UserServiceServer struct {
 	DB *gorm.DB
 	u.UserServiceServer
@@ -35,6 +68,30 @@ type OrderServiceServer struct {
 	DB                    *gorm.DB
 	CatalogServiceAPI     string
 	FulfillmentServiceAPI string
+	Pricing               *pricing.Engine
+	Quotes                *quotes.Signer
+	// Updates is told the id of every order whose state changed, and
+	// RestaurantUpdates the restaurant id of the order.
+	Updates           *pubsub.Hub[int64]
+	RestaurantUpdates *pubsub.Hub[string]
+	// Chats is told the id of every order whose chat got a message.
+	Chats *pubsub.Hub[int64]
+	// AcceptTimeout is how long restaurants have to accept an order,
+	// defaultAcceptTimeout if zero.
+	AcceptTimeout time.Duration
+	// ETA estimates delivery times. Orders get no estimate if nil.
+	ETA *eta.Estimator
+	// Zones limits where restaurants deliver. They deliver anywhere if nil.
+	Zones *zones.Checker
+	// Limits holds the minimum basket and maximums of each restaurant's
+	// orders. Orders aren't limited if nil.
+	Limits *limits.Checker
+	// Surge raises delivery fees where couriers are scarce. Fees never surge
+	// if nil.
+	Surge *surge.Tracker
+	// Loyalty sets the points earned on delivered orders and what they take
+	// off an order. No points are earned or spent if nil.
+	Loyalty *loyalty.Program
 	o.OrderServiceServer
 }
 
@@ -52,10 +109,135 @@ func createOrderServer() {
 	catalogServiceAPIUrl := "http://localhost:8080/api/v1/restaurants/"
 	fulfillmentServiceAPIUrl := "http://localhost:9090/api/v1/deliveries"
 
-	oServer := grpc.NewServer()
+	pricingConfig := pricing.DefaultConfig()
+	if path := os.Getenv("PRICING_CONFIG"); path != "" {
+		pricingConfig, err = pricing.LoadConfig(path)
+		if err != nil {
+			log.Fatalf("Failed to load pricing config %s: %v", path, err)
+		}
+	}
+
+	quoteSigningKey := []byte(os.Getenv("QUOTE_SIGNING_KEY"))
+	if len(quoteSigningKey) == 0 {
+		log.Println("QUOTE_SIGNING_KEY is not set, quotes will not survive a restart")
+		quoteSigningKey = make([]byte, 32)
+		if _, err := rand.Read(quoteSigningKey); err != nil {
+			log.Fatalf("Failed to generate quote signing key: %v", err)
+		}
+	}
+
+	acceptTimeout := defaultAcceptTimeout
+	if value := os.Getenv("ORDER_ACCEPT_TIMEOUT"); value != "" {
+		acceptTimeout, err = time.ParseDuration(value)
+		if err != nil || acceptTimeout <= 0 {
+			log.Fatalf("Invalid ORDER_ACCEPT_TIMEOUT %q, expected a positive duration such as 10m", value)
+		}
+	}
+
+	var centroids geo.Centroids
+	var estimator *eta.Estimator
+	if path := os.Getenv("ZIP_CENTROIDS"); path != "" {
+		centroids, err = geo.LoadCentroids(path)
+		if err != nil {
+			log.Fatalf("Failed to load ZIP centroids %s: %v", path, err)
+		}
+
+		etaConfig := eta.DefaultConfig()
+		if path := os.Getenv("ETA_CONFIG"); path != "" {
+			etaConfig, err = eta.LoadConfig(path)
+			if err != nil {
+				log.Fatalf("Failed to load ETA config %s: %v", path, err)
+			}
+		}
+
+		estimator = eta.NewEstimator(centroids, etaConfig)
+	} else {
+		log.Println("ZIP_CENTROIDS is not set, orders will have no delivery estimates")
+	}
+
+	var deliveryZones *zones.Checker
+	if path := os.Getenv("DELIVERY_ZONES"); path != "" {
+		zonesConfig, err := zones.LoadConfig(path)
+		if err != nil {
+			log.Fatalf("Failed to load delivery zones %s: %v", path, err)
+		}
+
+		deliveryZones = zones.NewChecker(centroids, zonesConfig)
+	} else {
+		log.Println("DELIVERY_ZONES is not set, restaurants will deliver anywhere")
+	}
+
+	var orderLimits *limits.Checker
+	if path := os.Getenv("ORDER_LIMITS"); path != "" {
+		limitsConfig, err := limits.LoadConfig(path)
+		if err != nil {
+			log.Fatalf("Failed to load order limits %s: %v", path, err)
+		}
+
+		orderLimits = limits.NewChecker(limitsConfig)
+	} else {
+		log.Println("ORDER_LIMITS is not set, orders will not be limited")
+	}
+
+	var surgeTracker *surge.Tracker
+	if path := os.Getenv("SURGE_CONFIG"); path != "" {
+		surgeConfig, err := surge.LoadConfig(path)
+		if err != nil {
+			log.Fatalf("Failed to load surge config %s: %v", path, err)
+		}
+
+		surgeTracker = surge.NewTracker(surgeConfig)
+	} else {
+		log.Println("SURGE_CONFIG is not set, delivery fees will not surge")
+	}
+
+	loyaltyConfig := loyalty.DefaultConfig()
+	if path := os.Getenv("LOYALTY_CONFIG"); path != "" {
+		loyaltyConfig, err = loyalty.LoadConfig(path)
+		if err != nil {
+			log.Fatalf("Failed to load loyalty config %s: %v", path, err)
+		}
+	}
+
+	oServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(errorMappingInterceptor, validationInterceptor),
+		grpc.ChainStreamInterceptor(errorMappingStreamInterceptor, validationStreamInterceptor),
+	)
 	db := database.Connection()
 
-	o.RegisterOrderServiceServer(oServer, &OrderServiceServer{DB: db, CatalogServiceAPI: catalogServiceAPIUrl, FulfillmentServiceAPI: fulfillmentServiceAPIUrl})
+	orderServer := &OrderServiceServer{
+		DB:                    db,
+		CatalogServiceAPI:     catalogServiceAPIUrl,
+		FulfillmentServiceAPI: fulfillmentServiceAPIUrl,
+		Pricing:               pricing.NewEngine(pricingConfig),
+		Quotes:                quotes.NewSigner(quoteSigningKey, quoteTTL),
+		Updates:               pubsub.NewHub[int64](),
+		RestaurantUpdates:     pubsub.NewHub[string](),
+		Chats:                 pubsub.NewHub[int64](),
+		AcceptTimeout:         acceptTimeout,
+		ETA:                   estimator,
+		Zones:                 deliveryZones,
+		Limits:                orderLimits,
+		Surge:                 surgeTracker,
+		Loyalty:               loyalty.NewProgram(loyaltyConfig),
+	}
+
+	o.RegisterOrderServiceServer(oServer, orderServer)
+	o.RegisterCartServiceServer(oServer, &CartServiceServer{Orders: orderServer})
+	o.RegisterLoyaltyServiceServer(oServer, &LoyaltyServiceServer{Orders: orderServer})
+	o.RegisterWalletServiceServer(oServer, &WalletServiceServer{Orders: orderServer})
+	o.RegisterWalletAdminServiceServer(oServer, &WalletAdminServiceServer{Orders: orderServer})
+	o.RegisterGroupOrderServiceServer(oServer, &GroupOrderServiceServer{Orders: orderServer})
+	o.RegisterRestaurantServiceServer(oServer, &RestaurantServiceServer{Orders: orderServer})
+
+	go NewOrderScheduler(orderServer).Run(context.Background())
+
+	if secret := os.Getenv("FULFILLMENT_WEBHOOK_SECRET"); secret != "" {
+		go serveFulfillmentWebhook(orderServer, []byte(secret))
+	} else {
+		log.Println("FULFILLMENT_WEBHOOK_SECRET is not set, delivery status callbacks are disabled")
+	}
+
 	err = oServer.Serve(lis2)
 	if err != nil {
 		log.Fatalf("Failed to serve 8002: %v", err)
@@ -68,7 +250,7 @@ func createUserServer() {
 		log.Fatalf("Failed to listen: 8001, %v", err)
 	}
 
-	uServer := grpc.NewServer()
+	uServer := grpc.NewServer(grpc.ChainUnaryInterceptor(errorMappingInterceptor, validationInterceptor))
 	db := database.Connection()
 
 	u.RegisterUserServiceServer(uServer, &UserServiceServer{DB: db})
@@ -78,13 +260,49 @@ func createUserServer() {
 	}
 }
 
-func (userServer *UserServiceServer) Register(_ context.Context, req *u.RegisterUserRequest) (*u.RegisterUserResponse, error) {
-	if req.Username == "" || req.Password == "" || req.Address == nil {
-		return nil, status.Errorf(codes.InvalidArgument, "Missing required user fields")
+// validationInterceptor rejects requests that break the (validate.rules)
+// constraints declared in the proto files before they reach a handler.
+func validationInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := validateRequest(req); err != nil {
+		return nil, err
 	}
 
-	if req.Address.City == "" || req.Address.Street == "" || req.Address.State == "" || req.Address.Zipcode == "" {
-		return nil, status.Errorf(codes.InvalidArgument, "Invalid address data")
+	return handler(ctx, req)
+}
+
+// validationStreamInterceptor is validationInterceptor for streaming RPCs,
+// checking every message the client sends.
+func validationStreamInterceptor(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, validatingStream{stream})
+}
+
+type validatingStream struct {
+	grpc.ServerStream
+}
+
+func (stream validatingStream) RecvMsg(m any) error {
+	if err := stream.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	return validateRequest(m)
+}
+
+func validateRequest(req any) error {
+	if msg, ok := req.(proto.Message); ok {
+		if violations := validation.Validate(msg); len(violations) > 0 {
+			return errInvalidArgument("Invalid request", violations...)
+		}
+	}
+
+	return nil
+}
+
+func (userServer *UserServiceServer) Register(_ context.Context, req *u.RegisterUserRequest) (*u.RegisterUserResponse, error) {
+	// The interceptor requires it too, but users without an address can't be
+	// delivered to, so Register never stores one whoever calls it.
+	if req.Address == nil {
+		return nil, errInvalidArgument("Invalid request", fieldViolation("address", "value is required"))
 	}
 
 	address := &model.Address{
@@ -97,7 +315,7 @@ func (userServer *UserServiceServer) Register(_ context.Context, req *u.Register
 	hashedPassword, err := HashPassword(req.Password)
 
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "Error hashing password")
+		return nil, errInternal("Error hashing password")
 	}
 
 	user := &model.User{
@@ -108,9 +326,12 @@ func (userServer *UserServiceServer) Register(_ context.Context, req *u.Register
 
 	err = userServer.DB.Create(&user).Error
 
+	if isUniqueViolation(err) {
+		return nil, errUsernameTaken(req.Username)
+	}
+
 	if err != nil {
-		errorString := fmt.Sprintf("error storing the user: %v", err)
-		return nil, status.Errorf(codes.Unknown, errorString)
+		return nil, toStatusError(fmt.Errorf("error storing the user: %w", err))
 	}
 
 	response := &u.RegisterUserResponse{
@@ -122,93 +343,329 @@ func (userServer *UserServiceServer) Register(_ context.Context, req *u.Register
 	return response, nil
 }
 
+// validateUsername returns a description of what is wrong with the username,
+// or an empty string when it is acceptable.
+func validateUsername(username string) string {
+	if username == "" {
+		return "username is required"
+	}
+
+	length := utf8.RuneCountInString(username)
+	if length < minUsernameLength || length > maxUsernameLength {
+		return fmt.Sprintf("username must be between %d and %d characters", minUsernameLength, maxUsernameLength)
+	}
+
+	if !usernamePattern.MatchString(username) {
+		return "username may only contain letters, digits, '.', '_' and '-' and must start with a letter or digit"
+	}
+
+	return ""
+}
+
+func (userServer *UserServiceServer) CheckUsernameAvailability(_ context.Context, req *u.CheckUsernameAvailabilityRequest) (*u.CheckUsernameAvailabilityResponse, error) {
+	response := &u.CheckUsernameAvailabilityResponse{Username: req.Username}
+
+	if problem := validateUsername(req.Username); problem != "" {
+		response.Reason = problem
+		return response, nil
+	}
+
+	taken, err := database.IsUsernameTaken(userServer.DB, req.Username)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	if taken {
+		response.Reason = "username is already taken"
+		return response, nil
+	}
+
+	response.Available = true
+	return response, nil
+}
+
 func HashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
 	return string(bytes), err
 }
 
 func (orderServer *OrderServiceServer) Create(ctx context.Context, req *o.CreateOrderRequest) (*o.CreateOrderResponse, error) {
-	username, password, ok := extractCredentials(ctx)
-	if !ok {
-		return nil, status.Errorf(codes.Unauthenticated, "Credentials not found")
+	user, err := orderServer.authenticate(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	user, err := database.GetUserByUsername(orderServer.DB, username)
+	return orderServer.placeOrder(user, req, nil)
+}
+
+// placeOrder prices and stores an order. Orders for now wait for the
+// restaurant to accept them, scheduled ones for the scheduler. inTransaction,
+// if set, runs in the transaction that stores the order, once the order has
+// its id.
+func (orderServer *OrderServiceServer) placeOrder(user *model.User, req *o.CreateOrderRequest, inTransaction func(tx *gorm.DB, order *model.Order) error) (*o.CreateOrderResponse, error) {
+	now := time.Now()
+
+	scheduledFor, err := scheduledTime(req.ScheduledFor, now)
 	if err != nil {
-		return nil, status.Errorf(codes.NotFound, err.Error())
+		return nil, err
 	}
 
-	res, err := isAuthenticated(user.Password, password)
-
+	var priced *pricedOrder
+	if req.QuoteToken != "" {
+		priced, err = orderServer.pricedFromQuote(user, req)
+	} else {
+		priced, err = orderServer.priceOrder(user, req)
+	}
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "Error while decrypting password")
+		return nil, toStatusError(err)
 	}
 
-	if !res {
-		return nil, status.Errorf(codes.InvalidArgument, "Unauthorized: Invalid username or password")
+	if err := orderServer.checkServiceability(req.RestaurantId, priced.restaurantAddress, user.Address); err != nil {
+		return nil, toStatusError(err)
+	}
+
+	if err := orderServer.checkLimits(req.RestaurantId, priced); err != nil {
+		return nil, toStatusError(err)
 	}
 
-	totalPrice, err := calculateOrderTotal(req, orderServer.CatalogServiceAPI)
+	// Scheduled orders must be for when the restaurant is open.
+	cookAt := now
+	if scheduledFor != nil {
+		cookAt = *scheduledFor
+	}
+
+	if !priced.restaurantHours.OpenAt(cookAt) {
+		return nil, toStatusError(errRestaurantClosed(req.RestaurantId, cookAt, priced.restaurantHours))
+	}
+
+	breakdown := priced.breakdown
+
+	paidFromWallet, err := walletAmount(req.WalletAmount, breakdown.Total)
 	if err != nil {
-		return nil, status.Errorf(codes.Unknown, err.Error())
+		return nil, toStatusError(err)
+	}
+
+	order := &model.Order{
+		Username:          user.Username,
+		RestaurantId:      req.RestaurantId,
+		Currency:          breakdown.Total.Currency,
+		SubtotalAmount:    breakdown.Subtotal.Amount,
+		TaxAmount:         breakdown.Tax.Amount,
+		TaxBasisPoints:    breakdown.TaxBasisPoints,
+		DeliveryFeeAmount: breakdown.DeliveryFee.Amount,
+		ServiceFeeAmount:  breakdown.ServiceFee.Amount,
+		TipAmount:         breakdown.Tip.Amount,
+		DiscountAmount:    breakdown.Discount.Amount,
+		TotalAmount:       breakdown.Total.Amount,
+		PromoCode:         priced.promoCode,
+		Items:             priced.items,
+		SurgeBasisPoints:  breakdown.SurgeBasisPoints,
+
+		LoyaltyPointsRedeemed: priced.loyaltyPoints,
+		LoyaltyDiscountAmount: priced.loyaltyDiscount,
+		WalletAmount:          paidFromWallet,
+
+		PromotionDiscountAmount: priced.promotionDiscount,
 	}
 
-	itemsByte, err := json.Marshal(req.MenuItems)
+	if meters, ok := orderServer.ETA.Distance(priced.restaurantAddress, user.Address); ok {
+		order.DeliveryDistanceMeters = &meters
+	}
+
+	if scheduledFor == nil {
+		acceptBy := now.Add(orderServer.acceptTimeout())
+		order.Status = model.OrderAwaitingAcceptance
+		order.AcceptBy = &acceptBy
+
+		if order.DeliveryDistanceMeters != nil {
+			estimate := orderServer.ETA.Estimate(now, orderServer.ETA.DefaultPrep(), *order.DeliveryDistanceMeters)
+			order.EstimatedDeliveryAt = &estimate
+		}
+	} else {
+		order.Status = model.OrderScheduled
+		order.ScheduledFor = scheduledFor
+		order.PriceChangePolicy = priceChangePolicies[req.PriceChangePolicy]
+	}
+
+	// The order, its items, the promotion redemption and what it is paid
+	// with are stored in a single transaction.
+	err = orderServer.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&order).Error; err != nil {
+			return fmt.Errorf("error storing the order: %w", err)
+		}
+
+		if priced.promotionId != 0 {
+			if err := redeemPromotion(tx, priced.promotionId, order); err != nil {
+				return err
+			}
+		}
+
+		if order.LoyaltyPointsRedeemed > 0 {
+			if err := redeemLoyaltyPoints(tx, order, now); err != nil {
+				return err
+			}
+		}
+
+		if order.WalletAmount > 0 {
+			if err := payWithWallet(tx, order); err != nil {
+				return err
+			}
+		}
+
+		if inTransaction != nil {
+			return inTransaction(tx, order)
+		}
+
+		return nil
+	})
 	if err != nil {
-		return nil, status.Errorf(codes.Unknown, err.Error())
+		return nil, toStatusError(err)
 	}
 
-	itemStr := string(itemsByte)
+	if order.Status == model.OrderAwaitingAcceptance {
+		orderServer.Surge.Opened(order.Id, priced.restaurantAddress)
+	}
 
-	order := &model.Order{
-		Username:     username,
+	orderServer.publishUpdate(order)
+
+	response := &o.CreateOrderResponse{
+		Id:           order.Id,
+		Username:     user.Username,
 		RestaurantId: req.RestaurantId,
-		MenuItems:    itemStr,
-		TotalPrice:   totalPrice,
+		MenuItems:    req.MenuItems,
+		TotalPrice:   breakdown.Total.Major(),
+		LineItems:    toLineItems(order.Items, order.Currency),
+		Total:        toProtoMoney(breakdown.Total),
+		Breakdown:    toProtoBreakdown(breakdown),
+		PromoCode:    order.PromoCode,
+		Status:       orderStatus(order),
+
+		LoyaltyPointsRedeemed: order.LoyaltyPointsRedeemed,
+		WalletPaid:            walletPaid(order),
 	}
 
-	err = orderServer.DB.Create(&order).Error
-	if err != nil {
-		errorString := fmt.Sprintf("error storing the order: %v", err)
-		return nil, status.Errorf(codes.Unknown, errorString)
+	if order.ScheduledFor != nil {
+		response.ScheduledFor = timestamppb.New(*order.ScheduledFor)
 	}
 
-	restaurantAddress, err := fetchRestaurantAddress(req.RestaurantId, orderServer.CatalogServiceAPI)
-	if err != nil {
-		return nil, status.Errorf(codes.Canceled, err.Error())
+	if order.EstimatedDeliveryAt != nil {
+		response.EstimatedDeliveryAt = timestamppb.New(*order.EstimatedDeliveryAt)
 	}
 
+	return response, nil
+}
+
+// dispatch asks the fulfillment service to deliver an order. The service
+// answers 409 for an order it already has, so a repeated dispatch is reported
+// as errOrderAlreadyAssigned rather than delivered twice.
+func (orderServer *OrderServiceServer) dispatch(order *model.Order, dropAddress *model.Address, pickupAddress *model.Address) error {
 	requestBody, _ := json.Marshal(map[string]any{
 		"orderId":       order.Id,
-		"dropAddress":   user.Address,
-		"pickupAddress": restaurantAddress,
+		"dropAddress":   dropAddress,
+		"pickupAddress": pickupAddress,
 	})
 
 	reqBody := bytes.NewBuffer(requestBody)
 
 	resp, err := http.Post(orderServer.FulfillmentServiceAPI, "application/json", reqBody)
+	if err != nil {
+		return errUpstreamUnavailable(fulfillmentService, err)
+	}
+	defer resp.Body.Close()
 
 	switch resp.StatusCode {
 	case http.StatusConflict:
-		return nil, status.Errorf(codes.Aborted, "OrderAlreadyAssignedException: %s", parseResponse(resp.Body))
+		return errOrderAlreadyAssigned(order.Id, parseResponse(resp.Body))
 	case http.StatusNotFound:
-		return nil, status.Errorf(codes.NotFound, "NoDeliveryExecutiveNearbyException: %s", parseResponse(resp.Body))
+		return errNoDeliveryExecutiveNearby(order.Id, parseResponse(resp.Body))
 	case http.StatusInternalServerError:
-		log.Println("inside")
-		return nil, status.Errorf(codes.Internal, "Internal Server Error: %s", parseResponse(resp.Body))
+		return errUpstreamStatus(fulfillmentService, resp.StatusCode, parseResponse(resp.Body))
 	default:
-		break
+		return nil
 	}
+}
 
-	response := &o.CreateOrderResponse{
-		Id:           order.Id,
-		Username:     username,
-		RestaurantId: req.RestaurantId,
-		MenuItems:    req.MenuItems,
-		TotalPrice:   totalPrice,
+// pricedOrder is an order that has been priced, but not stored yet.
+type pricedOrder struct {
+	items       []model.OrderItem
+	breakdown   pricing.Breakdown
+	promotionId int64
+	promoCode   string
+	// promotionDiscount is what the promotion took off the items.
+	promotionDiscount int64
+	// loyaltyPoints were spent, taking loyaltyDiscount off the items.
+	loyaltyPoints   int64
+	loyaltyDiscount int64
+	// restaurantAddress is the pickup address the order was priced for.
+	restaurantAddress *model.Address
+	restaurantHours   *hours.Hours
+}
+
+// priceOrder prices the requested items against the catalog and applies the
+// promo code, fees, taxes and tip. Create and QuoteOrder both go through it.
+func (orderServer *OrderServiceServer) priceOrder(user *model.User, req *o.CreateOrderRequest) (*pricedOrder, error) {
+	items, subtotal, err := calculateOrderTotal(req, orderServer.CatalogServiceAPI)
+	if err != nil {
+		return nil, err
 	}
 
-	return response, nil
+	restaurant, err := fetchRestaurant(req.RestaurantId, orderServer.CatalogServiceAPI)
+	if err != nil {
+		return nil, err
+	}
+
+	promotion, discount, err := applyPromotion(orderServer.DB, req.PromoCode, user.Username, req.RestaurantId, items, subtotal)
+	if err != nil {
+		return nil, err
+	}
+
+	// Loyalty points come off what the items cost after the promotion.
+	promotionDiscount := min(discount.Items.Amount, subtotal.Amount)
+	itemDiscount := discount.Items
+	var loyaltyPoints int64
+	var loyaltyDiscount money.Money
+	if req.RedeemPoints > 0 {
+		available, err := availableLoyaltyPoints(orderServer.DB, user.Username, time.Now())
+		if err != nil {
+			return nil, err
+		}
+
+		if available < req.RedeemPoints {
+			return nil, errInsufficientLoyaltyPoints(req.RedeemPoints, available)
+		}
+
+		loyaltyPoints, loyaltyDiscount = orderServer.Loyalty.Redeem(req.RedeemPoints, money.New(subtotal.Amount-promotionDiscount, subtotal.Currency))
+		itemDiscount = money.New(promotionDiscount+loyaltyDiscount.Amount, subtotal.Currency)
+	}
+
+	breakdown, err := orderServer.pricingEngine().Price(pricing.Input{
+		Subtotal:         subtotal,
+		DropAddress:      user.Address,
+		PickupAddress:    restaurant.Address,
+		Tip:              fromProtoMoney(req.Tip),
+		ItemDiscount:     itemDiscount,
+		FreeDelivery:     discount.FreeDelivery,
+		SurgeBasisPoints: orderServer.Surge.Multiplier(restaurant.Address),
+	})
+	if err != nil {
+		return nil, errInvalidArgument("Invalid tip", fieldViolation("tip", err.Error()))
+	}
+
+	priced := &pricedOrder{
+		items:             items,
+		breakdown:         breakdown,
+		loyaltyPoints:     loyaltyPoints,
+		loyaltyDiscount:   loyaltyDiscount.Amount,
+		restaurantAddress: restaurant.Address,
+		restaurantHours:   restaurant.Hours,
+	}
+	if promotion != nil {
+		priced.promotionId = promotion.Id
+		priced.promoCode = promotion.Code
+		priced.promotionDiscount = promotionDiscount
+	}
+
+	return priced, nil
 }
 
 func parseResponse(body io.Reader) string {
@@ -220,15 +677,35 @@ func parseResponse(body io.Reader) string {
 }
 
 func fetchRestaurantAddress(restaurantId string, url string) (*model.Address, error) {
+	restaurant, err := fetchRestaurant(restaurantId, url)
+	if err != nil {
+		return nil, err
+	}
+
+	return restaurant.Address, nil
+}
+
+// catalogRestaurant is what orders need to know of a restaurant. Hours is
+// nil for restaurants the catalog lists no opening hours for.
+type catalogRestaurant struct {
+	Address *model.Address
+	Hours   *hours.Hours
+}
+
+func fetchRestaurant(restaurantId string, url string) (*catalogRestaurant, error) {
 	// apiStr := fmt.Sprintf("http://localhost:8080/api/v1/restaurants/%v", r)
 	apiStr := fmt.Sprintf(url + restaurantId)
-	resp, _ := http.Get(apiStr)
+	resp, err := http.Get(apiStr)
+	if err != nil {
+		return nil, errUpstreamUnavailable(catalogService, err)
+	}
+	defer resp.Body.Close()
 
 	switch resp.StatusCode {
 	case http.StatusNotFound:
-		return nil, status.Errorf(codes.NotFound, "NoSuchElementException: %s", parseResponse(resp.Body))
+		return nil, errRestaurantNotFound(restaurantId, parseResponse(resp.Body))
 	case http.StatusInternalServerError:
-		return nil, status.Errorf(codes.Internal, "Internal Server Error: %s", parseResponse(resp.Body))
+		return nil, errUpstreamStatus(catalogService, resp.StatusCode, parseResponse(resp.Body))
 	default:
 		break
 	}
@@ -238,7 +715,10 @@ func fetchRestaurantAddress(restaurantId string, url string) (*model.Address, er
 	var response struct {
 		Data struct {
 			Restaurant struct {
-				Address *model.Address `json:"address"`
+				Address          *model.Address            `json:"address"`
+				TimeZone         string                    `json:"time_zone"`
+				OpeningHours     map[string][]hours.Window `json:"opening_hours"`
+				HolidayOverrides []hours.Holiday           `json:"holiday_overrides"`
 			} `json:"restaurant"`
 		} `json:"data"`
 	}
@@ -247,7 +727,43 @@ func fetchRestaurantAddress(restaurantId string, url string) (*model.Address, er
 		return nil, err
 	}
 
-	return response.Data.Restaurant.Address, nil
+	restaurant := response.Data.Restaurant
+	openingHours, err := hours.Parse(hours.Schedule{TimeZone: restaurant.TimeZone, Weekly: restaurant.OpeningHours, Holidays: restaurant.HolidayOverrides})
+	if err != nil {
+		return nil, errUpstreamStatus(catalogService, resp.StatusCode, "invalid opening hours: "+err.Error())
+	}
+
+	return &catalogRestaurant{Address: restaurant.Address, Hours: openingHours}, nil
+}
+
+// authenticate checks the Basic credentials sent with the request and returns
+// the matching user.
+func (orderServer *OrderServiceServer) authenticate(ctx context.Context) (*model.User, error) {
+	username, password, ok := extractCredentials(ctx)
+	if !ok {
+		return nil, errMissingCredentials()
+	}
+
+	user, err := database.GetUserByUsername(orderServer.DB, username)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errUserNotFound(username)
+	}
+
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	res, err := isAuthenticated(user.Password, password)
+
+	if err != nil {
+		return nil, errInternal("Error while decrypting password")
+	}
+
+	if !res {
+		return nil, errInvalidCredentials()
+	}
+
+	return user, nil
 }
 
 func isAuthenticated(storedHashPassword, password string) (bool, error) {
@@ -288,36 +804,263 @@ func extractCredentials(ctx context.Context) (string, string, bool) {
 	return credentials[0], credentials[1], true
 }
 
-func calculateOrderTotal(req *o.CreateOrderRequest, url string) (float64, error) {
+// calculateOrderTotal prices every requested line against the catalog and
+// returns the resulting order lines, sorted by name, along with their total.
+// Chosen options must fit the menu item's modifier groups, all items must be
+// priced in the same currency, and be available in the requested quantity;
+// errItemsUnavailable lists every item that isn't.
+func calculateOrderTotal(req *o.CreateOrderRequest, url string) ([]model.OrderItem, money.Money, error) {
 	restaurantID := req.RestaurantId
-	total := 0.0
+	total := money.Money{}
+
+	lines, err := requestedLines(req)
+	if err != nil {
+		return nil, money.Money{}, err
+	}
+
+	items := make([]model.OrderItem, 0, len(lines))
+	menuItems := make(map[string]*catalogMenuItem, len(lines))
+	var menuItemNames []string
+	quantities := make(map[string]int32, len(lines))
+	var invalid []*errdetails.BadRequest_FieldViolation
+
+	for _, line := range lines {
+		menuItem, fetched := menuItems[line.menuItem]
+		if !fetched {
+			menuItem, err = fetchMenuItem(url, restaurantID, line.menuItem)
+			if err != nil {
+				return nil, money.Money{}, err
+			}
+
+			menuItems[line.menuItem] = menuItem
+			menuItemNames = append(menuItemNames, line.menuItem)
+		}
+		quantities[line.menuItem] += line.quantity
 
-	for menuItemName, quantity := range req.MenuItems {
-		apiString := fmt.Sprintf(url + restaurantID + "/menuItems/" + menuItemName)
+		item, violation := menuItem.orderItem(line)
+		if violation != nil {
+			invalid = append(invalid, violation)
+			continue
+		}
 
-		resp, _ := http.Get(apiString)
+		sum, err := total.Add(money.New(item.LineAmount, menuItem.Price.Currency))
+		if errors.Is(err, money.ErrCurrencyMismatch) {
+			return nil, money.Money{}, errMixedCurrencies(restaurantID, total.Currency, menuItem.Price.Currency)
+		}
+		total = sum
 
-		if resp.StatusCode != http.StatusOK {
-			return 0.0, errors.New("item not found")
+		items = append(items, item)
+	}
+
+	if len(invalid) > 0 {
+		return nil, money.Money{}, errInvalidArgument("Invalid modifier options", invalid...)
+	}
+
+	var unavailable []*errdetails.PreconditionFailure_Violation
+	for _, menuItemName := range menuItemNames {
+		menuItem, quantity := menuItems[menuItemName], quantities[menuItemName]
+
+		switch {
+		case !menuItem.Available:
+			unavailable = append(unavailable, itemViolation(violationItemUnavailable, menuItemName, "not available right now"))
+		case menuItem.Stock != nil && *menuItem.Stock < quantity:
+			unavailable = append(unavailable, itemViolation(violationOutOfStock, menuItemName, fmt.Sprintf("only %d left", max(*menuItem.Stock, 0))))
 		}
+	}
+
+	if len(unavailable) > 0 {
+		return nil, money.Money{}, errItemsUnavailable(restaurantID, unavailable)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool { return items[i].MenuItemKey < items[j].MenuItemKey })
+
+	return items, total, nil
+}
+
+// catalogMenuItem is a menu item as the catalog lists it. Stock is nil for
+// items that don't run out.
+type catalogMenuItem struct {
+	Id             string
+	Name           string
+	Price          money.Money
+	Available      bool
+	Stock          *int32
+	ModifierGroups []modifierGroup
+}
+
+// modifierGroup is a choice to make about a menu item, such as its size.
+// Between Min and Max of its options may be chosen; Max 0 sets no limit.
+type modifierGroup struct {
+	Id      string
+	Name    string
+	Min     int
+	Max     int
+	Options []modifierOption
+}
 
-		body := parseResponse(resp.Body)
+// modifierOption is priced in the currency of its menu item.
+type modifierOption struct {
+	Id    string
+	Name  string
+	Price money.Money
+}
+
+// fetchMenuItem looks up the catalog id, current price and availability of a
+// menu item. Items the catalog doesn't flag are available.
+func fetchMenuItem(url string, restaurantId string, menuItemName string) (*catalogMenuItem, error) {
+	apiString := fmt.Sprintf(url + restaurantId + "/menuItems/" + menuItemName)
+
+	resp, err := http.Get(apiString)
+	if err != nil {
+		return nil, errUpstreamUnavailable(catalogService, err)
+	}
+
+	body := parseResponse(resp.Body)
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, errMenuItemNotFound(restaurantId, menuItemName)
+	case resp.StatusCode != http.StatusOK:
+		return nil, errUpstreamStatus(catalogService, resp.StatusCode, body)
+	}
 
-		var response struct {
-			Data struct {
-				MenuItem struct {
-					Price float64 `json:"price"`
-				} `json:"menu_item"`
-			} `json:"data"`
+	var response struct {
+		Data struct {
+			MenuItem struct {
+				Id             json.RawMessage `json:"id"`
+				Name           string          `json:"name"`
+				Price          json.Number     `json:"price"`
+				Currency       string          `json:"currency"`
+				Available      *bool           `json:"available"`
+				Stock          *int32          `json:"stock"`
+				ModifierGroups []struct {
+					Id            string `json:"id"`
+					Name          string `json:"name"`
+					MinSelections int    `json:"min_selections"`
+					MaxSelections int    `json:"max_selections"`
+					Options       []struct {
+						Id    string      `json:"id"`
+						Name  string      `json:"name"`
+						Price json.Number `json:"price"`
+					} `json:"options"`
+				} `json:"modifier_groups"`
+			} `json:"menu_item"`
+		} `json:"data"`
+	}
+
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		return nil, err
+	}
+
+	currency := response.Data.MenuItem.Currency
+	if currency == "" {
+		currency = defaultCurrency
+	}
+
+	price, err := money.Parse(response.Data.MenuItem.Price.String(), currency)
+	if err != nil {
+		return nil, errUpstreamStatus(catalogService, resp.StatusCode, err.Error())
+	}
+
+	menuItem := &catalogMenuItem{
+		Id:        strings.Trim(string(response.Data.MenuItem.Id), `"`),
+		Name:      response.Data.MenuItem.Name,
+		Price:     price,
+		Available: response.Data.MenuItem.Available == nil || *response.Data.MenuItem.Available,
+		Stock:     response.Data.MenuItem.Stock,
+	}
+
+	for _, group := range response.Data.MenuItem.ModifierGroups {
+		modifiers := modifierGroup{Id: group.Id, Name: group.Name, Min: group.MinSelections, Max: group.MaxSelections}
+
+		for _, option := range group.Options {
+			optionPrice := money.New(0, currency)
+			if option.Price != "" {
+				optionPrice, err = money.Parse(option.Price.String(), currency)
+				if err != nil {
+					return nil, errUpstreamStatus(catalogService, resp.StatusCode, err.Error())
+				}
+			}
+
+			modifiers.Options = append(modifiers.Options, modifierOption{Id: option.Id, Name: option.Name, Price: optionPrice})
+		}
+
+		menuItem.ModifierGroups = append(menuItem.ModifierGroups, modifiers)
+	}
+
+	return menuItem, nil
+}
+
+func toLineItems(items []model.OrderItem, currency string) []*o.OrderLineItem {
+	lineItems := make([]*o.OrderLineItem, 0, len(items))
+
+	for _, item := range items {
+		lineItem := &o.OrderLineItem{
+			MenuItemId:   item.MenuItemId,
+			Name:         item.MenuItemName,
+			Quantity:     item.Quantity,
+			UnitPrice:    toProtoMoney(money.New(item.UnitAmount, currency)),
+			LineTotal:    toProtoMoney(money.New(item.LineAmount, currency)),
+			Instructions: item.Instructions,
 		}
 
-		if err := json.Unmarshal([]byte(body), &response); err != nil {
-			return 0.0, err
+		for _, option := range item.Options {
+			lineItem.Modifiers = append(lineItem.Modifiers, &o.SelectedModifier{
+				GroupId:  option.GroupId,
+				OptionId: option.OptionId,
+				Name:     option.Name,
+				Price:    toProtoMoney(money.New(option.PriceAmount, currency)),
+			})
 		}
 
-		price := response.Data.MenuItem.Price
-		total += price * float64(quantity)
+		lineItems = append(lineItems, lineItem)
+	}
+
+	return lineItems
+}
+
+func toProtoMoney(amount money.Money) *o.Money {
+	return &o.Money{CurrencyCode: amount.Currency, AmountMinor: amount.Amount}
+}
+
+func fromProtoMoney(amount *o.Money) money.Money {
+	if amount == nil {
+		return money.Money{}
+	}
+
+	return money.New(amount.AmountMinor, strings.ToUpper(amount.CurrencyCode))
+}
+
+func toProtoBreakdown(breakdown pricing.Breakdown) *o.PriceBreakdown {
+	return &o.PriceBreakdown{
+		Subtotal:           toProtoMoney(breakdown.Subtotal),
+		Discount:           toProtoMoney(breakdown.Discount),
+		Tax:                toProtoMoney(breakdown.Tax),
+		TaxRateBasisPoints: breakdown.TaxBasisPoints,
+		DeliveryFee:        toProtoMoney(breakdown.DeliveryFee),
+		SurgeBasisPoints:   breakdown.SurgeBasisPoints,
+		ServiceFee:         toProtoMoney(breakdown.ServiceFee),
+		Tip:                toProtoMoney(breakdown.Tip),
+		Total:              toProtoMoney(breakdown.Total),
+	}
+}
+
+// acceptTimeout falls back to defaultAcceptTimeout when none was configured.
+func (orderServer *OrderServiceServer) acceptTimeout() time.Duration {
+	if orderServer.AcceptTimeout <= 0 {
+		return defaultAcceptTimeout
+	}
+
+	return orderServer.AcceptTimeout
+}
+
+// pricingEngine falls back to an engine without taxes or fees when none was
+// configured.
+func (orderServer *OrderServiceServer) pricingEngine() *pricing.Engine {
+	if orderServer.Pricing == nil {
+		return pricing.NewEngine(pricing.Config{})
 	}
 
-	return total, nil
+	return orderServer.Pricing
 }
@@ -2,18 +2,31 @@ package main
 
 import (
 	"context"
+	"database/sql/driver"
+	"encoding/base64"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 
+	"orderService.com/go-orderService-grpc/eta"
+	"orderService.com/go-orderService-grpc/geo"
+	"orderService.com/go-orderService-grpc/pricing"
 	o "orderService.com/go-orderService-grpc/proto/order"
 	u "orderService.com/go-orderService-grpc/proto/user"
 )
@@ -38,6 +51,84 @@ func setupTestDB(t *testing.T) (sqlmock.Sqlmock, *UserServiceServer) {
 	return mock, userServer
 }
 
+func openMockDB(t *testing.T) (sqlmock.Sqlmock, *gorm.DB) {
+	mockDB, mock, err := sqlmock.New()
+	assert.Nil(t, err, "Failed to create mock DB: %v", err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	dialect := postgres.New(postgres.Config{
+		Conn:       mockDB,
+		DriverName: "postgres",
+	})
+
+	gormDb, err := gorm.Open(dialect, &gorm.Config{})
+	assert.Nil(t, err, "Failed to open GORM DB: %v", err)
+
+	return mock, gormDb
+}
+
+// orderColumns are expected values of an order insert, by column.
+type orderColumns map[string]driver.Value
+
+// orderInsertColumns are the columns of an order insert, in the order GORM
+// sends them.
+var orderInsertColumns = []string{
+	"restaurant_id", "username", "currency", "subtotal_amount", "tax_amount", "tax_basis_points", "delivery_fee_amount",
+	"service_fee_amount", "tip_amount", "discount_amount", "promo_code", "total_amount", "status", "scheduled_for",
+	"price_change_policy", "dispatch_claimed_at", "dispatched_at", "cancellation_reason", "estimated_delivery_at",
+	"courier_name", "courier_phone", "accept_by", "accepted_at", "prep_time_minutes", "courier_username",
+	"delivery_distance_meters", "surge_basis_points", "loyalty_points_redeemed", "loyalty_discount_amount", "wallet_amount",
+	"promotion_discount_amount",
+}
+
+// placedOrder is the order most tests place: two naan at 40 INR for
+// "username", awaiting acceptance, without fees, discounts or estimates.
+var placedOrder = orderColumns{
+	"restaurant_id": "1", "username": "username", "currency": "INR", "subtotal_amount": 8000, "tax_amount": 0,
+	"tax_basis_points": 0, "delivery_fee_amount": 0, "service_fee_amount": 0, "tip_amount": 0, "discount_amount": 0,
+	"promo_code": "", "total_amount": 8000, "status": "AWAITING_ACCEPTANCE", "scheduled_for": nil,
+	"price_change_policy": "", "dispatch_claimed_at": nil, "dispatched_at": nil, "cancellation_reason": "",
+	"estimated_delivery_at": nil, "courier_name": "", "courier_phone": "", "accept_by": sqlmock.AnyArg(),
+	"accepted_at": nil, "prep_time_minutes": 0, "courier_username": "", "delivery_distance_meters": nil,
+	"surge_basis_points": 0, "loyalty_points_redeemed": 0, "loyalty_discount_amount": 0, "wallet_amount": 0,
+	"promotion_discount_amount": 0,
+}
+
+// expectOrderInsert expects placedOrder to be stored with the columns that
+// differ from it, and gives the order id.
+func expectOrderInsert(mock sqlmock.Sqlmock, id int64, differences orderColumns) {
+	args := make([]driver.Value, len(orderInsertColumns))
+	for i, column := range orderInsertColumns {
+		args[i] = placedOrder[column]
+		if value, ok := differences[column]; ok {
+			args[i] = value
+		}
+	}
+
+	for column := range differences {
+		if _, ok := placedOrder[column]; !ok {
+			panic("orders have no column " + column)
+		}
+	}
+
+	mock.ExpectQuery(`INSERT INTO "orders"`).WithArgs(args...).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
+}
+
+// validated runs handler behind the validation interceptor, as the gRPC server does.
+func validated[Req proto.Message, Resp any](handler func(context.Context, Req) (Resp, error)) func(context.Context, Req) (Resp, error) {
+	return func(ctx context.Context, req Req) (Resp, error) {
+		resp, err := validationInterceptor(ctx, req, nil, func(ctx context.Context, req any) (any, error) {
+			return handler(ctx, req.(Req))
+		})
+		if err != nil {
+			var zero Resp
+			return zero, err
+		}
+
+		return resp.(Resp), nil
+	}
+}
+
 func TestRegisterUser_InvalidUserData_UsernameEmpty_ReturnsError(t *testing.T) {
 	mock, userServer := setupTestDB(t)
 
@@ -51,7 +142,7 @@ func TestRegisterUser_InvalidUserData_UsernameEmpty_ReturnsError(t *testing.T) {
 		},
 	}
 
-	got, err := userServer.Register(context.Background(), user)
+	got, err := validated(userServer.Register)(context.Background(), user)
 
 	mock.ExpectRollback()
 	assert.NotNil(t, err)
@@ -74,7 +165,7 @@ func TestRegisterUser_InvalidUserData_PasswordEmpty_ReturnsError(t *testing.T) {
 		},
 	}
 
-	got, err := userServer.Register(context.Background(), user)
+	got, err := validated(userServer.Register)(context.Background(), user)
 
 	mock.ExpectRollback()
 	assert.NotNil(t, err)
@@ -93,7 +184,7 @@ func TestRegisterUser_InvalidUserData_NullAddress_ReturnsError(t *testing.T) {
 		Address:  nil,
 	}
 
-	got, err := userServer.Register(context.Background(), user)
+	got, err := validated(userServer.Register)(context.Background(), user)
 
 	mock.ExpectRollback()
 	assert.NotNil(t, err)
@@ -103,6 +194,16 @@ func TestRegisterUser_InvalidUserData_NullAddress_ReturnsError(t *testing.T) {
 	assert.Equal(t, codes.InvalidArgument, statusErr.Code(), "Expected InvalidArgument error")
 }
 
+func TestRegisterUser_NullAddressWithoutInterceptor_ReturnsError(t *testing.T) {
+	_, userServer := setupTestDB(t)
+
+	got, err := userServer.Register(context.Background(), &u.RegisterUserRequest{Username: "user", Password: "password"})
+
+	assert.Nil(t, got)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, []string{"address"}, violatedFields(err))
+}
+
 func TestRegisterUser_InvalidUserData_EmptyStreet_ReturnsError(t *testing.T) {
 	mock, userServer := setupTestDB(t)
 
@@ -117,7 +218,7 @@ func TestRegisterUser_InvalidUserData_EmptyStreet_ReturnsError(t *testing.T) {
 		},
 	}
 
-	got, err := userServer.Register(context.Background(), user)
+	got, err := validated(userServer.Register)(context.Background(), user)
 
 	mock.ExpectRollback()
 	assert.NotNil(t, err)
@@ -141,7 +242,7 @@ func TestRegisterUser_InvalidUserData_EmptyCity_ReturnsError(t *testing.T) {
 		},
 	}
 
-	got, err := userServer.Register(context.Background(), user)
+	got, err := validated(userServer.Register)(context.Background(), user)
 
 	mock.ExpectRollback()
 	assert.NotNil(t, err)
@@ -165,7 +266,7 @@ func TestRegisterUser_InvalidUserData_EmptyState_ReturnsError(t *testing.T) {
 		},
 	}
 
-	got, err := userServer.Register(context.Background(), user)
+	got, err := validated(userServer.Register)(context.Background(), user)
 
 	mock.ExpectRollback()
 	assert.NotNil(t, err)
@@ -189,7 +290,7 @@ func TestRegisterUser_InvalidUserData_EmptyZipCode_ReturnsError(t *testing.T) {
 		},
 	}
 
-	got, err := userServer.Register(context.Background(), user)
+	got, err := validated(userServer.Register)(context.Background(), user)
 
 	mock.ExpectRollback()
 	assert.NotNil(t, err)
@@ -224,7 +325,75 @@ func TestRegisterUser_UnexpectedDatabaseError_ReturnsError(t *testing.T) {
 	assert.Nil(t, got)
 	statusErr, ok := status.FromError(err)
 	assert.True(t, ok, "Expected gRPC status error")
-	assert.Equal(t, codes.Unknown, statusErr.Code(), "Expected InvalidArgument error")
+	assert.Equal(t, codes.Internal, statusErr.Code(), "Expected Internal error")
+}
+
+func TestRegisterUser_InvalidUsername_ReturnsError(t *testing.T) {
+	for _, username := range []string{"ab", "-user", "user name", "user@example", strings.Repeat("a", 31)} {
+		t.Run(username, func(t *testing.T) {
+			mock, userServer := setupTestDB(t)
+
+			got, err := validated(userServer.Register)(context.Background(), &u.RegisterUserRequest{
+				Username: username,
+				Password: "password",
+				Address:  &u.Address{Street: "street", City: "city", State: "state", Zipcode: "zip"},
+			})
+
+			mock.ExpectRollback()
+			assert.Nil(t, got)
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		})
+	}
+}
+
+func TestCheckUsernameAvailability(t *testing.T) {
+	tests := []struct {
+		name              string
+		username          string
+		existing          int
+		expectQuery       bool
+		expectedAvailable bool
+		expectedReason    string
+	}{
+		{
+			name:           "Invalid Username",
+			username:       "ab",
+			expectedReason: "username must be between 3 and 30 characters",
+		},
+		{
+			name:           "Taken In Different Case",
+			username:       "Alice",
+			existing:       1,
+			expectQuery:    true,
+			expectedReason: "username is already taken",
+		},
+		{
+			name:              "Available",
+			username:          "alice",
+			expectQuery:       true,
+			expectedAvailable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock, gormDb := openMockDB(t)
+			userServer := &UserServiceServer{DB: gormDb}
+
+			if tt.expectQuery {
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "users" WHERE normalized_username = $1`)).
+					WithArgs("alice").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(tt.existing))
+			}
+
+			got, err := userServer.CheckUsernameAvailability(context.Background(), &u.CheckUsernameAvailabilityRequest{Username: tt.username})
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expectedAvailable, got.Available)
+			assert.Equal(t, tt.expectedReason, got.Reason)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		})
+	}
 }
 
 func TestRegisterUser_Success(t *testing.T) {
@@ -372,7 +541,7 @@ func TestCreateOrder_GetUserByUsernameScenarios(t *testing.T) {
 			gormDb, err := gorm.Open(dialect, &gorm.Config{})
 			assert.Nil(t, err, "Failed to open GORM DB: %v", err)
 
-			mock.ExpectQuery("SELECT * FROM \"users\" WHERE username = ?").WithArgs("username").WillReturnError(gorm.ErrRecordNotFound)
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE normalized_username = $1`)).WithArgs("username", 1).WillReturnError(gorm.ErrRecordNotFound)
 
 			orderServiceServer := &OrderServiceServer{DB: gormDb}
 
@@ -386,3 +555,431 @@ func TestCreateOrder_GetUserByUsernameScenarios(t *testing.T) {
 		})
 	}
 }
+
+func basicAuthContext(username string, password string) context.Context {
+	credentials := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Basic "+credentials))
+}
+
+func expectUserLookup(t *testing.T, mock sqlmock.Sqlmock, username string, password string) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	assert.Nil(t, err)
+
+	rows := sqlmock.NewRows([]string{"id", "username", "normalized_username", "password", "street", "city", "state", "zipcode"}).
+		AddRow(1, username, username, string(hashed), "street", "city", "state", "zip")
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE normalized_username = $1`)).WithArgs(username, 1).WillReturnRows(rows)
+}
+
+// menuItemFixture is a menu item of the fake catalog. Extra is added to its
+// JSON as is, as in `,"available":false`.
+type menuItemFixture struct {
+	Price    string
+	Currency string
+	Extra    string
+}
+
+const catalogRestaurantAddress = `"address":{"street":"r street","city":"r city","state":"r state","zipcode":"r zip"}`
+
+// catalogName is the name the fake catalog shows for the menu item looked up
+// by key. It differs from the key, so that tests notice one used for the
+// other.
+func catalogName(key string) string {
+	return "House " + key
+}
+
+// newCatalogServer fakes the catalog service for a single restaurant with the
+// given menu items, keyed by the name they are looked up by.
+func newCatalogServer(t *testing.T, restaurantId string, menuItems map[string]menuItemFixture) *httptest.Server {
+	return newCatalogServerWithHours(t, restaurantId, "", menuItems)
+}
+
+// newCatalogServerWithHours adds openingHours, the JSON fields describing
+// them, to the restaurant.
+func newCatalogServerWithHours(t *testing.T, restaurantId string, openingHours string, menuItems map[string]menuItemFixture) *httptest.Server {
+	restaurant := catalogRestaurantAddress
+	if openingHours != "" {
+		restaurant += "," + openingHours
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefix := "/restaurants/" + restaurantId
+
+		switch {
+		case r.URL.Path == prefix:
+			fmt.Fprintf(w, `{"data":{"restaurant":{%s}}}`, restaurant)
+		case strings.HasPrefix(r.URL.Path, prefix+"/menuItems/"):
+			name := strings.TrimPrefix(r.URL.Path, prefix+"/menuItems/")
+			menuItem, ok := menuItems[name]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			fmt.Fprintf(w, `{"data":{"menu_item":{"id":%q,"name":%q,"price":%s,"currency":%q%s}}}`, "id-"+name, catalogName(name), menuItem.Price, menuItem.Currency, menuItem.Extra)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func newFulfillmentServer(t *testing.T, statusCode int) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(statusCode)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestCreateOrder_Success_StoresLineItems(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	catalog := newCatalogServer(t, "1", map[string]menuItemFixture{
+		"Paneer Tikka": {Price: "250.50", Currency: "INR"},
+		"Naan":         {Price: "40", Currency: "INR"},
+	})
+
+	expectUserLookup(t, mock, "username", "password")
+	mock.ExpectBegin()
+	expectOrderInsert(mock, 7, orderColumns{
+		"subtotal_amount":     58100,
+		"tax_amount":          2905,
+		"tax_basis_points":    500,
+		"delivery_fee_amount": 4000,
+		"tip_amount":          1000,
+		"total_amount":        66005,
+	})
+	mock.ExpectQuery(`INSERT INTO "order_items"`).
+		WithArgs(7, "id-Naan", "House Naan", "Naan", 2, 4000, 8000, nil, "", 7, "id-Paneer Tikka", "House Paneer Tikka", "Paneer Tikka", 2, 25050, 50100, nil, "").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	mock.ExpectCommit()
+
+	orderServiceServer := &OrderServiceServer{
+		DB:                    gormDb,
+		CatalogServiceAPI:     catalog.URL + "/restaurants/",
+		FulfillmentServiceAPI: httpServerThatFails(t),
+		Pricing: pricing.NewEngine(pricing.Config{
+			DefaultBasisPoints: 500,
+			DeliveryFees:       pricing.ZoneDeliveryFees{SameCity: 2000, SameState: 3000, OtherState: 4000},
+		}),
+	}
+
+	response, err := orderServiceServer.Create(basicAuthContext("username", "password"), &o.CreateOrderRequest{
+		RestaurantId: "1",
+		MenuItems:    map[string]int32{"Paneer Tikka": 2, "Naan": 2},
+		Tip:          &o.Money{CurrencyCode: "INR", AmountMinor: 1000},
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(7), response.Id)
+	assert.Equal(t, o.OrderStatus_ORDER_AWAITING_ACCEPTANCE, response.Status)
+	assert.Equal(t, &o.Money{CurrencyCode: "INR", AmountMinor: 66005}, response.Total)
+	assert.Equal(t, 660.05, response.TotalPrice)
+	assert.Equal(t, &o.PriceBreakdown{
+		Subtotal:           &o.Money{CurrencyCode: "INR", AmountMinor: 58100},
+		Discount:           &o.Money{CurrencyCode: "INR", AmountMinor: 0},
+		Tax:                &o.Money{CurrencyCode: "INR", AmountMinor: 2905},
+		TaxRateBasisPoints: 500,
+		DeliveryFee:        &o.Money{CurrencyCode: "INR", AmountMinor: 4000},
+		ServiceFee:         &o.Money{CurrencyCode: "INR", AmountMinor: 0},
+		Tip:                &o.Money{CurrencyCode: "INR", AmountMinor: 1000},
+		Total:              &o.Money{CurrencyCode: "INR", AmountMinor: 66005},
+	}, response.Breakdown)
+	assert.Equal(t, []*o.OrderLineItem{
+		{MenuItemId: "id-Naan", Name: "House Naan", Quantity: 2, UnitPrice: &o.Money{CurrencyCode: "INR", AmountMinor: 4000}, LineTotal: &o.Money{CurrencyCode: "INR", AmountMinor: 8000}},
+		{MenuItemId: "id-Paneer Tikka", Name: "House Paneer Tikka", Quantity: 2, UnitPrice: &o.Money{CurrencyCode: "INR", AmountMinor: 25050}, LineTotal: &o.Money{CurrencyCode: "INR", AmountMinor: 50100}},
+	}, response.LineItems)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateOrder_MixedCurrencies_ReturnsFailedPrecondition(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	catalog := newCatalogServer(t, "1", map[string]menuItemFixture{
+		"Paneer Tikka": {Price: "250", Currency: "INR"},
+		"Burger":       {Price: "5.99", Currency: "USD"},
+	})
+
+	expectUserLookup(t, mock, "username", "password")
+
+	orderServiceServer := &OrderServiceServer{DB: gormDb, CatalogServiceAPI: catalog.URL + "/restaurants/"}
+
+	response, err := orderServiceServer.Create(basicAuthContext("username", "password"), &o.CreateOrderRequest{
+		RestaurantId: "1",
+		MenuItems:    map[string]int32{"Paneer Tikka": 1, "Burger": 1},
+	})
+
+	assert.Nil(t, response)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.Equal(t, ReasonMixedCurrencies, errorInfoOf(t, err).Reason)
+	// Items are priced in name order, so the burger sets the currency.
+	assert.Equal(t, "USD,INR", errorInfoOf(t, err).Metadata["currencies"])
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateOrder_UnavailableItems_ListsEveryOffendingItem(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	catalog := newCatalogServer(t, "1", map[string]menuItemFixture{
+		"Naan":         {Price: "40", Currency: "INR", Extra: `,"available":false`},
+		"Paneer Tikka": {Price: "250", Currency: "INR", Extra: `,"stock":1`},
+		"Roti":         {Price: "20", Currency: "INR", Extra: `,"available":true,"stock":5`},
+	})
+
+	expectUserLookup(t, mock, "username", "password")
+
+	orderServiceServer := &OrderServiceServer{DB: gormDb, CatalogServiceAPI: catalog.URL + "/restaurants/"}
+
+	response, err := orderServiceServer.Create(basicAuthContext("username", "password"), &o.CreateOrderRequest{
+		RestaurantId: "1",
+		MenuItems:    map[string]int32{"Naan": 1, "Paneer Tikka": 2, "Roti": 5},
+	})
+
+	assert.Nil(t, response)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.Equal(t, ReasonItemsUnavailable, errorInfoOf(t, err).Reason)
+
+	var violations []*errdetails.PreconditionFailure_Violation
+	for _, detail := range status.Convert(err).Details() {
+		if failure, ok := detail.(*errdetails.PreconditionFailure); ok {
+			violations = failure.Violations
+		}
+	}
+	assert.Len(t, violations, 2)
+	assert.True(t, proto.Equal(&errdetails.PreconditionFailure_Violation{Type: "ITEM_UNAVAILABLE", Subject: "Naan", Description: "not available right now"}, violations[0]))
+	assert.True(t, proto.Equal(&errdetails.PreconditionFailure_Violation{Type: "OUT_OF_STOCK", Subject: "Paneer Tikka", Description: "only 1 left"}, violations[1]))
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateOrder_RestaurantClosed_TellsWhenItOpens(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	today := time.Now().UTC()
+	closed := func(days int) string {
+		return fmt.Sprintf(`{"date":%q,"windows":[]}`, today.AddDate(0, 0, days).Format("2006-01-02"))
+	}
+	catalog := newCatalogServerWithHours(t, "1", fmt.Sprintf(`"time_zone":"UTC","opening_hours":{%s},"holiday_overrides":[%s,%s,%s]`,
+		everyDay(`[{"open":"10:00","close":"11:00"}]`), closed(-1), closed(0), closed(1)),
+		map[string]menuItemFixture{"Naan": {Price: "40", Currency: "INR"}})
+
+	expectUserLookup(t, mock, "username", "password")
+
+	orderServiceServer := &OrderServiceServer{DB: gormDb, CatalogServiceAPI: catalog.URL + "/restaurants/", FulfillmentServiceAPI: httpServerThatFails(t)}
+
+	response, err := orderServiceServer.Create(basicAuthContext("username", "password"), &o.CreateOrderRequest{
+		RestaurantId: "1",
+		MenuItems:    map[string]int32{"Naan": 1},
+	})
+
+	opensAt := time.Date(today.Year(), today.Month(), today.Day()+2, 10, 0, 0, 0, time.UTC)
+
+	assert.Nil(t, response)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.Equal(t, ReasonRestaurantClosed, errorInfoOf(t, err).Reason)
+	assert.Equal(t, opensAt.Format(time.RFC3339), errorInfoOf(t, err).Metadata["opens_at"])
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+// everyDay is the JSON of weekly opening hours with the same windows on all
+// days.
+func everyDay(windows string) string {
+	days := []string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"}
+	for i, day := range days {
+		days[i] = fmt.Sprintf("%q:%s", day, windows)
+	}
+
+	return strings.Join(days, ",")
+}
+
+func TestCreateOrder_WithPromoCode_RedeemsPromotionInOrderTransaction(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	catalog := newCatalogServer(t, "1", map[string]menuItemFixture{"Naan": {Price: "40", Currency: "INR"}})
+	fulfillment := newFulfillmentServer(t, http.StatusCreated)
+
+	promotionColumns := []string{"id", "code", "kind", "currency", "percent_off_basis_points", "per_user_limit", "global_limit", "usage_count"}
+
+	expectUserLookup(t, mock, "username", "password")
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "promotions" WHERE code = $1`)).WithArgs("WELCOME10", 1).
+		WillReturnRows(sqlmock.NewRows(promotionColumns).AddRow(3, "WELCOME10", "PERCENTAGE_OFF", "INR", 1000, 1, 100, 41))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "orders" WHERE username = $1`)).WithArgs("username").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "promotion_redemptions" WHERE promotion_id = $1 AND username = $2`)).WithArgs(3, "username").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectBegin()
+	expectOrderInsert(mock, 7, orderColumns{
+		"subtotal_amount":           20000,
+		"discount_amount":           2000,
+		"promo_code":                "WELCOME10",
+		"total_amount":              18000,
+		"promotion_discount_amount": 2000,
+	})
+	mock.ExpectQuery(`INSERT INTO "order_items"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "promotions" WHERE id = $1`)+`.*FOR UPDATE`).WithArgs(3, 1).
+		WillReturnRows(sqlmock.NewRows(promotionColumns).AddRow(3, "WELCOME10", "PERCENTAGE_OFF", "INR", 1000, 1, 100, 41))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "promotion_redemptions"`)).WithArgs(3, "username").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "promotions" SET "usage_count"=usage_count + 1 WHERE id = $1`)).WithArgs(3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO "promotion_redemptions"`).WithArgs(3, "username", 7, 2000, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	orderServiceServer := &OrderServiceServer{DB: gormDb, CatalogServiceAPI: catalog.URL + "/restaurants/", FulfillmentServiceAPI: fulfillment.URL}
+
+	response, err := orderServiceServer.Create(basicAuthContext("username", "password"), &o.CreateOrderRequest{
+		RestaurantId: "1",
+		MenuItems:    map[string]int32{"Naan": 5},
+		PromoCode:    "welcome10",
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "WELCOME10", response.PromoCode)
+	assert.Equal(t, &o.Money{CurrencyCode: "INR", AmountMinor: 2000}, response.Breakdown.Discount)
+	assert.Equal(t, &o.Money{CurrencyCode: "INR", AmountMinor: 18000}, response.Total)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateOrder_BuyXGetYPromotion_MatchesItemsByKey(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	catalog := newCatalogServer(t, "1", map[string]menuItemFixture{"Naan": {Price: "40", Currency: "INR"}})
+	fulfillment := newFulfillmentServer(t, http.StatusCreated)
+
+	promotionColumns := []string{"id", "code", "kind", "currency", "buy_menu_item", "buy_quantity", "get_menu_item", "get_quantity"}
+	promotionRow := []driver.Value{3, "NAAN3", "BUY_X_GET_Y", "INR", "Naan", 2, "Naan", 1}
+
+	expectUserLookup(t, mock, "username", "password")
+	mock.ExpectQuery(`SELECT \* FROM "promotions" WHERE code`).WillReturnRows(sqlmock.NewRows(promotionColumns).AddRow(promotionRow...))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "orders"`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "promotion_redemptions"`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectBegin()
+	expectOrderInsert(mock, 7, orderColumns{
+		"subtotal_amount":           12000,
+		"discount_amount":           4000,
+		"promo_code":                "NAAN3",
+		"total_amount":              8000,
+		"promotion_discount_amount": 4000,
+	})
+	mock.ExpectQuery(`INSERT INTO "order_items"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(`FOR UPDATE`).WillReturnRows(sqlmock.NewRows(promotionColumns).AddRow(promotionRow...))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "promotion_redemptions"`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec(`UPDATE "promotions" SET "usage_count"`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO "promotion_redemptions"`).WithArgs(3, "username", 7, 4000, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	orderServiceServer := &OrderServiceServer{DB: gormDb, CatalogServiceAPI: catalog.URL + "/restaurants/", FulfillmentServiceAPI: fulfillment.URL}
+
+	response, err := orderServiceServer.Create(basicAuthContext("username", "password"), &o.CreateOrderRequest{
+		RestaurantId: "1",
+		MenuItems:    map[string]int32{"Naan": 3},
+		PromoCode:    "NAAN3",
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "House Naan", response.LineItems[0].Name)
+	assert.Equal(t, &o.Money{CurrencyCode: "INR", AmountMinor: 4000}, response.Breakdown.Discount)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateOrder_PromotionLimitReachedWhileLocked_RollsBack(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	catalog := newCatalogServer(t, "1", map[string]menuItemFixture{"Naan": {Price: "40", Currency: "INR"}})
+
+	promotionColumns := []string{"id", "code", "kind", "currency", "percent_off_basis_points", "global_limit", "usage_count"}
+
+	expectUserLookup(t, mock, "username", "password")
+	mock.ExpectQuery(`SELECT \* FROM "promotions" WHERE code`).
+		WillReturnRows(sqlmock.NewRows(promotionColumns).AddRow(3, "LAST1", "PERCENTAGE_OFF", "INR", 1000, 100, 99))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "orders"`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "promotion_redemptions"`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "orders"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectQuery(`INSERT INTO "order_items"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(`FOR UPDATE`).
+		WillReturnRows(sqlmock.NewRows(promotionColumns).AddRow(3, "LAST1", "PERCENTAGE_OFF", "INR", 1000, 100, 100))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "promotion_redemptions"`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectRollback()
+
+	orderServiceServer := &OrderServiceServer{DB: gormDb, CatalogServiceAPI: catalog.URL + "/restaurants/"}
+
+	response, err := orderServiceServer.Create(basicAuthContext("username", "password"), &o.CreateOrderRequest{
+		RestaurantId: "1",
+		MenuItems:    map[string]int32{"Naan": 5},
+		PromoCode:    "LAST1",
+	})
+
+	assert.Nil(t, response)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.Equal(t, ReasonPromotionLimitReached, errorInfoOf(t, err).Reason)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateOrder_FirstOrderPromotionUsedWhileLocked_RollsBack(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	catalog := newCatalogServer(t, "1", map[string]menuItemFixture{"Naan": {Price: "40", Currency: "INR"}})
+
+	promotionColumns := []string{"id", "code", "kind", "currency", "percent_off_basis_points", "first_order_only"}
+
+	expectUserLookup(t, mock, "username", "password")
+	mock.ExpectQuery(`SELECT \* FROM "promotions" WHERE code`).
+		WillReturnRows(sqlmock.NewRows(promotionColumns).AddRow(3, "FIRST", "PERCENTAGE_OFF", "INR", 1000, true))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "orders"`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "promotion_redemptions"`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "orders"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectQuery(`INSERT INTO "order_items"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(`FOR UPDATE`).
+		WillReturnRows(sqlmock.NewRows(promotionColumns).AddRow(3, "FIRST", "PERCENTAGE_OFF", "INR", 1000, true))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "promotion_redemptions"`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	// Another first order committed while this one waited for the lock.
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "orders" WHERE username = $1`)).WithArgs("username").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectRollback()
+
+	orderServiceServer := &OrderServiceServer{DB: gormDb, CatalogServiceAPI: catalog.URL + "/restaurants/"}
+
+	response, err := orderServiceServer.Create(basicAuthContext("username", "password"), &o.CreateOrderRequest{
+		RestaurantId: "1",
+		MenuItems:    map[string]int32{"Naan": 5},
+		PromoCode:    "FIRST",
+	})
+
+	assert.Nil(t, response)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.Equal(t, ReasonPromotionNotApplicable, errorInfoOf(t, err).Reason)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateOrder_WithKnownZipcodes_EstimatesDelivery(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	catalog := newCatalogServer(t, "1", map[string]menuItemFixture{"Naan": {Price: "40", Currency: "INR"}})
+	centroids, err := geo.ReadCentroids(strings.NewReader("zipcode,lat,lng\nr zip,12.9716,77.5946\nzip,13.0616,77.5946\n"))
+	assert.Nil(t, err)
+
+	expectUserLookup(t, mock, "username", "password")
+	mock.ExpectBegin()
+	expectOrderInsert(mock, 7, orderColumns{
+		"estimated_delivery_at":    sqlmock.AnyArg(),
+		"delivery_distance_meters": 10008,
+	})
+	mock.ExpectQuery(`INSERT INTO "order_items"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	orderServiceServer := &OrderServiceServer{
+		DB:                    gormDb,
+		CatalogServiceAPI:     catalog.URL + "/restaurants/",
+		FulfillmentServiceAPI: httpServerThatFails(t),
+		Pricing:               pricing.NewEngine(pricing.Config{}),
+		ETA:                   eta.NewEstimator(centroids, eta.DefaultConfig()),
+	}
+
+	before := time.Now()
+	response, err := orderServiceServer.Create(basicAuthContext("username", "password"), &o.CreateOrderRequest{
+		RestaurantId: "1",
+		MenuItems:    map[string]int32{"Naan": 2},
+	})
+
+	// 20 minutes of default preparation, about 39 minutes of ride and 5 to drop.
+	assert.Nil(t, err)
+	assert.WithinDuration(t, before.Add(64*time.Minute), response.EstimatedDeliveryAt.AsTime(), time.Minute)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
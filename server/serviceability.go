@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"orderService.com/go-orderService-grpc/model"
+	o "orderService.com/go-orderService-grpc/proto/order"
+	"orderService.com/go-orderService-grpc/zones"
+)
+
+func (orderServer *OrderServiceServer) CheckServiceability(ctx context.Context, req *o.CheckServiceabilityRequest) (*o.CheckServiceabilityResponse, error) {
+	user, err := orderServer.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	restaurantAddress, err := fetchRestaurantAddress(req.RestaurantId, orderServer.CatalogServiceAPI)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	response := &o.CheckServiceabilityResponse{RestaurantId: req.RestaurantId, Serviceable: true}
+
+	if err := orderServer.Zones.Check(req.RestaurantId, restaurantAddress, user.Address); err != nil {
+		response.Serviceable = false
+		response.Reason = err.Error()
+	}
+
+	return response, nil
+}
+
+// checkServiceability fails orders to addresses the restaurant doesn't
+// deliver to, before the fulfillment service gets to turn them down.
+func (orderServer *OrderServiceServer) checkServiceability(restaurantId string, restaurantAddress *model.Address, dropAddress *model.Address) error {
+	err := orderServer.Zones.Check(restaurantId, restaurantAddress, dropAddress)
+	if errors.Is(err, zones.ErrOutsideZone) || errors.Is(err, zones.ErrUnknownLocation) {
+		var zipcode string
+		if dropAddress != nil {
+			zipcode = dropAddress.Zipcode
+		}
+
+		return errOutsideDeliveryZone(restaurantId, zipcode, err)
+	}
+
+	return err
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	o "orderService.com/go-orderService-grpc/proto/order"
+	"orderService.com/go-orderService-grpc/zones"
+)
+
+// zipcodeZone only delivers to the listed ZIP codes of restaurant 1.
+func zipcodeZone(zipcodes ...string) *zones.Checker {
+	return zones.NewChecker(nil, zones.Config{Restaurants: map[string]zones.Zone{"1": {Zipcodes: zipcodes}}})
+}
+
+func TestCheckServiceability(t *testing.T) {
+	catalog := newCatalogServer(t, "1", nil)
+
+	for _, test := range []struct {
+		name        string
+		zones       *zones.Checker
+		serviceable bool
+		reason      string
+	}{
+		{name: "no zones", serviceable: true},
+		{name: "inside", zones: zipcodeZone("zip"), serviceable: true},
+		{name: "outside", zones: zipcodeZone("560001"), reason: zones.ErrOutsideZone.Error()},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			mock, gormDb := openMockDB(t)
+			orderServiceServer := &OrderServiceServer{DB: gormDb, CatalogServiceAPI: catalog.URL + "/restaurants/", Zones: test.zones}
+
+			expectUserLookup(t, mock, "username", "password")
+
+			response, err := orderServiceServer.CheckServiceability(basicAuthContext("username", "password"), &o.CheckServiceabilityRequest{RestaurantId: "1"})
+
+			assert.Nil(t, err)
+			assert.Equal(t, &o.CheckServiceabilityResponse{RestaurantId: "1", Serviceable: test.serviceable, Reason: test.reason}, response)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestCreateOrder_OutsideDeliveryZone_ReturnsFailedPrecondition(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	catalog := newCatalogServer(t, "1", map[string]menuItemFixture{"Naan": {Price: "40", Currency: "INR"}})
+
+	expectUserLookup(t, mock, "username", "password")
+
+	orderServiceServer := &OrderServiceServer{
+		DB:                    gormDb,
+		CatalogServiceAPI:     catalog.URL + "/restaurants/",
+		FulfillmentServiceAPI: httpServerThatFails(t),
+		Zones:                 zipcodeZone("560001"),
+	}
+
+	response, err := orderServiceServer.Create(basicAuthContext("username", "password"), &o.CreateOrderRequest{
+		RestaurantId: "1",
+		MenuItems:    map[string]int32{"Naan": 2},
+	})
+
+	assert.Nil(t, response)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.Equal(t, ReasonOutsideDeliveryZone, errorInfoOf(t, err).Reason)
+	assert.Equal(t, "zip", errorInfoOf(t, err).Metadata["zipcode"])
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
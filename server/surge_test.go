@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"orderService.com/go-orderService-grpc/model"
+	"orderService.com/go-orderService-grpc/pricing"
+	o "orderService.com/go-orderService-grpc/proto/order"
+	"orderService.com/go-orderService-grpc/surge"
+)
+
+// catalogPickup is where the fake catalog's restaurant is, the zone its
+// surge is tracked in.
+var catalogPickup = &model.Address{Street: "r street", City: "r city", State: "r state", Zipcode: "r zip"}
+
+// scarceCouriers surges once a single dispatch found no courier.
+func scarceCouriers() *surge.Tracker {
+	config := surge.DefaultConfig()
+	config.MinOutcomes = 1
+	return surge.NewTracker(config)
+}
+
+func TestAcceptOrder_NoCourierNearby_SurgesZone(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	catalog := newCatalogServer(t, "1", map[string]menuItemFixture{"Naan": {Price: "40", Currency: "INR"}})
+	fulfillment := newFulfillmentServer(t, http.StatusNotFound)
+	tracker := scarceCouriers()
+	restaurantServer := &RestaurantServiceServer{Orders: &OrderServiceServer{
+		DB:                    gormDb,
+		CatalogServiceAPI:     catalog.URL + "/restaurants/",
+		FulfillmentServiceAPI: fulfillment.URL,
+		Surge:                 tracker,
+	}}
+
+	expectUserLookup(t, mock, "chef", "password")
+	expectIncomingOrder(mock, "AWAITING_ACCEPTANCE")
+	expectOwnership(mock, "chef", true)
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "orders" SET "status"=$1,"dispatch_claimed_at"=$2,"estimated_delivery_at"=$3,"accepted_at"=$4,"prep_time_minutes"=$5 WHERE "id" = $6`)).
+		WithArgs("DISPATCHING", sqlmock.AnyArg(), nil, sqlmock.AnyArg(), 20, 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectUserLookup(t, mock, "username", "password")
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "orders" WHERE id = $1`)).WithArgs(7, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "restaurant_id", "username", "currency", "status", "accepted_at", "prep_time_minutes"}).
+			AddRow(7, "1", "username", "INR", "DISPATCHING", time.Now(), 20))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "order_items" WHERE "order_items"."order_id" = $1`)).WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "menu_item_name", "quantity", "unit_amount", "line_amount"}))
+
+	assert.Equal(t, int64(surge.NoSurge), tracker.Multiplier(catalogPickup))
+
+	_, err := restaurantServer.AcceptOrder(basicAuthContext("chef", "password"), &o.AcceptOrderRequest{OrderId: 7, PrepTimeMinutes: 20})
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(20000), tracker.Multiplier(catalogPickup))
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateOrder_SurgedZone_RecordsSurgedDeliveryFee(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	catalog := newCatalogServer(t, "1", map[string]menuItemFixture{"Naan": {Price: "40", Currency: "INR"}})
+	tracker := scarceCouriers()
+	tracker.RecordDispatch(catalogPickup, true)
+	tracker.RecordDispatch(catalogPickup, false)
+
+	orderServiceServer := &OrderServiceServer{
+		DB:                    gormDb,
+		CatalogServiceAPI:     catalog.URL + "/restaurants/",
+		FulfillmentServiceAPI: httpServerThatFails(t),
+		Pricing:               pricing.NewEngine(pricing.Config{DeliveryFees: pricing.ZoneDeliveryFees{OtherState: 3000}}),
+		Surge:                 tracker,
+	}
+
+	expectUserLookup(t, mock, "username", "password")
+	mock.ExpectBegin()
+	expectOrderInsert(mock, 7, orderColumns{
+		"delivery_fee_amount": 4500,
+		"total_amount":        12500,
+		"surge_basis_points":  15000,
+	})
+	mock.ExpectQuery(`INSERT INTO "order_items"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	response, err := orderServiceServer.Create(basicAuthContext("username", "password"), &o.CreateOrderRequest{
+		RestaurantId: "1",
+		MenuItems:    map[string]int32{"Naan": 2},
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, &o.Money{CurrencyCode: "INR", AmountMinor: 4500}, response.Breakdown.DeliveryFee)
+	assert.Equal(t, int64(15000), response.Breakdown.SurgeBasisPoints)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+	database "orderService.com/go-orderService-grpc/db"
+	"orderService.com/go-orderService-grpc/model"
+	"orderService.com/go-orderService-grpc/money"
+	o "orderService.com/go-orderService-grpc/proto/order"
+)
+
+const defaultWalletPageSize = 50
+
+type WalletServiceServer struct {
+	Orders *OrderServiceServer
+	o.WalletServiceServer
+}
+
+// WalletAdminServiceServer serves the support agents, as recorded in
+// model.SupportAgent.
+type WalletAdminServiceServer struct {
+	Orders *OrderServiceServer
+	o.WalletAdminServiceServer
+}
+
+func (walletServer *WalletServiceServer) GetWallet(ctx context.Context, _ *o.GetWalletRequest) (*o.Wallet, error) {
+	user, err := walletServer.Orders.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return walletOf(walletServer.Orders.DB, user.Username)
+}
+
+func (walletServer *WalletServiceServer) ListWalletTransactions(ctx context.Context, req *o.ListWalletTransactionsRequest) (*o.ListWalletTransactionsResponse, error) {
+	user, err := walletServer.Orders.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pageSize := int(req.PageSize)
+	if pageSize == 0 {
+		pageSize = defaultWalletPageSize
+	}
+
+	transactions, err := database.ListWalletTransactions(walletServer.Orders.DB, user.Username, req.BeforeId, pageSize)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	response := &o.ListWalletTransactionsResponse{}
+	for _, transaction := range transactions {
+		response.Transactions = append(response.Transactions, toProtoWalletTransaction(transaction))
+	}
+
+	if len(transactions) == pageSize {
+		response.NextBeforeId = transactions[len(transactions)-1].Id
+	}
+
+	return response, nil
+}
+
+var walletCreditSources = map[o.WalletCreditSource]model.WalletCreditSource{
+	o.WalletCreditSource_WALLET_CREDIT_REFUND:    model.WalletRefund,
+	o.WalletCreditSource_WALLET_CREDIT_GOODWILL:  model.WalletGoodwill,
+	o.WalletCreditSource_WALLET_CREDIT_GIFT_CARD: model.WalletGiftCard,
+}
+
+// IssueWalletCredit credits a customer's wallet from the account of the
+// credit's source. The idempotency key is looked up under the customer's lock,
+// so that a retry waits for the credit it repeats and returns it. Refunds are
+// for an order of the customer, and all of an order's refunds together can't
+// exceed its total.
+func (adminServer *WalletAdminServiceServer) IssueWalletCredit(ctx context.Context, req *o.IssueWalletCreditRequest) (*o.WalletTransaction, error) {
+	agent, err := adminServer.requireSupportAgent(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	source, ok := walletCreditSources[req.Source]
+	if !ok {
+		return nil, toStatusError(errInvalidArgument("Invalid request", fieldViolation("source", "value is required")))
+	}
+
+	amount := fromProtoMoney(req.Amount)
+	if _, err := money.Exponent(amount.Currency); err != nil {
+		return nil, toStatusError(errInvalidArgument("Invalid request", fieldViolation("amount.currency_code", err.Error())))
+	}
+
+	if amount.Amount <= 0 {
+		return nil, toStatusError(errInvalidArgument("Invalid request", fieldViolation("amount.amount_minor", "must be positive")))
+	}
+
+	if source == model.WalletRefund && req.OrderId == 0 {
+		return nil, toStatusError(errInvalidArgument("Invalid request", fieldViolation("order_id", "is required for refunds")))
+	}
+
+	// Usernames are case-insensitive, the customer's is resolved to the one
+	// their accounts are kept under.
+	customer, err := database.GetUserByUsername(adminServer.Orders.DB, req.Username)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, toStatusError(errUserNotFound(req.Username))
+	}
+
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	var credit *model.WalletTransaction
+	err = adminServer.Orders.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := database.LockUser(tx, customer.Username); err != nil {
+			return err
+		}
+
+		credit, err = database.GetWalletCredit(tx, req.IdempotencyKey)
+		if err == nil {
+			if credit.Username != customer.Username || credit.Source != source || credit.Currency != amount.Currency || credit.Amount != amount.Amount {
+				return errIdempotencyKeyReused(req.IdempotencyKey)
+			}
+			return nil
+		}
+
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if req.OrderId != 0 {
+			order, err := database.GetOrder(tx, req.OrderId)
+			if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && order.Username != customer.Username) {
+				return errOrderNotFound(req.OrderId)
+			}
+
+			if err != nil {
+				return err
+			}
+
+			if source == model.WalletRefund {
+				if err := checkRefundable(tx, order, amount); err != nil {
+					return err
+				}
+			}
+		}
+
+		credit = model.WalletTransfer(model.WalletCredit, customer.Username, req.OrderId, amount.Currency, amount.Amount,
+			model.WalletSourceAccount(source), model.WalletAccount(customer.Username))
+		credit.Source = source
+		credit.Reason = req.Reason
+		credit.IssuedBy = agent.Username
+		credit.IdempotencyKey = &req.IdempotencyKey
+
+		err = database.RecordWalletTransaction(tx, credit)
+		if isUniqueViolation(err) {
+			// Used for another customer's credit at the same time.
+			return errIdempotencyKeyReused(req.IdempotencyKey)
+		}
+
+		return err
+	})
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return toProtoWalletTransaction(*credit), nil
+}
+
+// checkRefundable checks that a refund, together with the order's earlier
+// refunds, doesn't exceed the order's total. It runs under the customer's
+// lock, which all refunds of the order take.
+func checkRefundable(tx *gorm.DB, order *model.Order, refund money.Money) error {
+	if refund.Currency != order.Currency {
+		return errInvalidArgument("Invalid request", fieldViolation("amount.currency_code", "must be the order's currency, "+order.Currency))
+	}
+
+	refunded, err := database.SumWalletRefunds(tx, order.Id)
+	if err != nil {
+		return err
+	}
+
+	refundable := max(order.TotalAmount-refunded, 0)
+	if refund.Amount > refundable {
+		return errRefundExceedsOrder(order.Id, refund, money.New(refundable, order.Currency))
+	}
+
+	return nil
+}
+
+func (adminServer *WalletAdminServiceServer) GetCustomerWallet(ctx context.Context, req *o.GetCustomerWalletRequest) (*o.Wallet, error) {
+	if _, err := adminServer.requireSupportAgent(ctx); err != nil {
+		return nil, err
+	}
+
+	customer, err := database.GetUserByUsername(adminServer.Orders.DB, req.Username)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, toStatusError(errUserNotFound(req.Username))
+	}
+
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return walletOf(adminServer.Orders.DB, customer.Username)
+}
+
+func (adminServer *WalletAdminServiceServer) requireSupportAgent(ctx context.Context) (*model.User, error) {
+	user, err := adminServer.Orders.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	support, err := database.IsSupportAgent(adminServer.Orders.DB, user.Username)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	if !support {
+		return nil, toStatusError(errNotSupportAgent())
+	}
+
+	return user, nil
+}
+
+// walletOf returns a user's balances, in currency order.
+func walletOf(db *gorm.DB, username string) (*o.Wallet, error) {
+	balances, err := database.GetWalletBalances(db, username)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	currencies := make([]string, 0, len(balances))
+	for currency, amount := range balances {
+		if amount != 0 {
+			currencies = append(currencies, currency)
+		}
+	}
+	slices.Sort(currencies)
+
+	wallet := &o.Wallet{Username: username}
+	for _, currency := range currencies {
+		wallet.Balances = append(wallet.Balances, toProtoMoney(money.New(balances[currency], currency)))
+	}
+
+	return wallet, nil
+}
+
+var protoWalletCreditSources = map[model.WalletCreditSource]o.WalletCreditSource{
+	model.WalletRefund:   o.WalletCreditSource_WALLET_CREDIT_REFUND,
+	model.WalletGoodwill: o.WalletCreditSource_WALLET_CREDIT_GOODWILL,
+	model.WalletGiftCard: o.WalletCreditSource_WALLET_CREDIT_GIFT_CARD,
+}
+
+var walletKinds = map[model.WalletKind]o.WalletTransactionKind{
+	model.WalletCredit:   o.WalletTransactionKind_WALLET_CREDIT,
+	model.WalletPayment:  o.WalletTransactionKind_WALLET_PAYMENT,
+	model.WalletReversal: o.WalletTransactionKind_WALLET_PAYMENT_REVERSAL,
+}
+
+func toProtoWalletTransaction(transaction model.WalletTransaction) *o.WalletTransaction {
+	response := &o.WalletTransaction{
+		Id:        transaction.Id,
+		Kind:      walletKinds[transaction.Kind],
+		OrderId:   transaction.OrderId,
+		CreatedAt: timestamppb.New(transaction.CreatedAt),
+		Source:    protoWalletCreditSources[transaction.Source],
+		Reason:    transaction.Reason,
+	}
+
+	var change int64
+	account := model.WalletAccount(transaction.Username)
+	for _, entry := range transaction.Entries {
+		response.Entries = append(response.Entries, &o.WalletEntry{Account: entry.Account, Amount: toProtoMoney(money.New(entry.Amount, entry.Currency))})
+		if entry.Account == account {
+			change += entry.Amount
+		}
+	}
+	response.Amount = toProtoMoney(money.New(change, transaction.Currency))
+
+	return response
+}
+
+// walletAmount is the part of an order's total that a request pays with store
+// credit.
+func walletAmount(requested *o.Money, total money.Money) (int64, error) {
+	amount := fromProtoMoney(requested)
+
+	switch {
+	case amount.Amount < 0:
+		return 0, errInvalidArgument("Invalid request", fieldViolation("wallet_amount.amount_minor", "must not be negative"))
+	case amount.Amount == 0:
+		return 0, nil
+	case amount.Currency != "" && amount.Currency != total.Currency:
+		return 0, errInvalidArgument("Invalid request", fieldViolation("wallet_amount.currency_code", "must be the order's currency, "+total.Currency))
+	case amount.Amount > total.Amount:
+		return 0, errInvalidArgument("Invalid request", fieldViolation("wallet_amount.amount_minor", "must not exceed the order total of "+total.String()))
+	}
+
+	return amount.Amount, nil
+}
+
+// payWithWallet spends the store credit an order is paid with. It runs in the
+// transaction that stores the order, and checks the balance under the user's
+// lock so that concurrent orders can't spend the same credit twice.
+func payWithWallet(tx *gorm.DB, order *model.Order) error {
+	if _, err := database.LockUser(tx, order.Username); err != nil {
+		return err
+	}
+
+	balance, err := database.GetWalletBalance(tx, order.Username, order.Currency)
+	if err != nil {
+		return err
+	}
+
+	if balance < order.WalletAmount {
+		return errInsufficientWalletBalance(money.New(order.WalletAmount, order.Currency), money.New(balance, order.Currency))
+	}
+
+	return database.RecordWalletTransaction(tx, model.WalletTransfer(model.WalletPayment, order.Username, order.Id, order.Currency, order.WalletAmount,
+		model.WalletAccount(order.Username), model.WalletPaymentsAccount))
+}
+
+// walletPaid is the store credit an order was paid with, nil if none.
+func walletPaid(order *model.Order) *o.Money {
+	if order.WalletAmount == 0 {
+		return nil
+	}
+
+	return toProtoMoney(money.New(order.WalletAmount, order.Currency))
+}
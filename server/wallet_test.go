@@ -0,0 +1,438 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+
+	"orderService.com/go-orderService-grpc/model"
+	o "orderService.com/go-orderService-grpc/proto/order"
+	u "orderService.com/go-orderService-grpc/proto/user"
+)
+
+func expectWalletBalance(mock sqlmock.Sqlmock, username string, balance int64) {
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COALESCE(SUM(amount), 0) FROM "wallet_entries" WHERE account = $1 AND currency = $2`)).
+		WithArgs("customer:"+username, "INR").WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(balance))
+}
+
+// expectWalletTransfer expects a transaction moving an amount of INR from one
+// account to another.
+func expectWalletTransfer(mock sqlmock.Sqlmock, kind string, orderId int64, amount int64, from string, to string) {
+	mock.ExpectQuery(`INSERT INTO "wallet_transactions"`).
+		WithArgs("username", kind, orderId, "INR", amount, "", "", "", nil, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectQuery(`INSERT INTO "wallet_entries"`).
+		WithArgs(3, from, "INR", -amount, 3, to, "INR", amount).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5).AddRow(6))
+}
+
+func expectSupportAgent(mock sqlmock.Sqlmock, username string, support bool) {
+	count := 0
+	if support {
+		count = 1
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "support_agents" WHERE username = $1`)).WithArgs(username).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(count))
+}
+
+// expectCustomerLookup expects a support agent's request naming a customer
+// as requested to be resolved to the customer's username.
+func expectCustomerLookup(mock sqlmock.Sqlmock, requested string, username string) {
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE normalized_username = $1`)).WithArgs(model.NormalizeUsername(requested), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "normalized_username"}).AddRow(1, username, model.NormalizeUsername(username)))
+}
+
+func newWalletOrderServer(t *testing.T) (sqlmock.Sqlmock, *OrderServiceServer) {
+	mock, gormDb := openMockDB(t)
+	catalog := newCatalogServer(t, "1", map[string]menuItemFixture{"Naan": {Price: "40", Currency: "INR"}})
+
+	return mock, &OrderServiceServer{
+		DB:                    gormDb,
+		CatalogServiceAPI:     catalog.URL + "/restaurants/",
+		FulfillmentServiceAPI: httpServerThatFails(t),
+	}
+}
+
+func TestCreateOrder_PaysWithWallet(t *testing.T) {
+	mock, orderServiceServer := newWalletOrderServer(t)
+
+	expectUserLookup(t, mock, "username", "password")
+	mock.ExpectBegin()
+	expectOrderInsert(mock, 7, orderColumns{
+		"wallet_amount": 3000,
+	})
+	mock.ExpectQuery(`INSERT INTO "order_items"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectUserLock(mock, "username")
+	expectWalletBalance(mock, "username", 5000)
+	expectWalletTransfer(mock, "PAYMENT", 7, 3000, "customer:username", "wallet:payments")
+	mock.ExpectCommit()
+
+	response, err := orderServiceServer.Create(basicAuthContext("username", "password"), &o.CreateOrderRequest{
+		RestaurantId: "1",
+		MenuItems:    map[string]int32{"Naan": 2},
+		WalletAmount: &o.Money{CurrencyCode: "INR", AmountMinor: 3000},
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, &o.Money{CurrencyCode: "INR", AmountMinor: 3000}, response.WalletPaid)
+	assert.Equal(t, &o.Money{CurrencyCode: "INR", AmountMinor: 8000}, response.Total)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateOrder_WalletSpentByAnotherOrder_RollsBack(t *testing.T) {
+	mock, orderServiceServer := newWalletOrderServer(t)
+
+	expectUserLookup(t, mock, "username", "password")
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "orders"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectQuery(`INSERT INTO "order_items"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectUserLock(mock, "username")
+	expectWalletBalance(mock, "username", 1000)
+	mock.ExpectRollback()
+
+	_, err := orderServiceServer.Create(basicAuthContext("username", "password"), &o.CreateOrderRequest{
+		RestaurantId: "1",
+		MenuItems:    map[string]int32{"Naan": 2},
+		WalletAmount: &o.Money{CurrencyCode: "INR", AmountMinor: 3000},
+	})
+
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.Equal(t, ReasonInsufficientWalletBalance, errorInfoOf(t, err).Reason)
+	assert.Equal(t, "10.00 INR", errorInfoOf(t, err).Metadata["available"])
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateOrder_InvalidWalletAmount_ReturnsInvalidArgument(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount *o.Money
+		field  string
+	}{
+		{"More Than Total", &o.Money{CurrencyCode: "INR", AmountMinor: 8001}, "wallet_amount.amount_minor"},
+		{"Other Currency", &o.Money{CurrencyCode: "USD", AmountMinor: 100}, "wallet_amount.currency_code"},
+		{"Negative", &o.Money{CurrencyCode: "INR", AmountMinor: -1}, "wallet_amount.amount_minor"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mock, orderServiceServer := newWalletOrderServer(t)
+			expectUserLookup(t, mock, "username", "password")
+
+			_, err := orderServiceServer.Create(basicAuthContext("username", "password"), &o.CreateOrderRequest{
+				RestaurantId: "1",
+				MenuItems:    map[string]int32{"Naan": 2},
+				WalletAmount: test.amount,
+			})
+
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+			assert.Equal(t, []string{test.field}, violatedFields(err))
+			assert.Nil(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRejectOrder_GivesBackStoreCredit(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	restaurantServer := &RestaurantServiceServer{Orders: &OrderServiceServer{DB: gormDb}}
+
+	expectUserLookup(t, mock, "chef", "password")
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "orders" WHERE id = $1`)+`.*FOR UPDATE`).WithArgs(7, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "restaurant_id", "username", "currency", "status", "wallet_amount"}).AddRow(7, "1", "username", "INR", "AWAITING_ACCEPTANCE", 3000))
+	expectOwnership(mock, "chef", true)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "promotion_redemptions" WHERE order_id = $1`)).WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	expectWalletTransfer(mock, "REVERSAL", 7, 3000, "wallet:payments", "customer:username")
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "orders" SET "status"=$1,"cancellation_reason"=$2 WHERE "id" = $3`)).
+		WithArgs("REJECTED", "Out of paneer", 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectOrderLookup(mock, "username", "REJECTED", "")
+
+	_, err := restaurantServer.RejectOrder(basicAuthContext("chef", "password"), &o.RejectOrderRequest{OrderId: 7, Reason: "Out of paneer"})
+
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestDispatchDue_PriceDropped_GivesBackStoreCreditBeyondNewTotal(t *testing.T) {
+	mock, scheduler, now := newTestScheduler(t, "30", http.StatusCreated)
+
+	expectClaimedRow(mock, []driver.Value{7, "1", "username", "INR", 8000, 8000, "", "SCHEDULED", now.Add(30 * time.Minute), "ACCEPT_NEW_PRICE", nil, 8000})
+	expectUserLookup(t, mock, "username", "password")
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "order_items" WHERE order_id = $1 ORDER BY menu_item_name`)).WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "menu_item_name", "quantity", "unit_amount", "line_amount"}).AddRow(1, 7, "Naan", 2, 4000, 8000))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "order_items" WHERE order_id = $1`)).WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO "order_items"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	expectWalletTransfer(mock, "REVERSAL", 7, 2000, "wallet:payments", "customer:username")
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "orders" SET "currency"=$1,"subtotal_amount"=$2,"tax_amount"=$3,"tax_basis_points"=$4,"delivery_fee_amount"=$5,"service_fee_amount"=$6,"discount_amount"=$7,"total_amount"=$8,"loyalty_discount_amount"=$9,"wallet_amount"=$10,"promotion_discount_amount"=$11 WHERE "id" = $12`)).
+		WithArgs("INR", 6000, 0, 0, 0, 0, 0, 6000, 0, 6000, 0, 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "orders" SET "status"=$1,"dispatch_claimed_at"=$2,"accept_by"=$3 WHERE "id" = $4`)).
+		WithArgs("AWAITING_ACCEPTANCE", nil, now.Add(defaultAcceptTimeout), 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.Nil(t, scheduler.DispatchDue())
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestIssueWalletCredit_CreditsOnceAndReturnsItOnRetry(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	adminServer := &WalletAdminServiceServer{Orders: &OrderServiceServer{DB: gormDb}}
+	request := &o.IssueWalletCreditRequest{
+		Username:       "username",
+		Amount:         &o.Money{CurrencyCode: "inr", AmountMinor: 5000},
+		Source:         o.WalletCreditSource_WALLET_CREDIT_GOODWILL,
+		Reason:         "Order arrived cold",
+		IdempotencyKey: "ticket-42",
+	}
+
+	expectUserLookup(t, mock, "agent", "password")
+	expectSupportAgent(mock, "agent", true)
+	expectCustomerLookup(mock, "username", "username")
+	mock.ExpectBegin()
+	expectUserLock(mock, "username")
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "wallet_transactions" WHERE idempotency_key = $1`)).WithArgs("ticket-42", 1).
+		WillReturnError(gorm.ErrRecordNotFound)
+	mock.ExpectQuery(`INSERT INTO "wallet_transactions"`).
+		WithArgs("username", "CREDIT", 0, "INR", 5000, "GOODWILL", "Order arrived cold", "agent", "ticket-42", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectQuery(`INSERT INTO "wallet_entries"`).
+		WithArgs(3, "wallet:goodwill", "INR", -5000, 3, "customer:username", "INR", 5000).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5).AddRow(6))
+	mock.ExpectCommit()
+
+	credit, err := adminServer.IssueWalletCredit(basicAuthContext("agent", "password"), request)
+
+	assert.Nil(t, err)
+	assert.Equal(t, o.WalletTransactionKind_WALLET_CREDIT, credit.Kind)
+	assert.Equal(t, o.WalletCreditSource_WALLET_CREDIT_GOODWILL, credit.Source)
+	assert.Equal(t, &o.Money{CurrencyCode: "INR", AmountMinor: 5000}, credit.Amount)
+
+	expectUserLookup(t, mock, "agent", "password")
+	expectSupportAgent(mock, "agent", true)
+	expectCustomerLookup(mock, "username", "username")
+	mock.ExpectBegin()
+	expectUserLock(mock, "username")
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "wallet_transactions" WHERE idempotency_key = $1`)).WithArgs("ticket-42", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "kind", "currency", "amount", "source", "reason"}).
+			AddRow(3, "username", "CREDIT", "INR", 5000, "GOODWILL", "Order arrived cold"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "wallet_entries" WHERE "wallet_entries"."transaction_id" = $1 ORDER BY id`)).WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "transaction_id", "account", "currency", "amount"}).
+			AddRow(5, 3, "wallet:goodwill", "INR", -5000).AddRow(6, 3, "customer:username", "INR", 5000))
+	mock.ExpectCommit()
+
+	retried, err := adminServer.IssueWalletCredit(basicAuthContext("agent", "password"), request)
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), retried.Id)
+	assert.Equal(t, credit.Amount, retried.Amount)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestIssueWalletCredit_UsernameInOtherCase_CreditsCustomersAccount(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	adminServer := &WalletAdminServiceServer{Orders: &OrderServiceServer{DB: gormDb}}
+
+	expectUserLookup(t, mock, "agent", "password")
+	expectSupportAgent(mock, "agent", true)
+	expectCustomerLookup(mock, "USERNAME", "username")
+	mock.ExpectBegin()
+	expectUserLock(mock, "username")
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "wallet_transactions" WHERE idempotency_key = $1`)).WithArgs("ticket-42", 1).
+		WillReturnError(gorm.ErrRecordNotFound)
+	mock.ExpectQuery(`INSERT INTO "wallet_transactions"`).
+		WithArgs("username", "CREDIT", 0, "INR", 5000, "GOODWILL", "", "agent", "ticket-42", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectQuery(`INSERT INTO "wallet_entries"`).
+		WithArgs(3, "wallet:goodwill", "INR", -5000, 3, "customer:username", "INR", 5000).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5).AddRow(6))
+	mock.ExpectCommit()
+
+	_, err := adminServer.IssueWalletCredit(basicAuthContext("agent", "password"), &o.IssueWalletCreditRequest{
+		Username:       "USERNAME",
+		Amount:         &o.Money{CurrencyCode: "INR", AmountMinor: 5000},
+		Source:         o.WalletCreditSource_WALLET_CREDIT_GOODWILL,
+		IdempotencyKey: "ticket-42",
+	})
+
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestGetCustomerWallet_UsernameInOtherCase_ReturnsCustomersWallet(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	adminServer := &WalletAdminServiceServer{Orders: &OrderServiceServer{DB: gormDb}}
+
+	expectUserLookup(t, mock, "agent", "password")
+	expectSupportAgent(mock, "agent", true)
+	expectCustomerLookup(mock, "USERNAME", "username")
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT currency, SUM(amount) AS amount FROM "wallet_entries" WHERE account = $1 GROUP BY "currency"`)).
+		WithArgs("customer:username").
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "amount"}).AddRow("INR", 5000))
+
+	wallet, err := adminServer.GetCustomerWallet(basicAuthContext("agent", "password"), &o.GetCustomerWalletRequest{Username: "USERNAME"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "username", wallet.Username)
+	assert.Equal(t, []*o.Money{{CurrencyCode: "INR", AmountMinor: 5000}}, wallet.Balances)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestIssueWalletCredit_RefundWithoutOrder_ReturnsInvalidArgument(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	adminServer := &WalletAdminServiceServer{Orders: &OrderServiceServer{DB: gormDb}}
+
+	expectUserLookup(t, mock, "agent", "password")
+	expectSupportAgent(mock, "agent", true)
+
+	_, err := adminServer.IssueWalletCredit(basicAuthContext("agent", "password"), &o.IssueWalletCreditRequest{
+		Username:       "username",
+		Amount:         &o.Money{CurrencyCode: "INR", AmountMinor: 5000},
+		Source:         o.WalletCreditSource_WALLET_CREDIT_REFUND,
+		Reason:         "Missing naan",
+		IdempotencyKey: "ticket-43",
+	})
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, []string{"order_id"}, violatedFields(err))
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestIssueWalletCredit_RefundBeyondOrderTotal_ReturnsFailedPrecondition(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	adminServer := &WalletAdminServiceServer{Orders: &OrderServiceServer{DB: gormDb}}
+
+	expectUserLookup(t, mock, "agent", "password")
+	expectSupportAgent(mock, "agent", true)
+	expectCustomerLookup(mock, "username", "username")
+	mock.ExpectBegin()
+	expectUserLock(mock, "username")
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "wallet_transactions" WHERE idempotency_key = $1`)).WithArgs("ticket-44", 1).
+		WillReturnError(gorm.ErrRecordNotFound)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "orders" WHERE id = $1`)).WithArgs(7, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "currency", "total_amount"}).AddRow(7, "username", "INR", 8000))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "order_items" WHERE "order_items"."order_id" = $1`)).WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id"}))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COALESCE(SUM(amount), 0) FROM "wallet_transactions" WHERE order_id = $1 AND kind = $2 AND source = $3`)).
+		WithArgs(7, "CREDIT", "REFUND").WillReturnRows(sqlmock.NewRows([]string{"refunded"}).AddRow(5000))
+	mock.ExpectRollback()
+
+	_, err := adminServer.IssueWalletCredit(basicAuthContext("agent", "password"), &o.IssueWalletCreditRequest{
+		Username:       "username",
+		Amount:         &o.Money{CurrencyCode: "INR", AmountMinor: 4000},
+		Source:         o.WalletCreditSource_WALLET_CREDIT_REFUND,
+		Reason:         "Missing naan",
+		OrderId:        7,
+		IdempotencyKey: "ticket-44",
+	})
+
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.Equal(t, ReasonRefundExceedsOrder, errorInfoOf(t, err).Reason)
+	assert.Equal(t, "30.00 INR", errorInfoOf(t, err).Metadata["refundable"])
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestIssueWalletCredit_KeyUsedConcurrentlyForAnotherCustomer_ReturnsAlreadyExists(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	adminServer := &WalletAdminServiceServer{Orders: &OrderServiceServer{DB: gormDb}}
+
+	expectUserLookup(t, mock, "agent", "password")
+	expectSupportAgent(mock, "agent", true)
+	expectCustomerLookup(mock, "username", "username")
+	mock.ExpectBegin()
+	expectUserLock(mock, "username")
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "wallet_transactions" WHERE idempotency_key = $1`)).WithArgs("ticket-42", 1).
+		WillReturnError(gorm.ErrRecordNotFound)
+	mock.ExpectQuery(`INSERT INTO "wallet_transactions"`).WillReturnError(&pgconn.PgError{Code: "23505"})
+	mock.ExpectRollback()
+
+	_, err := adminServer.IssueWalletCredit(basicAuthContext("agent", "password"), &o.IssueWalletCreditRequest{
+		Username:       "username",
+		Amount:         &o.Money{CurrencyCode: "INR", AmountMinor: 5000},
+		Source:         o.WalletCreditSource_WALLET_CREDIT_GOODWILL,
+		Reason:         "Order arrived cold",
+		IdempotencyKey: "ticket-42",
+	})
+
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	assert.Equal(t, ReasonIdempotencyKeyReused, errorInfoOf(t, err).Reason)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestIssueWalletCredit_NotSupportAgent_ReturnsPermissionDenied(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	adminServer := &WalletAdminServiceServer{Orders: &OrderServiceServer{DB: gormDb}}
+
+	expectUserLookup(t, mock, "username", "password")
+	expectSupportAgent(mock, "username", false)
+
+	_, err := adminServer.IssueWalletCredit(basicAuthContext("username", "password"), &o.IssueWalletCreditRequest{
+		Username:       "username",
+		Amount:         &o.Money{CurrencyCode: "INR", AmountMinor: 5000},
+		Source:         o.WalletCreditSource_WALLET_CREDIT_GIFT_CARD,
+		Reason:         "Top-up",
+		IdempotencyKey: "card-1",
+	})
+
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.Equal(t, ReasonNotSupportAgent, errorInfoOf(t, err).Reason)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestGetWallet_ReturnsBalancesByCurrency(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	walletServer := &WalletServiceServer{Orders: &OrderServiceServer{DB: gormDb}}
+
+	expectUserLookup(t, mock, "username", "password")
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT currency, SUM(amount) AS amount FROM "wallet_entries" WHERE account = $1 GROUP BY "currency"`)).
+		WithArgs("customer:username").
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "amount"}).AddRow("USD", 250).AddRow("EUR", 0).AddRow("INR", 5000))
+
+	wallet, err := walletServer.GetWallet(basicAuthContext("username", "password"), &o.GetWalletRequest{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []*o.Money{{CurrencyCode: "INR", AmountMinor: 5000}, {CurrencyCode: "USD", AmountMinor: 250}}, wallet.Balances)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestGetWallet_UserNamedLikeServiceAccount_SeesOnlyOwnCredit(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	userServer := &UserServiceServer{DB: gormDb}
+	walletServer := &WalletServiceServer{Orders: &OrderServiceServer{DB: gormDb}}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "users"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	_, err := validated(userServer.Register)(context.Background(), &u.RegisterUserRequest{
+		Username: "payments",
+		Password: "password",
+		Address:  &u.Address{Street: "street", City: "city", State: "state", Zipcode: "zip"},
+	})
+	assert.Nil(t, err)
+
+	expectUserLookup(t, mock, "payments", "password")
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT currency, SUM(amount) AS amount FROM "wallet_entries" WHERE account = $1 GROUP BY "currency"`)).
+		WithArgs("customer:payments").
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "amount"}))
+
+	wallet, err := walletServer.GetWallet(basicAuthContext("payments", "password"), &o.GetWalletRequest{})
+
+	assert.Nil(t, err)
+	assert.NotEqual(t, model.WalletPaymentsAccount, model.WalletAccount("payments"))
+	assert.Empty(t, wallet.Balances)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+	database "orderService.com/go-orderService-grpc/db"
+	"orderService.com/go-orderService-grpc/model"
+	o "orderService.com/go-orderService-grpc/proto/order"
+)
+
+// watchHeartbeatInterval is how often WatchOrder tells the client it is still
+// connected while the order doesn't change.
+var watchHeartbeatInterval = 15 * time.Second
+
+func (orderServer *OrderServiceServer) GetOrder(ctx context.Context, req *o.GetOrderRequest) (*o.Order, error) {
+	user, err := orderServer.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := orderServer.ownedOrder(user, req.OrderId)
+	if err != nil {
+		return nil, err
+	}
+
+	return toProtoOrder(order), nil
+}
+
+func (orderServer *OrderServiceServer) WatchOrder(req *o.WatchOrderRequest, stream o.OrderService_WatchOrderServer) error {
+	ctx := stream.Context()
+
+	user, err := orderServer.authenticate(ctx)
+	if err != nil {
+		return err
+	}
+
+	if orderServer.Updates == nil {
+		return errInternal("order updates are not configured")
+	}
+
+	// Subscribing before the first read makes sure no change is missed
+	// between the two.
+	subscription := orderServer.Updates.Subscribe(req.OrderId)
+	defer subscription.Close()
+
+	heartbeat := time.NewTicker(watchHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	var sent *o.Order
+	for {
+		order, err := orderServer.ownedOrder(user, req.OrderId)
+		if err != nil {
+			return err
+		}
+
+		current := toProtoOrder(order)
+		if !proto.Equal(current, sent) {
+			if err := stream.Send(&o.OrderUpdate{Order: current, SentAt: timestamppb.Now()}); err != nil {
+				return err
+			}
+			sent = current
+			heartbeat.Reset(watchHeartbeatInterval)
+		}
+
+		if order.Final() {
+			return nil
+		}
+
+	wait:
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-heartbeat.C:
+				if err := stream.Send(&o.OrderUpdate{Heartbeat: true, SentAt: timestamppb.Now()}); err != nil {
+					return err
+				}
+			case <-subscription.C:
+				break wait
+			}
+		}
+	}
+}
+
+// ownedOrder reads an order of the user with its items.
+func (orderServer *OrderServiceServer) ownedOrder(user *model.User, orderId int64) (*model.Order, error) {
+	order, err := database.GetOrder(orderServer.DB, orderId)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && order.Username != user.Username) {
+		return nil, errOrderNotFound(orderId)
+	}
+
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return order, nil
+}
+
+// publishUpdate tells the order's watchers and its restaurant's feeds that
+// the order changed.
+func (orderServer *OrderServiceServer) publishUpdate(order *model.Order) {
+	if order.Final() {
+		orderServer.Surge.Closed(order.Id)
+	}
+
+	orderServer.Updates.Publish(order.Id)
+	orderServer.RestaurantUpdates.Publish(order.RestaurantId)
+}
+
+func toProtoOrder(order *model.Order) *o.Order {
+	breakdown := breakdownOf(order)
+
+	response := &o.Order{
+		Id:                 order.Id,
+		Username:           order.Username,
+		RestaurantId:       order.RestaurantId,
+		Status:             orderStatus(order),
+		LineItems:          toLineItems(order.Items, order.Currency),
+		Breakdown:          toProtoBreakdown(breakdown),
+		Total:              toProtoMoney(breakdown.Total),
+		PromoCode:          order.PromoCode,
+		CancellationReason: order.CancellationReason,
+		PrepTimeMinutes:    order.PrepTimeMinutes,
+
+		LoyaltyPointsRedeemed: order.LoyaltyPointsRedeemed,
+		WalletPaid:            walletPaid(order),
+	}
+
+	if order.Status == model.OrderAwaitingAcceptance && order.AcceptBy != nil {
+		response.AcceptBy = timestamppb.New(*order.AcceptBy)
+	}
+
+	if order.ScheduledFor != nil {
+		response.ScheduledFor = timestamppb.New(*order.ScheduledFor)
+	}
+
+	if order.EstimatedDeliveryAt != nil {
+		response.EstimatedDeliveryAt = timestamppb.New(*order.EstimatedDeliveryAt)
+	}
+
+	if order.CourierName != "" || order.CourierPhone != "" {
+		response.Courier = &o.Courier{Name: order.CourierName, Phone: order.CourierPhone}
+	}
+
+	return response
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	o "orderService.com/go-orderService-grpc/proto/order"
+	"orderService.com/go-orderService-grpc/pubsub"
+)
+
+// watchStream collects what WatchOrder sends.
+type watchStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	updates chan *o.OrderUpdate
+}
+
+func (stream *watchStream) Context() context.Context {
+	return stream.ctx
+}
+
+func (stream *watchStream) Send(update *o.OrderUpdate) error {
+	stream.updates <- update
+	return nil
+}
+
+func expectOrderLookup(mock sqlmock.Sqlmock, username string, status string, courierName string) {
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "orders" WHERE id = $1`)).WithArgs(7, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "restaurant_id", "username", "currency", "total_amount", "status", "courier_name"}).
+			AddRow(7, "1", username, "INR", 8000, status, courierName))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "order_items" WHERE "order_items"."order_id" = $1`)).WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "menu_item_name", "quantity", "unit_amount", "line_amount"}).AddRow(1, 7, "Naan", 2, 4000, 8000))
+}
+
+func nextUpdate(t *testing.T, stream *watchStream) *o.OrderUpdate {
+	select {
+	case update := <-stream.updates:
+		return update
+	case <-time.After(time.Second):
+		t.Fatal("Expected an order update")
+		return nil
+	}
+}
+
+func TestGetOrder(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	orderServiceServer := &OrderServiceServer{DB: gormDb}
+
+	expectUserLookup(t, mock, "username", "password")
+	expectOrderLookup(mock, "username", "DISPATCHED", "Ravi")
+
+	order, err := orderServiceServer.GetOrder(basicAuthContext("username", "password"), &o.GetOrderRequest{OrderId: 7})
+
+	assert.Nil(t, err)
+	assert.Equal(t, o.OrderStatus_ORDER_DISPATCHED, order.Status)
+	assert.Equal(t, &o.Courier{Name: "Ravi"}, order.Courier)
+	assert.Equal(t, &o.Money{CurrencyCode: "INR", AmountMinor: 8000}, order.Total)
+	assert.Len(t, order.LineItems, 1)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestWatchOrder_SendsCurrentStateThenChangesUntilFinal(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	hub := pubsub.NewHub[int64]()
+	orderServiceServer := &OrderServiceServer{DB: gormDb, Updates: hub}
+	stream := &watchStream{ctx: basicAuthContext("username", "password"), updates: make(chan *o.OrderUpdate, 10)}
+
+	expectUserLookup(t, mock, "username", "password")
+	expectOrderLookup(mock, "username", "SCHEDULED", "")
+
+	done := make(chan error)
+	go func() { done <- orderServiceServer.WatchOrder(&o.WatchOrderRequest{OrderId: 7}, stream) }()
+
+	assert.Equal(t, o.OrderStatus_ORDER_SCHEDULED, nextUpdate(t, stream).Order.Status)
+
+	expectOrderLookup(mock, "username", "CANCELLED", "")
+	hub.Publish(7)
+
+	assert.Equal(t, o.OrderStatus_ORDER_CANCELLED, nextUpdate(t, stream).Order.Status)
+	assert.Nil(t, <-done)
+	assert.Equal(t, 0, hub.Subscribers(7))
+	assert.Empty(t, stream.updates)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestWatchOrder_HeartbeatsAndStopsWhenClientLeaves(t *testing.T) {
+	defer func(interval time.Duration) { watchHeartbeatInterval = interval }(watchHeartbeatInterval)
+	watchHeartbeatInterval = 10 * time.Millisecond
+
+	mock, gormDb := openMockDB(t)
+	hub := pubsub.NewHub[int64]()
+	orderServiceServer := &OrderServiceServer{DB: gormDb, Updates: hub}
+	ctx, cancel := context.WithCancel(basicAuthContext("username", "password"))
+	stream := &watchStream{ctx: ctx, updates: make(chan *o.OrderUpdate, 10)}
+
+	expectUserLookup(t, mock, "username", "password")
+	expectOrderLookup(mock, "username", "PLACED", "")
+
+	done := make(chan error)
+	go func() { done <- orderServiceServer.WatchOrder(&o.WatchOrderRequest{OrderId: 7}, stream) }()
+
+	assert.NotNil(t, nextUpdate(t, stream).Order)
+	heartbeat := nextUpdate(t, stream)
+	assert.True(t, heartbeat.Heartbeat)
+	assert.Nil(t, heartbeat.Order)
+
+	cancel()
+	assert.Nil(t, <-done)
+	assert.Equal(t, 0, hub.Subscribers(7))
+}
+
+func TestWatchOrder_OrderOfAnotherUser_ReturnsNotFound(t *testing.T) {
+	mock, gormDb := openMockDB(t)
+	orderServiceServer := &OrderServiceServer{DB: gormDb, Updates: pubsub.NewHub[int64]()}
+	stream := &watchStream{ctx: basicAuthContext("username", "password"), updates: make(chan *o.OrderUpdate, 10)}
+
+	expectUserLookup(t, mock, "username", "password")
+	expectOrderLookup(mock, "someone else", "PLACED", "")
+
+	err := orderServiceServer.WatchOrder(&o.WatchOrderRequest{OrderId: 7}, stream)
+
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Empty(t, stream.updates)
+}
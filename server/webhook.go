@@ -0,0 +1,310 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	database "orderService.com/go-orderService-grpc/db"
+	"orderService.com/go-orderService-grpc/model"
+)
+
+const (
+	fulfillmentWebhookPath     = "/webhooks/fulfillment"
+	fulfillmentSignatureHeader = "X-Fulfillment-Signature"
+	fulfillmentTimestampHeader = "X-Fulfillment-Timestamp"
+	// webhookTolerance is how far the timestamp of a callback may be from
+	// our clock. Older callbacks are rejected as possible replays.
+	webhookTolerance    = 5 * time.Minute
+	maxWebhookBodyBytes = 64 << 10
+
+	// Callbacks are small, so slow clients are cut off early rather than
+	// holding connections open.
+	webhookReadHeaderTimeout = 5 * time.Second
+	webhookReadTimeout       = 10 * time.Second
+	webhookWriteTimeout      = 10 * time.Second
+	webhookIdleTimeout       = 60 * time.Second
+)
+
+var (
+	errWebhookSignature = errors.New("signature does not match")
+	errWebhookTimestamp = errors.New("timestamp is missing or outside the tolerance")
+	errWebhookReplayed  = errors.New("callback was already received")
+)
+
+var deliveryStatuses = map[string]model.OrderStatus{
+	"COURIER_ASSIGNED": model.OrderCourierAssigned,
+	"PICKED_UP":        model.OrderPickedUp,
+	"DELIVERED":        model.OrderDelivered,
+}
+
+// deliveryProgress ranks the statuses of orders handed to the fulfillment
+// service along a delivery. Callbacks may arrive out of order, so one that
+// would move an order back is ignored. Accepted orders are sent while
+// OrderDispatching and may hear back before they are marked dispatched.
+var deliveryProgress = map[model.OrderStatus]int{
+	model.OrderDispatching:     0,
+	model.OrderPlaced:          1,
+	model.OrderDispatched:      1,
+	model.OrderCourierAssigned: 2,
+	model.OrderPickedUp:        3,
+	model.OrderDelivered:       4,
+}
+
+// deliveryEvent is the body of a fulfillment service callback.
+type deliveryEvent struct {
+	OrderId int64  `json:"orderId"`
+	Status  string `json:"status"`
+	Courier *struct {
+		Name  string `json:"name"`
+		Phone string `json:"phone"`
+		// Username is the courier's account with this service, if any.
+		Username string `json:"username"`
+	} `json:"courier"`
+	EstimatedDeliveryAt *time.Time `json:"estimatedDeliveryAt"`
+}
+
+// FulfillmentWebhook receives delivery status callbacks from the fulfillment
+// service. Each callback is signed with a secret shared with the service:
+// X-Fulfillment-Signature is "sha256=" followed by the hex HMAC-SHA256 of the
+// X-Fulfillment-Timestamp header (unix seconds), a dot and the body.
+type FulfillmentWebhook struct {
+	Orders *OrderServiceServer
+	secret []byte
+	now    func() time.Time
+
+	mu sync.Mutex
+	// seen holds the signatures of callbacks being handled or handled until
+	// their timestamp falls out of the tolerance, after which they are
+	// rejected anyway. It is kept in memory, so a replay sent to another
+	// instance, or after a restart, within the tolerance is not caught. Such
+	// a replay can only repeat a signed status the order already reached:
+	// apply ignores it for delivered orders and otherwise writes the same
+	// values again.
+	seen map[string]time.Time
+}
+
+func NewFulfillmentWebhook(orders *OrderServiceServer, secret []byte) *FulfillmentWebhook {
+	return &FulfillmentWebhook{Orders: orders, secret: secret, now: time.Now, seen: make(map[string]time.Time)}
+}
+
+func serveFulfillmentWebhook(orders *OrderServiceServer, secret []byte) {
+	mux := http.NewServeMux()
+	mux.Handle(fulfillmentWebhookPath, NewFulfillmentWebhook(orders, secret))
+
+	server := &http.Server{
+		Addr:              ":8003",
+		Handler:           mux,
+		ReadHeaderTimeout: webhookReadHeaderTimeout,
+		ReadTimeout:       webhookReadTimeout,
+		WriteTimeout:      webhookWriteTimeout,
+		IdleTimeout:       webhookIdleTimeout,
+	}
+
+	err := server.ListenAndServe()
+	if err != nil {
+		log.Fatalf("Failed to serve 8003: %v", err)
+	}
+}
+
+func (webhook *FulfillmentWebhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodyBytes))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, "body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if err != nil {
+		http.Error(w, "could not read body", http.StatusBadRequest)
+		return
+	}
+
+	signature, err := webhook.verify(r.Header, body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var event deliveryEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	status, ok := deliveryStatuses[event.Status]
+	if !ok || event.OrderId <= 0 {
+		http.Error(w, "invalid orderId or status", http.StatusBadRequest)
+		return
+	}
+
+	err = webhook.apply(event, status)
+	if err != nil {
+		webhook.forget(signature)
+	}
+
+	var domainErr *DomainError
+	switch {
+	case errors.As(err, &domainErr) && domainErr.Reason == ReasonOrderNotFound:
+		http.Error(w, domainErr.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		log.Printf("Error applying delivery status of order %d: %v", event.OrderId, err)
+		http.Error(w, "could not apply the status", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// verify checks the signature and timestamp of a callback and returns the
+// signature. The signature is reserved right away, so that a replay sent
+// while the callback is being applied is rejected too.
+func (webhook *FulfillmentWebhook) verify(header http.Header, body []byte) (string, error) {
+	timestamp := header.Get(fulfillmentTimestampHeader)
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "", errWebhookTimestamp
+	}
+
+	age := webhook.now().Sub(time.Unix(seconds, 0))
+	if age > webhookTolerance || age < -webhookTolerance {
+		return "", errWebhookTimestamp
+	}
+
+	mac := hmac.New(sha256.New, webhook.secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+
+	signature := strings.TrimPrefix(header.Get(fulfillmentSignatureHeader), "sha256=")
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return "", errWebhookSignature
+	}
+
+	webhook.mu.Lock()
+	defer webhook.mu.Unlock()
+
+	now := webhook.now()
+	for seenSignature, forgetAt := range webhook.seen {
+		if now.After(forgetAt) {
+			delete(webhook.seen, seenSignature)
+		}
+	}
+
+	if _, seen := webhook.seen[signature]; seen {
+		return "", errWebhookReplayed
+	}
+
+	webhook.seen[signature] = now.Add(2 * webhookTolerance)
+	return signature, nil
+}
+
+// forget releases the signature of a callback that could not be applied, so
+// the fulfillment service can retry it.
+func (webhook *FulfillmentWebhook) forget(signature string) {
+	webhook.mu.Lock()
+	defer webhook.mu.Unlock()
+
+	delete(webhook.seen, signature)
+}
+
+// apply moves the order forward to status. Callbacks for final orders, for
+// orders that were never handed to the fulfillment service, or that arrive
+// after a later status, change nothing.
+func (webhook *FulfillmentWebhook) apply(event deliveryEvent, status model.OrderStatus) error {
+	var changed *model.Order
+
+	err := webhook.Orders.DB.Transaction(func(tx *gorm.DB) error {
+		order, err := database.LockOrder(tx, event.OrderId)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errOrderNotFound(event.OrderId)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		progress, handedOver := deliveryProgress[order.Status]
+		if order.Status == model.OrderDispatching && order.AcceptedAt == nil {
+			// A scheduled order being claimed, not yet accepted.
+			handedOver = false
+		}
+
+		if order.Final() || !handedOver || deliveryProgress[status] < progress {
+			return nil
+		}
+
+		order.Status = status
+		if event.Courier != nil {
+			order.CourierName = event.Courier.Name
+			order.CourierPhone = event.Courier.Phone
+			order.CourierUsername = event.Courier.Username
+		}
+		if event.EstimatedDeliveryAt != nil {
+			order.EstimatedDeliveryAt = event.EstimatedDeliveryAt
+		} else if estimate, ok := webhook.estimate(order, status); ok {
+			order.EstimatedDeliveryAt = &estimate
+		}
+
+		if status == model.OrderDelivered {
+			if err := webhook.Orders.earnLoyaltyPoints(tx, order, webhook.now()); err != nil {
+				return err
+			}
+		}
+
+		changed = order
+		return database.UpdateOrder(tx, order, "status", "courier_name", "courier_phone", "courier_username", "estimated_delivery_at")
+	})
+	if err != nil {
+		return err
+	}
+
+	if changed != nil {
+		webhook.Orders.publishUpdate(changed)
+	}
+
+	return nil
+}
+
+// estimate updates the delivery estimate of an order for a callback that
+// didn't carry one: once a courier is assigned, what is left of the
+// preparation time still applies; once picked up, only the ride is left.
+func (webhook *FulfillmentWebhook) estimate(order *model.Order, status model.OrderStatus) (time.Time, bool) {
+	estimator := webhook.Orders.ETA
+	if estimator == nil || order.DeliveryDistanceMeters == nil {
+		return time.Time{}, false
+	}
+
+	now := webhook.now()
+	meters := *order.DeliveryDistanceMeters
+
+	switch status {
+	case model.OrderCourierAssigned:
+		prep := time.Duration(0)
+		if order.AcceptedAt != nil {
+			prep = order.AcceptedAt.Add(time.Duration(order.PrepTimeMinutes) * time.Minute).Sub(now)
+		}
+		return estimator.Estimate(now, prep, meters), true
+	case model.OrderPickedUp:
+		return estimator.AfterPickup(now, meters), true
+	default:
+		return time.Time{}, false
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"orderService.com/go-orderService-grpc/eta"
+	"orderService.com/go-orderService-grpc/geo"
+	"orderService.com/go-orderService-grpc/pubsub"
+)
+
+var webhookNow = time.Date(2024, 3, 10, 20, 0, 0, 0, time.UTC)
+
+func newTestWebhook(t *testing.T) (sqlmock.Sqlmock, *FulfillmentWebhook, *pubsub.Hub[int64]) {
+	mock, gormDb := openMockDB(t)
+	hub := pubsub.NewHub[int64]()
+
+	webhook := NewFulfillmentWebhook(&OrderServiceServer{DB: gormDb, Updates: hub}, []byte("secret"))
+	webhook.now = func() time.Time { return webhookNow }
+
+	return mock, webhook, hub
+}
+
+func signedCallback(secret string, timestamp time.Time, body string) *http.Request {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unix + "." + body))
+
+	req := httptest.NewRequest(http.MethodPost, fulfillmentWebhookPath, strings.NewReader(body))
+	req.Header.Set(fulfillmentTimestampHeader, unix)
+	req.Header.Set(fulfillmentSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	return req
+}
+
+func serve(webhook *FulfillmentWebhook, req *http.Request) int {
+	recorder := httptest.NewRecorder()
+	webhook.ServeHTTP(recorder, req)
+	return recorder.Code
+}
+
+func expectLockedOrder(mock sqlmock.Sqlmock, status string) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "orders" WHERE id = $1`)+`.*FOR UPDATE`).WithArgs(7, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "status"}).AddRow(7, "username", status))
+}
+
+const courierAssigned = `{"orderId":7,"status":"COURIER_ASSIGNED","courier":{"name":"Ravi","phone":"+911234567890","username":"ravi"},"estimatedDeliveryAt":"2024-03-10T20:40:00Z"}`
+
+func TestFulfillmentWebhook_AppliesStatusOnceAndNotifiesWatchers(t *testing.T) {
+	mock, webhook, hub := newTestWebhook(t)
+	subscription := hub.Subscribe(7)
+
+	expectLockedOrder(mock, "DISPATCHED")
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "orders" SET "status"=$1,"estimated_delivery_at"=$2,"courier_name"=$3,"courier_phone"=$4,"courier_username"=$5 WHERE "id" = $6`)).
+		WithArgs("COURIER_ASSIGNED", time.Date(2024, 3, 10, 20, 40, 0, 0, time.UTC), "Ravi", "+911234567890", "ravi", 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.Equal(t, http.StatusNoContent, serve(webhook, signedCallback("secret", webhookNow, courierAssigned)))
+	assert.Len(t, subscription.C, 1)
+
+	// The same callback sent again is a replay.
+	assert.Equal(t, http.StatusUnauthorized, serve(webhook, signedCallback("secret", webhookNow, courierAssigned)))
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestFulfillmentWebhook_RejectsUnverifiableCallbacks(t *testing.T) {
+	_, webhook, _ := newTestWebhook(t)
+
+	assert.Equal(t, http.StatusUnauthorized, serve(webhook, signedCallback("other secret", webhookNow, courierAssigned)))
+	assert.Equal(t, http.StatusUnauthorized, serve(webhook, signedCallback("secret", webhookNow.Add(-10*time.Minute), courierAssigned)))
+
+	unsigned := httptest.NewRequest(http.MethodPost, fulfillmentWebhookPath, strings.NewReader(courierAssigned))
+	assert.Equal(t, http.StatusUnauthorized, serve(webhook, unsigned))
+
+	tampered := signedCallback("secret", webhookNow, courierAssigned)
+	tampered.Body = httptest.NewRequest(http.MethodPost, fulfillmentWebhookPath, strings.NewReader(strings.Replace(courierAssigned, "7", "8", 1))).Body
+	assert.Equal(t, http.StatusUnauthorized, serve(webhook, tampered))
+
+	assert.Equal(t, http.StatusBadRequest, serve(webhook, signedCallback("secret", webhookNow, `{"orderId":7,"status":"LOST"}`)))
+
+	oversized := `{"orderId":7,"status":"COURIER_ASSIGNED","padding":"` + strings.Repeat("x", maxWebhookBodyBytes) + `"}`
+	assert.Equal(t, http.StatusRequestEntityTooLarge, serve(webhook, signedCallback("secret", webhookNow, oversized)))
+}
+
+func TestFulfillmentWebhook_IgnoresOutOfOrderStatus(t *testing.T) {
+	mock, webhook, hub := newTestWebhook(t)
+	subscription := hub.Subscribe(7)
+
+	expectLockedOrder(mock, "PICKED_UP")
+	mock.ExpectCommit()
+
+	assert.Equal(t, http.StatusNoContent, serve(webhook, signedCallback("secret", webhookNow, courierAssigned)))
+	assert.Len(t, subscription.C, 0)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestFulfillmentWebhook_IgnoresOrdersNeverDispatched(t *testing.T) {
+	mock, webhook, hub := newTestWebhook(t)
+	subscription := hub.Subscribe(7)
+
+	expectLockedOrder(mock, "AWAITING_ACCEPTANCE")
+	mock.ExpectCommit()
+
+	assert.Equal(t, http.StatusNoContent, serve(webhook, signedCallback("secret", webhookNow, `{"orderId":7,"status":"DELIVERED"}`)))
+	assert.Len(t, subscription.C, 0)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestFulfillmentWebhook_FailedCallback_CanBeRetried(t *testing.T) {
+	mock, webhook, _ := newTestWebhook(t)
+
+	expectLockedOrder(mock, "DISPATCHED")
+	mock.ExpectExec(`UPDATE "orders"`).WillReturnError(errors.New("connection reset"))
+	mock.ExpectRollback()
+	expectLockedOrder(mock, "DISPATCHED")
+	mock.ExpectExec(`UPDATE "orders"`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.Equal(t, http.StatusInternalServerError, serve(webhook, signedCallback("secret", webhookNow, courierAssigned)))
+	assert.Equal(t, http.StatusNoContent, serve(webhook, signedCallback("secret", webhookNow, courierAssigned)))
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestFulfillmentWebhook_UnknownOrder_ReturnsNotFound(t *testing.T) {
+	mock, webhook, _ := newTestWebhook(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "orders"`).WillReturnError(gorm.ErrRecordNotFound)
+	mock.ExpectRollback()
+
+	assert.Equal(t, http.StatusNotFound, serve(webhook, signedCallback("secret", webhookNow, courierAssigned)))
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestFulfillmentWebhook_PickedUpWithoutEstimate_EstimatesRide(t *testing.T) {
+	mock, webhook, _ := newTestWebhook(t)
+	webhook.Orders.ETA = eta.NewEstimator(geo.Centroids{}, eta.DefaultConfig())
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "orders" WHERE id = $1`)+`.*FOR UPDATE`).WithArgs(7, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "status", "delivery_distance_meters"}).AddRow(7, "username", "COURIER_ASSIGNED", 10000))
+	// 10 km of road at 1.3 times the straight line, at 20 km/h, then 5 minutes to drop.
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "orders" SET "status"=$1,"estimated_delivery_at"=$2`)).
+		WithArgs("PICKED_UP", webhookNow.Add(44*time.Minute), "", "", "", 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.Equal(t, http.StatusNoContent, serve(webhook, signedCallback("secret", webhookNow, `{"orderId":7,"status":"PICKED_UP"}`)))
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,219 @@
+package surge
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"orderService.com/go-orderService-grpc/geo"
+	"orderService.com/go-orderService-grpc/model"
+)
+
+// NoSurge is the multiplier of a regular delivery fee. Multipliers are in
+// basis points, so 15000 charges one and a half times the fee.
+const NoSurge = 10000
+
+// Config tunes how scarce couriers and busy zones raise the delivery fee.
+type Config struct {
+	// WindowMinutes is how far back dispatch outcomes count.
+	WindowMinutes float64 `json:"window_minutes"`
+	// MinOutcomes is how many dispatches a zone needs within the window
+	// before its failure rate is trusted.
+	MinOutcomes int `json:"min_outcomes"`
+	// FailureBasisPoints is added when no dispatch in the window found a
+	// courier, and proportionally less when some did.
+	FailureBasisPoints int64 `json:"failure_basis_points"`
+	// Every OpenOrdersPerStep orders open in a zone add StepBasisPoints.
+	OpenOrdersPerStep int   `json:"open_orders_per_step"`
+	StepBasisPoints   int64 `json:"step_basis_points"`
+	// MaxBasisPoints bounds the multiplier.
+	MaxBasisPoints int64 `json:"max_basis_points"`
+	// OpenOrderTTLMinutes stops counting orders that were never seen to
+	// close, e.g. because a webhook went missing.
+	OpenOrderTTLMinutes float64 `json:"open_order_ttl_minutes"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		WindowMinutes:       15,
+		MinOutcomes:         5,
+		FailureBasisPoints:  10000,
+		OpenOrdersPerStep:   10,
+		StepBasisPoints:     1000,
+		MaxBasisPoints:      20000,
+		OpenOrderTTLMinutes: 180,
+	}
+}
+
+// LoadConfig reads a JSON encoded Config, starting from DefaultConfig so that
+// the file only needs to list what it overrides.
+func LoadConfig(path string) (Config, error) {
+	config := DefaultConfig()
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+
+	if err := json.Unmarshal(content, &config); err != nil {
+		return Config{}, err
+	}
+
+	switch {
+	case config.WindowMinutes <= 0 || config.OpenOrderTTLMinutes <= 0:
+		return Config{}, errors.New("window_minutes and open_order_ttl_minutes must be positive")
+	case config.MinOutcomes < 0 || config.OpenOrdersPerStep < 0 || config.FailureBasisPoints < 0 || config.StepBasisPoints < 0:
+		return Config{}, errors.New("surge settings must not be negative")
+	case config.MaxBasisPoints < NoSurge:
+		return Config{}, errors.New("max_basis_points must be at least 10000")
+	}
+
+	return config, nil
+}
+
+// Tracker keeps the recent dispatch outcomes and the open orders of each
+// zone, the ZIP code of the restaurant: couriers are sought around the
+// pickup address. It only knows what this process saw since it started. A
+// nil Tracker never surges.
+type Tracker struct {
+	config Config
+	now    func() time.Time
+
+	mu       sync.Mutex
+	outcomes map[string][]outcome
+	open     map[string]map[int64]time.Time
+	zones    map[int64]string
+}
+
+type outcome struct {
+	at     time.Time
+	failed bool
+}
+
+func NewTracker(config Config) *Tracker {
+	return &Tracker{
+		config:   config,
+		now:      time.Now,
+		outcomes: map[string][]outcome{},
+		open:     map[string]map[int64]time.Time{},
+		zones:    map[int64]string{},
+	}
+}
+
+func zoneOf(pickup *model.Address) string {
+	if pickup == nil {
+		return ""
+	}
+
+	return geo.NormalizeZipcode(pickup.Zipcode)
+}
+
+// RecordDispatch notes whether the fulfillment service found a courier for
+// an order picked up at pickup.
+func (tracker *Tracker) RecordDispatch(pickup *model.Address, courierFound bool) {
+	zone := zoneOf(pickup)
+	if tracker == nil || zone == "" {
+		return
+	}
+
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	tracker.outcomes[zone] = append(tracker.prune(zone), outcome{at: tracker.now(), failed: !courierFound})
+}
+
+// Opened counts the order towards its zone until it is Closed.
+func (tracker *Tracker) Opened(orderId int64, pickup *model.Address) {
+	zone := zoneOf(pickup)
+	if tracker == nil || zone == "" {
+		return
+	}
+
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	if tracker.open[zone] == nil {
+		tracker.open[zone] = map[int64]time.Time{}
+	}
+	tracker.open[zone][orderId] = tracker.now()
+	tracker.zones[orderId] = zone
+}
+
+func (tracker *Tracker) Closed(orderId int64) {
+	if tracker == nil {
+		return
+	}
+
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	if zone, ok := tracker.zones[orderId]; ok {
+		delete(tracker.open[zone], orderId)
+		delete(tracker.zones, orderId)
+	}
+}
+
+// Multiplier is the surge on the delivery fee of orders picked up at pickup,
+// between NoSurge and the configured maximum.
+func (tracker *Tracker) Multiplier(pickup *model.Address) int64 {
+	zone := zoneOf(pickup)
+	if tracker == nil || zone == "" {
+		return NoSurge
+	}
+
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	multiplier := int64(NoSurge)
+
+	outcomes := tracker.prune(zone)
+	if len(outcomes) > 0 && len(outcomes) >= tracker.config.MinOutcomes {
+		var failed int64
+		for _, outcome := range outcomes {
+			if outcome.failed {
+				failed++
+			}
+		}
+
+		multiplier += tracker.config.FailureBasisPoints * failed / int64(len(outcomes))
+	}
+
+	if tracker.config.OpenOrdersPerStep > 0 {
+		openSince := tracker.now().Add(-minutes(tracker.config.OpenOrderTTLMinutes))
+		for orderId, opened := range tracker.open[zone] {
+			if opened.Before(openSince) {
+				delete(tracker.open[zone], orderId)
+				delete(tracker.zones, orderId)
+			}
+		}
+
+		multiplier += tracker.config.StepBasisPoints * int64(len(tracker.open[zone])/tracker.config.OpenOrdersPerStep)
+	}
+
+	return min(multiplier, max(tracker.config.MaxBasisPoints, NoSurge))
+}
+
+// prune drops the zone's outcomes that fell out of the window and returns
+// the rest. The caller holds mu.
+func (tracker *Tracker) prune(zone string) []outcome {
+	since := tracker.now().Add(-minutes(tracker.config.WindowMinutes))
+
+	outcomes := tracker.outcomes[zone]
+	for len(outcomes) > 0 && outcomes[0].at.Before(since) {
+		outcomes = outcomes[1:]
+	}
+
+	if len(outcomes) == 0 {
+		delete(tracker.outcomes, zone)
+		return nil
+	}
+
+	tracker.outcomes[zone] = outcomes
+	return outcomes
+}
+
+func minutes(value float64) time.Duration {
+	return time.Duration(value * float64(time.Minute))
+}
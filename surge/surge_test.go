@@ -0,0 +1,84 @@
+package surge
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"orderService.com/go-orderService-grpc/model"
+)
+
+func newTracker(config Config) (*Tracker, *time.Time) {
+	now := time.Date(2024, 3, 8, 19, 0, 0, 0, time.UTC)
+	tracker := NewTracker(config)
+	tracker.now = func() time.Time { return now }
+	return tracker, &now
+}
+
+func address(zipcode string) *model.Address {
+	return &model.Address{Zipcode: zipcode}
+}
+
+func TestMultiplier_FailedDispatchesWithinWindow(t *testing.T) {
+	tracker, now := newTracker(Config{WindowMinutes: 15, MinOutcomes: 4, FailureBasisPoints: 10000, MaxBasisPoints: 20000})
+
+	for _, found := range []bool{true, false, false} {
+		tracker.RecordDispatch(address("560001"), found)
+	}
+	// Too few outcomes to tell.
+	assert.Equal(t, int64(NoSurge), tracker.Multiplier(address("560001")))
+
+	tracker.RecordDispatch(address("560 001"), false)
+	assert.Equal(t, int64(17500), tracker.Multiplier(address("560001")))
+	assert.Equal(t, int64(NoSurge), tracker.Multiplier(address("560002")))
+
+	*now = now.Add(16 * time.Minute)
+	assert.Equal(t, int64(NoSurge), tracker.Multiplier(address("560001")))
+}
+
+func TestMultiplier_OpenOrdersAndBound(t *testing.T) {
+	tracker, now := newTracker(Config{WindowMinutes: 15, OpenOrdersPerStep: 2, StepBasisPoints: 2500, MaxBasisPoints: 15000, OpenOrderTTLMinutes: 60})
+
+	for orderId := int64(1); orderId <= 3; orderId++ {
+		tracker.Opened(orderId, address("560001"))
+	}
+	assert.Equal(t, int64(12500), tracker.Multiplier(address("560001")))
+
+	tracker.Opened(4, address("560001"))
+	tracker.Opened(5, address("560001"))
+	assert.Equal(t, int64(15000), tracker.Multiplier(address("560001")))
+
+	tracker.Closed(1)
+	tracker.Closed(2)
+	tracker.Closed(3)
+	assert.Equal(t, int64(12500), tracker.Multiplier(address("560001")))
+
+	*now = now.Add(61 * time.Minute)
+	assert.Equal(t, int64(NoSurge), tracker.Multiplier(address("560001")))
+}
+
+func TestMultiplier_NilTrackerNeverSurges(t *testing.T) {
+	var tracker *Tracker
+
+	tracker.RecordDispatch(address("560001"), false)
+	tracker.Opened(1, address("560001"))
+	tracker.Closed(1)
+	assert.Equal(t, int64(NoSurge), tracker.Multiplier(address("560001")))
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "surge.json")
+
+	assert.Nil(t, os.WriteFile(path, []byte(`{"max_basis_points":25000}`), 0o600))
+	config, err := LoadConfig(path)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(25000), config.MaxBasisPoints)
+	assert.Equal(t, 15.0, config.WindowMinutes)
+
+	assert.Nil(t, os.WriteFile(path, []byte(`{"max_basis_points":5000}`), 0o600))
+	_, err = LoadConfig(path)
+	assert.ErrorContains(t, err, "max_basis_points")
+}
@@ -0,0 +1,188 @@
+package validation
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+	"unicode/utf8"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"orderService.com/go-orderService-grpc/proto/validate"
+)
+
+var patterns sync.Map
+
+// Validate checks msg against the (validate.rules) options declared on its
+// fields and returns one violation per broken constraint.
+func Validate(msg proto.Message) []*errdetails.BadRequest_FieldViolation {
+	var violations []*errdetails.BadRequest_FieldViolation
+	validateMessage(msg.ProtoReflect(), "", &violations)
+	return violations
+}
+
+func validateMessage(msg protoreflect.Message, prefix string, violations *[]*errdetails.BadRequest_FieldViolation) {
+	fields := msg.Descriptor().Fields()
+
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		path := prefix + string(field.Name())
+		rules := rulesOf(field)
+
+		switch {
+		case field.IsMap():
+			validateMap(msg.Get(field).Map(), field, rules, path, violations)
+		case field.IsList():
+			validateList(msg.Get(field).List(), field, rules, path, violations)
+		case field.Kind() == protoreflect.MessageKind:
+			if !msg.Has(field) {
+				if rules.GetRequired() {
+					addViolation(violations, path, "value is required")
+				}
+				continue
+			}
+			validateMessage(msg.Get(field).Message(), path+".", violations)
+		default:
+			validateScalar(msg.Get(field), rules, path, violations)
+		}
+	}
+}
+
+func validateMap(entries protoreflect.Map, field protoreflect.FieldDescriptor, rules *validate.FieldRules, path string, violations *[]*errdetails.BadRequest_FieldViolation) {
+	mapRules := rules.GetMap()
+	size := uint64(entries.Len())
+
+	if mapRules != nil && mapRules.MinPairs != nil && size < mapRules.GetMinPairs() {
+		addViolation(violations, path, fmt.Sprintf("must contain at least %d entries", mapRules.GetMinPairs()))
+	}
+
+	if mapRules != nil && mapRules.MaxPairs != nil && size > mapRules.GetMaxPairs() {
+		addViolation(violations, path, fmt.Sprintf("must contain at most %d entries", mapRules.GetMaxPairs()))
+	}
+
+	entries.Range(func(key protoreflect.MapKey, value protoreflect.Value) bool {
+		entryPath := fmt.Sprintf("%s[%v]", path, key.Interface())
+		validateScalar(key.Value(), mapRules.GetKeys(), entryPath, violations)
+
+		if field.MapValue().Kind() == protoreflect.MessageKind {
+			validateMessage(value.Message(), entryPath+".", violations)
+		} else {
+			validateScalar(value, mapRules.GetValues(), entryPath, violations)
+		}
+
+		return true
+	})
+}
+
+func validateList(items protoreflect.List, field protoreflect.FieldDescriptor, rules *validate.FieldRules, path string, violations *[]*errdetails.BadRequest_FieldViolation) {
+	listRules := rules.GetRepeated()
+	size := uint64(items.Len())
+
+	if listRules != nil && listRules.MinItems != nil && size < listRules.GetMinItems() {
+		addViolation(violations, path, fmt.Sprintf("must contain at least %d items", listRules.GetMinItems()))
+	}
+
+	if listRules != nil && listRules.MaxItems != nil && size > listRules.GetMaxItems() {
+		addViolation(violations, path, fmt.Sprintf("must contain at most %d items", listRules.GetMaxItems()))
+	}
+
+	for i := 0; i < items.Len(); i++ {
+		itemPath := fmt.Sprintf("%s[%d]", path, i)
+
+		if field.Kind() == protoreflect.MessageKind {
+			validateMessage(items.Get(i).Message(), itemPath+".", violations)
+		} else {
+			validateScalar(items.Get(i), listRules.GetItems(), itemPath, violations)
+		}
+	}
+}
+
+func validateScalar(value protoreflect.Value, rules *validate.FieldRules, path string, violations *[]*errdetails.BadRequest_FieldViolation) {
+	if rules == nil {
+		return
+	}
+
+	if stringRules := rules.GetString_(); stringRules != nil {
+		validateString(value.String(), stringRules, path, violations)
+	}
+
+	if intRules := rules.GetInt32(); intRules != nil {
+		validateInt(value.Int(), int64Rules(intRules), path, violations)
+	}
+
+	if intRules := rules.GetInt64(); intRules != nil {
+		validateInt(value.Int(), intRules, path, violations)
+	}
+}
+
+func validateString(value string, rules *validate.StringRules, path string, violations *[]*errdetails.BadRequest_FieldViolation) {
+	length := uint64(utf8.RuneCountInString(value))
+
+	if rules.MinLen != nil && length < rules.GetMinLen() {
+		if rules.GetMinLen() == 1 {
+			addViolation(violations, path, "value is required")
+		} else {
+			addViolation(violations, path, fmt.Sprintf("must be at least %d characters", rules.GetMinLen()))
+		}
+		return
+	}
+
+	if rules.MaxLen != nil && length > rules.GetMaxLen() {
+		addViolation(violations, path, fmt.Sprintf("must be at most %d characters", rules.GetMaxLen()))
+		return
+	}
+
+	if rules.Pattern != "" && !compile(rules.Pattern).MatchString(value) {
+		addViolation(violations, path, fmt.Sprintf("must match pattern %s", rules.Pattern))
+	}
+}
+
+func validateInt(value int64, rules *validate.Int64Rules, path string, violations *[]*errdetails.BadRequest_FieldViolation) {
+	switch {
+	case rules.Gt != nil && value <= rules.GetGt():
+		addViolation(violations, path, fmt.Sprintf("must be greater than %d", rules.GetGt()))
+	case rules.Gte != nil && value < rules.GetGte():
+		addViolation(violations, path, fmt.Sprintf("must be greater than or equal to %d", rules.GetGte()))
+	case rules.Lt != nil && value >= rules.GetLt():
+		addViolation(violations, path, fmt.Sprintf("must be less than %d", rules.GetLt()))
+	case rules.Lte != nil && value > rules.GetLte():
+		addViolation(violations, path, fmt.Sprintf("must be less than or equal to %d", rules.GetLte()))
+	}
+}
+
+func int64Rules(rules *validate.Int32Rules) *validate.Int64Rules {
+	widen := func(v *int32) *int64 {
+		if v == nil {
+			return nil
+		}
+		w := int64(*v)
+		return &w
+	}
+
+	return &validate.Int64Rules{Gt: widen(rules.Gt), Gte: widen(rules.Gte), Lt: widen(rules.Lt), Lte: widen(rules.Lte)}
+}
+
+func rulesOf(field protoreflect.FieldDescriptor) *validate.FieldRules {
+	options, ok := field.Options().(*descriptorpb.FieldOptions)
+	if !ok || options == nil || !proto.HasExtension(options, validate.E_Rules) {
+		return nil
+	}
+
+	return proto.GetExtension(options, validate.E_Rules).(*validate.FieldRules)
+}
+
+func compile(pattern string) *regexp.Regexp {
+	if cached, ok := patterns.Load(pattern); ok {
+		return cached.(*regexp.Regexp)
+	}
+
+	compiled := regexp.MustCompile(pattern)
+	patterns.Store(pattern, compiled)
+	return compiled
+}
+
+func addViolation(violations *[]*errdetails.BadRequest_FieldViolation, field string, description string) {
+	*violations = append(*violations, &errdetails.BadRequest_FieldViolation{Field: field, Description: description})
+}
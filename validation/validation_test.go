@@ -0,0 +1,90 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	o "orderService.com/go-orderService-grpc/proto/order"
+	u "orderService.com/go-orderService-grpc/proto/user"
+)
+
+func TestValidate_CreateOrderRequest(t *testing.T) {
+	tooMany := map[string]int32{}
+	for i := 0; i < 51; i++ {
+		tooMany[strings.Repeat("x", i+1)] = 1
+	}
+
+	tests := []struct {
+		name           string
+		req            *o.CreateOrderRequest
+		expectedFields []string
+	}{
+		{
+			name:           "Valid Request",
+			req:            &o.CreateOrderRequest{RestaurantId: "1", MenuItems: map[string]int32{"Paneer Tikka": 2}},
+			expectedFields: nil,
+		},
+		{
+			name:           "Missing Restaurant",
+			req:            &o.CreateOrderRequest{MenuItems: map[string]int32{"Paneer Tikka": 2}},
+			expectedFields: []string{"restaurant_id"},
+		},
+		{
+			name:           "Lines Instead Of Menu Items",
+			req:            &o.CreateOrderRequest{RestaurantId: "1", Lines: []*o.OrderLine{{MenuItemId: "Naan", Quantity: 1, ModifierOptionIds: []string{"large"}}}},
+			expectedFields: nil,
+		},
+		{
+			name:           "Invalid Line",
+			req:            &o.CreateOrderRequest{RestaurantId: "1", Lines: []*o.OrderLine{{Quantity: 100, ModifierOptionIds: []string{""}, Instructions: strings.Repeat("x", 201)}}},
+			expectedFields: []string{"lines[0].menu_item_id", "lines[0].quantity", "lines[0].modifier_option_ids[0]", "lines[0].instructions"},
+		},
+		{
+			name:           "Zero Quantity",
+			req:            &o.CreateOrderRequest{RestaurantId: "1", MenuItems: map[string]int32{"Paneer Tikka": 0}},
+			expectedFields: []string{"menu_items[Paneer Tikka]"},
+		},
+		{
+			name:           "Negative Quantity",
+			req:            &o.CreateOrderRequest{RestaurantId: "1", MenuItems: map[string]int32{"Paneer Tikka": -3}},
+			expectedFields: []string{"menu_items[Paneer Tikka]"},
+		},
+		{
+			name:           "Too Many Items",
+			req:            &o.CreateOrderRequest{RestaurantId: "1", MenuItems: tooMany},
+			expectedFields: []string{"menu_items"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fields []string
+			for _, violation := range Validate(tt.req) {
+				fields = append(fields, violation.Field)
+			}
+
+			assert.Equal(t, tt.expectedFields, fields)
+		})
+	}
+}
+
+func TestValidate_RegisterUserRequest_NestedAndRequiredFields(t *testing.T) {
+	violations := Validate(&u.RegisterUserRequest{
+		Username: "a!",
+		Password: "password",
+		Address:  &u.Address{Street: "street", City: "city", State: "state", Zipcode: "12345678901"},
+	})
+
+	var fields []string
+	for _, violation := range violations {
+		fields = append(fields, violation.Field)
+	}
+	assert.Equal(t, []string{"username", "address.zipcode"}, fields)
+
+	violations = Validate(&u.RegisterUserRequest{Username: "user", Password: "password"})
+	assert.Len(t, violations, 1)
+	assert.Equal(t, "address", violations[0].Field)
+	assert.Equal(t, "value is required", violations[0].Description)
+}
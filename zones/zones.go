@@ -0,0 +1,143 @@
+package zones
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"orderService.com/go-orderService-grpc/geo"
+	"orderService.com/go-orderService-grpc/model"
+)
+
+var (
+	ErrOutsideZone     = errors.New("drop address is outside the restaurant's delivery zone")
+	ErrUnknownLocation = errors.New("drop address can't be located to check the delivery zone")
+)
+
+// Zone is the area a restaurant delivers to. An address is inside it if its
+// ZIP code is listed, or if its ZIP code centroid is within RadiusMeters of
+// the restaurant's or inside Polygon.
+type Zone struct {
+	RadiusMeters float64     `json:"radius_meters"`
+	Polygon      geo.Polygon `json:"polygon"`
+	Zipcodes     []string    `json:"zipcodes"`
+}
+
+// Config holds the delivery zone of each restaurant. Restaurants not listed
+// get Default; if there is no Default either, they deliver anywhere.
+type Config struct {
+	Default     *Zone           `json:"default"`
+	Restaurants map[string]Zone `json:"restaurants"`
+}
+
+// LoadConfig reads a JSON encoded Config.
+func LoadConfig(path string) (Config, error) {
+	var config Config
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+
+	if err := json.Unmarshal(content, &config); err != nil {
+		return Config{}, err
+	}
+
+	if config.Default != nil {
+		if err := config.Default.validate(); err != nil {
+			return Config{}, fmt.Errorf("default zone: %w", err)
+		}
+	}
+
+	for restaurantId, zone := range config.Restaurants {
+		if err := zone.validate(); err != nil {
+			return Config{}, fmt.Errorf("zone of restaurant %s: %w", restaurantId, err)
+		}
+	}
+
+	return config, nil
+}
+
+func (zone Zone) validate() error {
+	switch {
+	case zone.RadiusMeters < 0:
+		return errors.New("radius_meters must not be negative")
+	case len(zone.Polygon) > 0 && len(zone.Polygon) < 3:
+		return errors.New("polygon needs at least 3 points")
+	case zone.RadiusMeters == 0 && len(zone.Polygon) == 0 && len(zone.Zipcodes) == 0:
+		return errors.New("zone needs a radius, a polygon or ZIP codes")
+	}
+
+	return nil
+}
+
+// Checker tells whether restaurants deliver to an address. A nil Checker
+// knows no zones, so every address is serviceable.
+type Checker struct {
+	centroids geo.Centroids
+	config    Config
+}
+
+func NewChecker(centroids geo.Centroids, config Config) *Checker {
+	return &Checker{centroids: centroids, config: config}
+}
+
+// Check returns nil if the restaurant at pickup delivers to drop, and
+// ErrOutsideZone otherwise. ErrUnknownLocation is returned if the zone is
+// drawn on the map but either address can't be located on it.
+func (checker *Checker) Check(restaurantId string, pickup *model.Address, drop *model.Address) error {
+	if checker == nil {
+		return nil
+	}
+
+	zone, ok := checker.config.Restaurants[restaurantId]
+	if !ok {
+		if checker.config.Default == nil {
+			return nil
+		}
+
+		zone = *checker.config.Default
+	}
+
+	if drop != nil && zone.lists(drop.Zipcode) {
+		return nil
+	}
+
+	if zone.RadiusMeters == 0 && len(zone.Polygon) == 0 {
+		return ErrOutsideZone
+	}
+
+	dropPoint, ok := checker.centroids.Locate(drop)
+	if !ok {
+		return ErrUnknownLocation
+	}
+
+	if zone.Polygon.Contains(dropPoint) {
+		return nil
+	}
+
+	if zone.RadiusMeters > 0 {
+		pickupPoint, ok := checker.centroids.Locate(pickup)
+		if !ok {
+			return ErrUnknownLocation
+		}
+
+		if geo.Distance(pickupPoint, dropPoint) <= zone.RadiusMeters {
+			return nil
+		}
+	}
+
+	return ErrOutsideZone
+}
+
+func (zone Zone) lists(zipcode string) bool {
+	zipcode = geo.NormalizeZipcode(zipcode)
+	for _, listed := range zone.Zipcodes {
+		if geo.NormalizeZipcode(listed) == zipcode {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,73 @@
+package zones
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"orderService.com/go-orderService-grpc/geo"
+	"orderService.com/go-orderService-grpc/model"
+)
+
+var centroids = geo.Centroids{
+	"560001": {Lat: 12.97, Lng: 77.59},
+	"560002": {Lat: 13.00, Lng: 77.59},
+	"560003": {Lat: 13.10, Lng: 77.59},
+}
+
+func address(zipcode string) *model.Address {
+	return &model.Address{Zipcode: zipcode}
+}
+
+func TestCheck_Radius(t *testing.T) {
+	checker := NewChecker(centroids, Config{Restaurants: map[string]Zone{"1": {RadiusMeters: 5000}}})
+
+	assert.Nil(t, checker.Check("1", address("560001"), address("560002")))
+	assert.ErrorIs(t, checker.Check("1", address("560001"), address("560003")), ErrOutsideZone)
+	assert.ErrorIs(t, checker.Check("1", address("560001"), address("110001")), ErrUnknownLocation)
+	assert.ErrorIs(t, checker.Check("1", address("110001"), address("560002")), ErrUnknownLocation)
+}
+
+func TestCheck_PolygonAndZipcodes(t *testing.T) {
+	checker := NewChecker(centroids, Config{Restaurants: map[string]Zone{
+		"1": {Polygon: geo.Polygon{{Lat: 12.9, Lng: 77.5}, {Lat: 12.9, Lng: 77.7}, {Lat: 13.05, Lng: 77.7}, {Lat: 13.05, Lng: 77.5}}},
+		"2": {Zipcodes: []string{"110 001"}},
+	}})
+
+	assert.Nil(t, checker.Check("1", nil, address("560002")))
+	assert.ErrorIs(t, checker.Check("1", nil, address("560003")), ErrOutsideZone)
+
+	// Listed ZIP codes need no centroid.
+	assert.Nil(t, checker.Check("2", nil, address("110001")))
+	assert.ErrorIs(t, checker.Check("2", nil, address("560001")), ErrOutsideZone)
+}
+
+func TestCheck_UnlistedRestaurants(t *testing.T) {
+	unrestricted := NewChecker(centroids, Config{Restaurants: map[string]Zone{"1": {Zipcodes: []string{"560001"}}}})
+	assert.Nil(t, unrestricted.Check("2", address("560001"), address("560003")))
+
+	withDefault := NewChecker(centroids, Config{Default: &Zone{RadiusMeters: 5000}})
+	assert.ErrorIs(t, withDefault.Check("2", address("560001"), address("560003")), ErrOutsideZone)
+
+	var disabled *Checker
+	assert.Nil(t, disabled.Check("1", address("560001"), address("560003")))
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "zones.json")
+	assert.Nil(t, os.WriteFile(path, []byte(`{
+		"default": {"radius_meters": 8000},
+		"restaurants": {"1": {"polygon": [{"lat": 12.9, "lng": 77.5}, {"lat": 12.9, "lng": 77.7}, {"lat": 13.05, "lng": 77.6}]}}
+	}`), 0o600))
+
+	config, err := LoadConfig(path)
+	assert.Nil(t, err)
+	assert.Equal(t, 8000.0, config.Default.RadiusMeters)
+	assert.Equal(t, geo.Point{Lat: 13.05, Lng: 77.6}, config.Restaurants["1"].Polygon[2])
+
+	assert.Nil(t, os.WriteFile(path, []byte(`{"restaurants": {"1": {}}}`), 0o600))
+	_, err = LoadConfig(path)
+	assert.ErrorContains(t, err, "restaurant 1")
+}